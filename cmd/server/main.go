@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -19,6 +20,8 @@ var (
 	port        int
 	host        string
 	fileLogging bool
+	recordPath  string
+	replayPath  string
 )
 
 func main() {
@@ -46,6 +49,9 @@ func init() {
 	rootCmd.PersistentFlags().IntVarP(&port, "port", "p", 0, "HTTP server port (only for HTTP transport)")
 	rootCmd.PersistentFlags().StringVar(&host, "host", "", "HTTP server host (only for HTTP transport)")
 	rootCmd.PersistentFlags().BoolVarP(&fileLogging, "file-logging", "f", false, "Enable file logging with automatic rolling dates")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "Record every sqlpp run to this cassette file")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "Serve sqlpp responses from this cassette file instead of running sqlpp")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
 }
 
 func runServer(cmd *cobra.Command, args []string) error {
@@ -71,6 +77,16 @@ func runServer(cmd *cobra.Command, args []string) error {
 	if fileLogging {
 		cfg.Log.FileLogging = true
 	}
+	if recordPath != "" {
+		if cfg.Sqlpp.Cassette, err = cassetteFlag(config.CassetteRecord, recordPath); err != nil {
+			return err
+		}
+	}
+	if replayPath != "" {
+		if cfg.Sqlpp.Cassette, err = cassetteFlag(config.CassetteReplay, replayPath); err != nil {
+			return err
+		}
+	}
 
 	// Setup logger
 	logger := logrus.New()
@@ -100,7 +116,7 @@ func runServer(cmd *cobra.Command, args []string) error {
 
 	// Log startup information
 	logger.WithFields(logrus.Fields{
-		"version":    "1.0.0",
+		"version":    server.Version,
 		"transport":  cfg.Server.Transport,
 		"log_level":  cfg.Log.Level,
 		"sqlpp_path": cfg.Sqlpp.GetSqlppExecutablePath(),
@@ -123,3 +139,13 @@ func runServer(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// cassetteFlag builds cassette settings from --record or --replay. Paths
+// given on the command line are relative to the working directory.
+func cassetteFlag(mode, path string) (config.CassetteConfig, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return config.CassetteConfig{}, fmt.Errorf("invalid cassette path: %w", err)
+	}
+	return config.CassetteConfig{Mode: mode, Path: abs}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -25,8 +26,129 @@ type ServerConfig struct {
 
 // SqlppConfig holds sqlpp executable configuration
 type SqlppConfig struct {
-	ExecutablePath string `mapstructure:"executable_path"` // Directory path containing sqlpp executable (defaults to .bin)
-	Timeout        int    `mapstructure:"timeout"`         // timeout in seconds
+	ExecutablePath string     `mapstructure:"executable_path"`     // Directory path containing sqlpp executable (defaults to .bin)
+	Timeout        int        `mapstructure:"timeout"`             // timeout in seconds
+	MaxTimeout     int        `mapstructure:"max_timeout"`         // Largest timeout_seconds a tool call may request (0 = no cap)
+	CancelGrace    int        `mapstructure:"cancel_grace_period"` // Seconds a cancelled or timed-out sqlpp gets to exit after SIGINT
+	MinVersion     string     `mapstructure:"min_version"`         // Oldest sqlpp version the server starts with, e.g. "1.4.0" (empty = any)
+	Pool           PoolConfig `mapstructure:"pool"`
+
+	// Concurrency limits
+	MaxConcurrent int `mapstructure:"max_concurrent"` // Concurrent sqlpp calls across all connections (0 = unlimited)
+	MaxQueue      int `mapstructure:"max_queue"`      // Calls allowed to wait for a free slot (0 = unlimited)
+	QueueTimeout  int `mapstructure:"queue_timeout"`  // Seconds a call may wait for a free slot (0 = no limit)
+
+	// Retries for transient database failures
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Output limits
+	MaxOutputBytes int    `mapstructure:"max_output_bytes"` // In-memory output per call before spilling to disk (0 = unlimited)
+	ResultDir      string `mapstructure:"result_dir"`       // Directory for spilled output (defaults to a temp directory)
+	ResultTTL      int    `mapstructure:"result_ttl"`       // Seconds spilled output is kept (0 = until the session ends)
+
+	// Largest spilled output a tool reads back whole to parse, such as a
+	// large catalog (0 = built-in 64 MiB)
+	MaxCatalogBytes int `mapstructure:"max_catalog_bytes"`
+
+	// sqlpp config file, working directory and environment
+	ProcessConfig `mapstructure:",squash"`
+
+	// Directories of vetted .sql scripts for the execute_sql_file tool
+	ScriptRoots []string `mapstructure:"script_roots"`
+
+	// Recording sqlpp runs to a cassette file, or replaying them without sqlpp
+	Cassette CassetteConfig `mapstructure:"cassette"`
+
+	// Transactions held open across tool calls of one MCP session
+	Transactions TransactionConfig `mapstructure:"transactions"`
+
+	// Caching of list_schema_* results
+	SchemaCache SchemaCacheConfig `mapstructure:"schema_cache"`
+
+	// Row limits for the sample_table_rows tool
+	Sampling SamplingConfig `mapstructure:"sampling"`
+
+	// Largest count a "GO n" batch separator may repeat its batch (0 = built-in 100)
+	MaxBatchRepeat int `mapstructure:"max_batch_repeat"`
+
+	// Per-connection settings keyed by sqlpp connection name
+	Connections map[string]ConnectionConfig `mapstructure:"connections"`
+}
+
+// ConnectionConfig holds settings for a single sqlpp connection
+type ConnectionConfig struct {
+	MaxConcurrent int         `mapstructure:"max_concurrent"` // Concurrent sqlpp calls for this connection (0 = unlimited)
+	Timeout       int         `mapstructure:"timeout"`        // Default timeout in seconds for this connection (0 = sqlpp.timeout)
+	Retry         RetryConfig `mapstructure:"retry"`          // Overrides for the global retry settings (0 = inherit)
+
+	// Overrides for the global config file, working directory and environment
+	ProcessConfig `mapstructure:",squash"`
+}
+
+// ProcessConfig controls the environment a sqlpp process starts in, so its
+// behaviour does not depend on where the server was launched
+type ProcessConfig struct {
+	ConfigFile string    `mapstructure:"config_file"` // sqlpp config file passed with --config (empty = sqlpp's own lookup)
+	WorkingDir string    `mapstructure:"working_dir"` // Working directory for sqlpp (empty = the server's)
+	Env        EnvConfig `mapstructure:"env"`
+}
+
+// EnvConfig controls which environment variables sqlpp sees
+type EnvConfig struct {
+	Allow []string `mapstructure:"allow"` // Server variables passed through, PREFIX* matches by prefix (empty = all)
+	Set   []string `mapstructure:"set"`   // KEY=VALUE pairs set for sqlpp
+}
+
+// RetryConfig holds the retry policy for transient failures such as refused
+// connections, deadlocks and serialization errors
+type RetryConfig struct {
+	MaxAttempts      int `mapstructure:"max_attempts"`       // Attempts per call including the first (0 or 1 disables retries)
+	InitialBackoffMs int `mapstructure:"initial_backoff_ms"` // Delay before the first retry, doubled for each further retry
+	MaxBackoffMs     int `mapstructure:"max_backoff_ms"`     // Upper bound on the delay between attempts
+}
+
+// CassetteConfig selects record or replay mode for sqlpp runs
+type CassetteConfig struct {
+	Mode string `mapstructure:"mode"` // "record", "replay" or empty to run sqlpp normally
+	Path string `mapstructure:"path"` // Cassette file, one JSON interaction per line
+}
+
+// Cassette modes
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// PoolConfig holds configuration for persistent sqlpp worker processes
+type PoolConfig struct {
+	Enabled             bool   `mapstructure:"enabled"`               // Keep long-lived sqlpp processes per connection
+	MaxWorkers          int    `mapstructure:"max_workers"`           // Workers per session, connection and output format
+	MaxUses             int    `mapstructure:"max_uses"`              // Statements a worker runs before it is recycled
+	HealthCheckInterval int    `mapstructure:"health_check_interval"` // Seconds between idle worker health checks (0 disables)
+	HealthCheckQuery    string `mapstructure:"health_check_query"`    // Statement used to probe idle workers
+	Delimiter           string `mapstructure:"delimiter"`             // Batch delimiter line passed to sqlpp --delimiter
+}
+
+// TransactionConfig holds configuration for session-scoped transactions. Each
+// open transaction keeps its own sqlpp process, driven with the pool delimiter.
+type TransactionConfig struct {
+	Enabled           bool   `mapstructure:"enabled"`            // Offer the begin/commit/rollback_transaction tools
+	IdleTimeout       int    `mapstructure:"idle_timeout"`       // Seconds an unused transaction stays open before it is rolled back (0 = no limit)
+	BeginStatement    string `mapstructure:"begin_statement"`    // Statement that opens a transaction, e.g. "BEGIN TRANSACTION" for SQL Server
+	CommitStatement   string `mapstructure:"commit_statement"`   // Statement that commits it
+	RollbackStatement string `mapstructure:"rollback_statement"` // Statement that rolls it back
+}
+
+// SchemaCacheConfig holds configuration for caching schema command results
+type SchemaCacheConfig struct {
+	Enabled bool `mapstructure:"enabled"` // Cache results and coalesce identical concurrent schema calls
+	TTL     int  `mapstructure:"ttl"`     // Seconds a result is reused (0 = only coalesce concurrent calls)
+}
+
+// SamplingConfig holds the row limits of sample_table_rows
+type SamplingConfig struct {
+	DefaultRows int `mapstructure:"default_rows"` // Rows returned when the call gives no limit (0 = 10)
+	MaxRows     int `mapstructure:"max_rows"`     // Largest limit a call may request; larger limits are capped (0 = 100)
 }
 
 // LogConfig holds logging configuration
@@ -100,6 +222,33 @@ func setDefaults(v *viper.Viper) {
 	// Sqlpp defaults
 	v.SetDefault("sqlpp.executable_path", ".bin") // Default to .bin directory
 	v.SetDefault("sqlpp.timeout", 300)            // 5 minutes
+	v.SetDefault("sqlpp.max_timeout", 3600)       // 1 hour
+	v.SetDefault("sqlpp.cancel_grace_period", 5)
+	v.SetDefault("sqlpp.max_concurrent", 0) // unlimited
+	v.SetDefault("sqlpp.max_queue", 100)
+	v.SetDefault("sqlpp.queue_timeout", 60)
+	v.SetDefault("sqlpp.max_output_bytes", 1024*1024)     // 1 MiB
+	v.SetDefault("sqlpp.result_ttl", 3600)                // 1 hour
+	v.SetDefault("sqlpp.max_catalog_bytes", 64*1024*1024) // 64 MiB
+	v.SetDefault("sqlpp.retry.max_attempts", 3)
+	v.SetDefault("sqlpp.retry.initial_backoff_ms", 200)
+	v.SetDefault("sqlpp.retry.max_backoff_ms", 5000)
+	v.SetDefault("sqlpp.pool.enabled", false)
+	v.SetDefault("sqlpp.pool.max_workers", 2)
+	v.SetDefault("sqlpp.pool.max_uses", 100)
+	v.SetDefault("sqlpp.pool.health_check_interval", 60)
+	v.SetDefault("sqlpp.pool.health_check_query", "SELECT 1")
+	v.SetDefault("sqlpp.pool.delimiter", "--@@mcp_sqlpp_end@@")
+	v.SetDefault("sqlpp.transactions.enabled", false)
+	v.SetDefault("sqlpp.transactions.idle_timeout", 300) // 5 minutes
+	v.SetDefault("sqlpp.transactions.begin_statement", "BEGIN")
+	v.SetDefault("sqlpp.transactions.commit_statement", "COMMIT")
+	v.SetDefault("sqlpp.transactions.rollback_statement", "ROLLBACK")
+	v.SetDefault("sqlpp.schema_cache.enabled", true)
+	v.SetDefault("sqlpp.schema_cache.ttl", 300) // 5 minutes
+	v.SetDefault("sqlpp.sampling.default_rows", 10)
+	v.SetDefault("sqlpp.sampling.max_rows", 100)
+	v.SetDefault("sqlpp.max_batch_repeat", 100)
 
 	// Log defaults
 	v.SetDefault("log.level", "info")
@@ -148,9 +297,256 @@ func validate(config *Config) error {
 		return fmt.Errorf("invalid sqlpp timeout: %d (must be greater than 0)", config.Sqlpp.Timeout)
 	}
 
+	if config.Sqlpp.MaxTimeout < 0 {
+		return fmt.Errorf("invalid sqlpp max_timeout: %d (must not be negative)", config.Sqlpp.MaxTimeout)
+	}
+	if config.Sqlpp.CancelGrace < 0 {
+		return fmt.Errorf("invalid sqlpp cancel_grace_period: %d (must not be negative)", config.Sqlpp.CancelGrace)
+	}
+
+	// Validate concurrency limits
+	if config.Sqlpp.MaxConcurrent < 0 {
+		return fmt.Errorf("invalid sqlpp max_concurrent: %d (must not be negative)", config.Sqlpp.MaxConcurrent)
+	}
+	if config.Sqlpp.MaxQueue < 0 {
+		return fmt.Errorf("invalid sqlpp max_queue: %d (must not be negative)", config.Sqlpp.MaxQueue)
+	}
+	if config.Sqlpp.QueueTimeout < 0 {
+		return fmt.Errorf("invalid sqlpp queue_timeout: %d (must not be negative)", config.Sqlpp.QueueTimeout)
+	}
+	for name, conn := range config.Sqlpp.Connections {
+		if conn.MaxConcurrent < 0 {
+			return fmt.Errorf("invalid max_concurrent for connection %s: %d (must not be negative)", name, conn.MaxConcurrent)
+		}
+		if conn.Timeout < 0 {
+			return fmt.Errorf("invalid timeout for connection %s: %d (must not be negative)", name, conn.Timeout)
+		}
+		if err := validateRetry(conn.Retry); err != nil {
+			return fmt.Errorf("invalid retry settings for connection %s: %w", name, err)
+		}
+		if err := validateEnv(conn.Env); err != nil {
+			return fmt.Errorf("invalid env settings for connection %s: %w", name, err)
+		}
+	}
+
+	// Validate retry policy
+	if err := validateRetry(config.Sqlpp.Retry); err != nil {
+		return fmt.Errorf("invalid sqlpp retry settings: %w", err)
+	}
+
+	// Validate sqlpp environment
+	if err := validateEnv(config.Sqlpp.Env); err != nil {
+		return fmt.Errorf("invalid sqlpp env settings: %w", err)
+	}
+
+	// Validate cassette settings
+	if err := config.Sqlpp.Cassette.Validate(); err != nil {
+		return err
+	}
+
+	// Validate output limits
+	if config.Sqlpp.MaxOutputBytes < 0 {
+		return fmt.Errorf("invalid sqlpp max_output_bytes: %d (must not be negative)", config.Sqlpp.MaxOutputBytes)
+	}
+	if config.Sqlpp.MaxCatalogBytes < 0 {
+		return fmt.Errorf("invalid sqlpp max_catalog_bytes: %d (must not be negative)", config.Sqlpp.MaxCatalogBytes)
+	}
+	if config.Sqlpp.ResultTTL < 0 {
+		return fmt.Errorf("invalid sqlpp result_ttl: %d (must not be negative)", config.Sqlpp.ResultTTL)
+	}
+
+	// Validate worker pool settings
+	if config.Sqlpp.Pool.Enabled {
+		pool := config.Sqlpp.Pool
+		if pool.MaxWorkers < 1 {
+			return fmt.Errorf("invalid sqlpp pool max_workers: %d (must be greater than 0)", pool.MaxWorkers)
+		}
+		if pool.MaxUses < 1 {
+			return fmt.Errorf("invalid sqlpp pool max_uses: %d (must be greater than 0)", pool.MaxUses)
+		}
+		if pool.HealthCheckInterval < 0 {
+			return fmt.Errorf("invalid sqlpp pool health_check_interval: %d (must not be negative)", pool.HealthCheckInterval)
+		}
+		if pool.Delimiter == "" {
+			return fmt.Errorf("sqlpp pool delimiter must not be empty")
+		}
+	}
+
+	// Validate transaction settings
+	if tx := config.Sqlpp.Transactions; tx.Enabled {
+		if tx.IdleTimeout < 0 {
+			return fmt.Errorf("invalid sqlpp transactions idle_timeout: %d (must not be negative)", tx.IdleTimeout)
+		}
+		if tx.BeginStatement == "" || tx.CommitStatement == "" || tx.RollbackStatement == "" {
+			return fmt.Errorf("sqlpp transactions begin_statement, commit_statement and rollback_statement must not be empty")
+		}
+		if config.Sqlpp.Pool.Delimiter == "" {
+			return fmt.Errorf("sqlpp pool delimiter must not be empty when transactions are enabled")
+		}
+	}
+
+	if config.Sqlpp.SchemaCache.TTL < 0 {
+		return fmt.Errorf("invalid sqlpp schema_cache ttl: %d (must not be negative)", config.Sqlpp.SchemaCache.TTL)
+	}
+
+	// Validate sampling limits
+	sampling := config.Sqlpp.Sampling
+	if sampling.DefaultRows < 0 {
+		return fmt.Errorf("invalid sqlpp sampling default_rows: %d (must not be negative)", sampling.DefaultRows)
+	}
+	if sampling.MaxRows < 0 {
+		return fmt.Errorf("invalid sqlpp sampling max_rows: %d (must not be negative)", sampling.MaxRows)
+	}
+	if sampling.MaxRows > 0 && sampling.DefaultRows > sampling.MaxRows {
+		return fmt.Errorf("invalid sqlpp sampling default_rows: %d (must not exceed max_rows %d)", sampling.DefaultRows, sampling.MaxRows)
+	}
+
+	if config.Sqlpp.MaxBatchRepeat < 0 {
+		return fmt.Errorf("invalid sqlpp max_batch_repeat: %d (must not be negative)", config.Sqlpp.MaxBatchRepeat)
+	}
+
 	return nil
 }
 
+// validateRetry checks that retry settings are not negative
+func validateRetry(retry RetryConfig) error {
+	if retry.MaxAttempts < 0 {
+		return fmt.Errorf("max_attempts %d must not be negative", retry.MaxAttempts)
+	}
+	if retry.InitialBackoffMs < 0 {
+		return fmt.Errorf("initial_backoff_ms %d must not be negative", retry.InitialBackoffMs)
+	}
+	if retry.MaxBackoffMs < 0 {
+		return fmt.Errorf("max_backoff_ms %d must not be negative", retry.MaxBackoffMs)
+	}
+	return nil
+}
+
+// Validate checks the cassette mode and that a mode comes with a path
+func (c CassetteConfig) Validate() error {
+	switch c.Mode {
+	case "":
+		return nil
+	case CassetteRecord, CassetteReplay:
+		if c.Path == "" {
+			return fmt.Errorf("sqlpp cassette path is required in %s mode", c.Mode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid sqlpp cassette mode: %s (must be 'record' or 'replay')", c.Mode)
+	}
+}
+
+// validateEnv checks that environment overrides are KEY=VALUE pairs
+func validateEnv(env EnvConfig) error {
+	for _, kv := range env.Set {
+		if name, _, ok := strings.Cut(kv, "="); !ok || name == "" {
+			return fmt.Errorf("set entry %q must be KEY=VALUE", kv)
+		}
+	}
+	return nil
+}
+
+// Connection returns the settings for the named sqlpp connection. Names are
+// matched case-insensitively because configuration keys are lowercased on load.
+func (c *SqlppConfig) Connection(name string) ConnectionConfig {
+	if conn, ok := c.Connections[name]; ok {
+		return conn
+	}
+	for key, conn := range c.Connections {
+		if strings.EqualFold(key, name) {
+			return conn
+		}
+	}
+	return ConnectionConfig{}
+}
+
+// RetryPolicy returns the retry settings for the named connection, with
+// unset per-connection values inherited from the global settings
+func (c *SqlppConfig) RetryPolicy(connection string) RetryConfig {
+	policy := c.Retry
+	override := c.Connection(connection).Retry
+	if override.MaxAttempts > 0 {
+		policy.MaxAttempts = override.MaxAttempts
+	}
+	if override.InitialBackoffMs > 0 {
+		policy.InitialBackoffMs = override.InitialBackoffMs
+	}
+	if override.MaxBackoffMs > 0 {
+		policy.MaxBackoffMs = override.MaxBackoffMs
+	}
+	return policy
+}
+
+// Process returns the process settings for the named connection. The config
+// file and working directory fall back to the global values, the allowlist
+// is the union of both and per-connection variables override global ones.
+// Relative paths are resolved against the MCP server binary's directory.
+func (c *SqlppConfig) Process(connection string) ProcessConfig {
+	process := c.ProcessConfig
+	override := c.Connection(connection).ProcessConfig
+
+	if override.ConfigFile != "" {
+		process.ConfigFile = override.ConfigFile
+	}
+	if override.WorkingDir != "" {
+		process.WorkingDir = override.WorkingDir
+	}
+	process.Env = EnvConfig{
+		Allow: append(append([]string(nil), process.Env.Allow...), override.Env.Allow...),
+		Set:   append(append([]string(nil), process.Env.Set...), override.Env.Set...),
+	}
+
+	if process.ConfigFile != "" {
+		process.ConfigFile = c.resolvePath(process.ConfigFile)
+	}
+	if process.WorkingDir != "" {
+		process.WorkingDir = c.resolvePath(process.WorkingDir)
+	}
+	return process
+}
+
+// LimitsEnabled reports whether any global or per-connection concurrency limit is set
+func (c *SqlppConfig) LimitsEnabled() bool {
+	if c.MaxConcurrent > 0 {
+		return true
+	}
+	for _, conn := range c.Connections {
+		if conn.MaxConcurrent > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetResultDir returns the directory for spilled output, resolved relative to
+// the MCP server binary's directory. An empty result means a temp directory.
+func (c *SqlppConfig) GetResultDir() string {
+	if c.ResultDir == "" {
+		return ""
+	}
+	return c.resolvePath(c.ResultDir)
+}
+
+// GetScriptRoots returns the script directories, resolved relative to the
+// MCP server binary's directory
+func (c *SqlppConfig) GetScriptRoots() []string {
+	roots := make([]string, 0, len(c.ScriptRoots))
+	for _, root := range c.ScriptRoots {
+		roots = append(roots, c.resolvePath(root))
+	}
+	return roots
+}
+
+// GetCassettePath returns the cassette file path, resolved relative to the
+// MCP server binary's directory
+func (c *SqlppConfig) GetCassettePath() string {
+	if c.Cassette.Path == "" {
+		return ""
+	}
+	return c.resolvePath(c.Cassette.Path)
+}
+
 // GetSqlppExecutablePath returns the full path to the sqlpp executable
 // Relative paths are resolved relative to the MCP server binary's directory
 func (c *SqlppConfig) GetSqlppExecutablePath() string {
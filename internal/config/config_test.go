@@ -28,6 +28,20 @@ func TestLoad_Defaults(t *testing.T) {
 	assert.Equal(t, "localhost", config.Server.Host)
 	assert.Equal(t, ".bin", config.Sqlpp.ExecutablePath)
 	assert.Equal(t, 300, config.Sqlpp.Timeout)
+	assert.False(t, config.Sqlpp.Pool.Enabled)
+	assert.Equal(t, 2, config.Sqlpp.Pool.MaxWorkers)
+	assert.Equal(t, 100, config.Sqlpp.Pool.MaxUses)
+	assert.Equal(t, 3, config.Sqlpp.Retry.MaxAttempts)
+	assert.Equal(t, 5, config.Sqlpp.CancelGrace)
+	assert.False(t, config.Sqlpp.Transactions.Enabled)
+	assert.Equal(t, 300, config.Sqlpp.Transactions.IdleTimeout)
+	assert.Equal(t, "BEGIN", config.Sqlpp.Transactions.BeginStatement)
+	assert.True(t, config.Sqlpp.SchemaCache.Enabled)
+	assert.Equal(t, 300, config.Sqlpp.SchemaCache.TTL)
+	assert.Equal(t, 10, config.Sqlpp.Sampling.DefaultRows)
+	assert.Equal(t, 100, config.Sqlpp.Sampling.MaxRows)
+	assert.Equal(t, 100, config.Sqlpp.MaxBatchRepeat)
+	assert.Equal(t, 64*1024*1024, config.Sqlpp.MaxCatalogBytes)
 	assert.Equal(t, "info", config.Log.Level)
 	assert.Equal(t, "text", config.Log.Format)
 	assert.Equal(t, "us-east-1", config.AWS.Region)
@@ -224,6 +238,57 @@ func TestValidate_InvalidTimeout(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid sqlpp timeout")
 }
 
+func TestValidate_InvalidPool(t *testing.T) {
+	config := &Config{
+		Server: ServerConfig{
+			Transport: "stdio",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Sqlpp: SqlppConfig{
+			Timeout: 300,
+			Pool: PoolConfig{
+				Enabled:    true,
+				MaxWorkers: 0,
+				MaxUses:    100,
+				Delimiter:  "--end--",
+			},
+		},
+	}
+
+	err := validate(config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid sqlpp pool max_workers")
+}
+
+func TestValidate_InvalidTransactions(t *testing.T) {
+	config := &Config{
+		Server: ServerConfig{
+			Transport: "stdio",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Sqlpp: SqlppConfig{
+			Timeout: 300,
+			Pool:    PoolConfig{Delimiter: "--end--"},
+			Transactions: TransactionConfig{
+				Enabled:           true,
+				IdleTimeout:       300,
+				BeginStatement:    "BEGIN",
+				RollbackStatement: "ROLLBACK",
+			},
+		},
+	}
+
+	err := validate(config)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "commit_statement")
+}
+
 func TestValidate_Valid(t *testing.T) {
 	config := &Config{
 		Server: ServerConfig{
@@ -296,3 +361,98 @@ func TestSqlppConfig_GetSqlppExecutablePath(t *testing.T) {
 		})
 	}
 }
+
+func TestSqlppConfig_Connection(t *testing.T) {
+	config := &SqlppConfig{
+		Connections: map[string]ConnectionConfig{
+			"reporting": {MaxConcurrent: 2},
+		},
+	}
+
+	assert.Equal(t, 2, config.Connection("reporting").MaxConcurrent)
+	assert.Equal(t, 2, config.Connection("Reporting").MaxConcurrent)
+	assert.Equal(t, 0, config.Connection("main").MaxConcurrent)
+	assert.True(t, config.LimitsEnabled())
+
+	assert.False(t, (&SqlppConfig{}).LimitsEnabled())
+}
+
+func TestSqlppConfig_RetryPolicy(t *testing.T) {
+	config := &SqlppConfig{
+		Retry: RetryConfig{MaxAttempts: 3, InitialBackoffMs: 200, MaxBackoffMs: 5000},
+		Connections: map[string]ConnectionConfig{
+			"warehouse": {Retry: RetryConfig{MaxAttempts: 5, MaxBackoffMs: 30000}},
+		},
+	}
+
+	assert.Equal(t, RetryConfig{MaxAttempts: 3, InitialBackoffMs: 200, MaxBackoffMs: 5000}, config.RetryPolicy("main"))
+	assert.Equal(t, RetryConfig{MaxAttempts: 5, InitialBackoffMs: 200, MaxBackoffMs: 30000}, config.RetryPolicy("Warehouse"))
+}
+
+func TestLoad_ProcessSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "test-config.yaml")
+
+	configContent := `
+sqlpp:
+  config_file: "/etc/sqlpp/sqlpp.yaml"
+  working_dir: "/var/lib/sqlpp"
+  env:
+    allow: ["PATH", "HOME"]
+    set: ["TZ=UTC"]
+  connections:
+    warehouse:
+      config_file: "/etc/sqlpp/warehouse.yaml"
+      env:
+        allow: ["PG*"]
+        set: ["PGAPPNAME=mcp_sqlpp"]
+`
+
+	err := os.WriteFile(configFile, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	config, err := Load(configFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, ProcessConfig{
+		ConfigFile: "/etc/sqlpp/sqlpp.yaml",
+		WorkingDir: "/var/lib/sqlpp",
+		Env:        EnvConfig{Allow: []string{"PATH", "HOME"}, Set: []string{"TZ=UTC"}},
+	}, config.Sqlpp.Process("main"))
+
+	assert.Equal(t, ProcessConfig{
+		ConfigFile: "/etc/sqlpp/warehouse.yaml",
+		WorkingDir: "/var/lib/sqlpp",
+		Env: EnvConfig{
+			Allow: []string{"PATH", "HOME", "PG*"},
+			Set:   []string{"TZ=UTC", "PGAPPNAME=mcp_sqlpp"},
+		},
+	}, config.Sqlpp.Process("warehouse"))
+}
+
+func TestValidate_InvalidEnv(t *testing.T) {
+	config := &Config{
+		Server: ServerConfig{Transport: "stdio", Port: 8080},
+		Sqlpp:  SqlppConfig{Timeout: 30},
+		Log:    LogConfig{Level: "info", Format: "text"},
+	}
+	config.Sqlpp.Env.Set = []string{"NOVALUE"}
+
+	err := validate(config)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `set entry "NOVALUE" must be KEY=VALUE`)
+}
+
+func TestCassetteConfig_Validate(t *testing.T) {
+	assert.NoError(t, CassetteConfig{}.Validate())
+	assert.NoError(t, CassetteConfig{Mode: CassetteRecord, Path: "session.cassette"}.Validate())
+	assert.NoError(t, CassetteConfig{Mode: CassetteReplay, Path: "session.cassette"}.Validate())
+
+	err := CassetteConfig{Mode: CassetteReplay}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sqlpp cassette path is required in replay mode")
+
+	err = CassetteConfig{Mode: "rewind", Path: "session.cassette"}.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid sqlpp cassette mode: rewind")
+}
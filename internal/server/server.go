@@ -2,8 +2,11 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,75 +17,437 @@ import (
 	"github.com/stainedhead/gosqlpp-mcp-server/internal/config"
 	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
 	"github.com/stainedhead/gosqlpp-mcp-server/internal/tools"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
 )
 
+// Version is the mcp_sqlpp server version
+const Version = "1.0.0"
+
+// scriptURIScheme is the URI scheme of script resources, e.g.
+// sqlpp-script:///reports/daily_sales.sql
+const scriptURIScheme = "sqlpp-script"
+
 // Server represents the MCP server
 type Server struct {
 	config      *config.Config
 	logger      *logrus.Logger
-	executor    *sqlpp.Executor
+	executor    sqlpp.ExecutorInterface
 	toolHandler *tools.ToolHandler
 	mcpServer   *mcp.Server
+	closers     []io.Closer
+}
+
+// Option configures optional Server features
+type Option func(*options)
+
+type options struct {
+	interceptors []sqlpp.Interceptor
+}
+
+// WithInterceptors adds interceptors around every sqlpp run, inside the
+// built-in logging interceptor and in the order given
+func WithInterceptors(interceptors ...sqlpp.Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
 }
 
 // New creates a new MCP server instance
-func New(cfg *config.Config, logger *logrus.Logger) (*Server, error) {
-	// Create sqlpp executor
-	executor := sqlpp.NewExecutor(cfg.Sqlpp.GetSqlppExecutablePath(), cfg.Sqlpp.Timeout, logger)
+func New(cfg *config.Config, logger *logrus.Logger, opts ...Option) (*Server, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-	// Validate sqlpp executable
-	if err := executor.ValidateExecutable(); err != nil {
-		return nil, fmt.Errorf("sqlpp validation failed: %w", err)
+	// Run sqlpp, or serve recorded responses in replay mode
+	sessions := newSessionTracker(logger)
+	var b *backend
+	var err error
+	if cfg.Sqlpp.Cassette.Mode == config.CassetteReplay {
+		b, err = newReplayBackend(cfg, logger, o)
+	} else {
+		b, err = newSqlppBackend(cfg, logger, o, sessions)
+	}
+	if err != nil {
+		return nil, err
+	}
+	executor, caps, closers := b.executor, b.caps, b.closers
+
+	// Let agents run vetted scripts by name from the script roots
+	var scripts *sqlpp.ScriptLibrary
+	if len(cfg.Sqlpp.ScriptRoots) > 0 {
+		scripts, err = sqlpp.NewScriptLibrary(cfg.Sqlpp.GetScriptRoots())
+		if err != nil {
+			return nil, err
+		}
+		b.toolOpts = append(b.toolOpts, tools.WithScriptLibrary(scripts))
+	}
+
+	// Cap the rows sample_table_rows returns
+	b.toolOpts = append(b.toolOpts, tools.WithSampleLimits(cfg.Sqlpp.Sampling.DefaultRows, cfg.Sqlpp.Sampling.MaxRows))
+
+	// Cap GO n repeat counts
+	b.toolOpts = append(b.toolOpts, tools.WithBatchRepeatLimit(cfg.Sqlpp.MaxBatchRepeat))
+
+	// Bound concurrent sqlpp processes globally and per connection
+	if cfg.Sqlpp.LimitsEnabled() {
+		limiter := sqlpp.NewLimitedExecutor(executor, sqlpp.LimitOptions{
+			MaxConcurrent: cfg.Sqlpp.MaxConcurrent,
+			ConnectionLimit: func(connection string) int {
+				return cfg.Sqlpp.Connection(connection).MaxConcurrent
+			},
+			MaxQueue:     cfg.Sqlpp.MaxQueue,
+			QueueTimeout: time.Duration(cfg.Sqlpp.QueueTimeout) * time.Second,
+		}, logger)
+		executor = limiter
+
+		// An open transaction keeps a sqlpp process running, so it holds a
+		// slot until it ends
+		if b.transactions != nil {
+			b.transactions.SetSlots(limiter.Acquire)
+		}
+		// Pooled calls run on their caller's slot; health probes need one
+		if b.pool != nil {
+			b.pool.SetSlots(limiter.Acquire)
+		}
+	}
+
+	// Retry safe calls that hit transient database failures. Retries sit
+	// outside the limiter so a backing-off call does not hold a slot.
+	executor = sqlpp.NewRetryingExecutor(executor, func(connection string) sqlpp.RetryPolicy {
+		retry := cfg.Sqlpp.RetryPolicy(connection)
+		return sqlpp.RetryPolicy{
+			MaxAttempts:    retry.MaxAttempts,
+			InitialBackoff: time.Duration(retry.InitialBackoffMs) * time.Millisecond,
+			MaxBackoff:     time.Duration(retry.MaxBackoffMs) * time.Millisecond,
+		}
+	}, logger)
+
+	// Serve repeated schema calls from a cache. It sits outermost so cache
+	// hits and coalesced calls take no concurrency slot.
+	if cfg.Sqlpp.SchemaCache.Enabled {
+		schemaCache := sqlpp.NewCachingExecutor(executor, time.Duration(cfg.Sqlpp.SchemaCache.TTL)*time.Second, logger)
+		executor = schemaCache
+		b.toolOpts = append(b.toolOpts, tools.WithSchemaCache(schemaCache))
+	}
+
+	// Release executor resources such as pooled sqlpp workers on exit
+	if closer, ok := executor.(io.Closer); ok {
+		closers = append(closers, closer)
 	}
 
 	// Create tool handler
-	toolHandler := tools.NewToolHandler(executor, logger)
+	toolHandler := tools.NewToolHandler(executor, logger, b.toolOpts...)
+
+	// Only offer tools the installed sqlpp supports
+	for name, feature := range toolHandler.UnsupportedTools() {
+		logger.WithFields(logrus.Fields{
+			"tool":          name,
+			"missing":       feature,
+			"sqlpp_version": caps.VersionString(),
+		}).Warn("Tool disabled: not supported by sqlpp")
+	}
 
-	// Create MCP server
-	mcpServer := mcp.NewServer("mcp_sqlpp", "1.0.0", &mcp.ServerOptions{})
+	// Create MCP server, reporting the sqlpp version as build metadata
+	mcpServer := mcp.NewServer("mcp_sqlpp", serverVersion(caps), &mcp.ServerOptions{})
 
 	// Register tools
 	for _, tool := range toolHandler.GetTools() {
 		toolName := tool.Name // Capture for closure
 		handler := mcp.ToolHandler(func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
-			result, err := toolHandler.ExecuteTool(toolName, params.Arguments)
+			// Attribute sqlpp work to the calling session
+			ctx = sqlpp.WithSessionID(ctx, sessions.ID(session))
+
+			// Stream progress back to the client when it supplied a progress token
+			if token := params.GetProgressToken(); token != nil {
+				ctx = sqlpp.WithProgress(ctx, progressNotifier(ctx, session, token, logger))
+			}
+
+			// Pass the request context down so client cancellation and
+			// disconnects terminate the sqlpp process
+			result, err := toolHandler.ExecuteToolResult(ctx, toolName, params.Arguments)
 			if err != nil {
-				return &mcp.CallToolResult{
+				errResult := &mcp.CallToolResult{
 					Content: []mcp.Content{
 						&mcp.TextContent{Text: err.Error()},
 					},
 					IsError: true,
-				}, nil
+				}
+				// Failed sqlpp runs still report exit code, stderr, timing
+				// and the error category
+				var execErr *tools.ExecutionError
+				var batchErr *tools.BatchError
+				if errors.As(err, &execErr) {
+					errResult.StructuredContent = execErr.Result.Metadata()
+				} else if errors.As(err, &batchErr) {
+					errResult.StructuredContent = batchErr.Execution
+				} else if code := sqlpp.ClassifyError(err); code != "" {
+					errResult.StructuredContent = types.ExecutionMetadata{
+						ExitCode:  -1,
+						Error:     err.Error(),
+						ErrorCode: string(code),
+					}
+				}
+				return errResult, nil
 			}
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{
-					&mcp.TextContent{Text: result},
+					&mcp.TextContent{Text: result.Text},
 				},
+				StructuredContent: result.Structured,
 			}, nil
 		})
 
 		serverTool := &mcp.ServerTool{
 			Tool: &mcp.Tool{
-				Name:        toolName,
-				Description: tool.Description,
-				InputSchema: tool.InputSchema,
+				Name:         toolName,
+				Description:  tool.Description,
+				InputSchema:  tool.InputSchema,
+				OutputSchema: tool.OutputSchema,
 			},
 			Handler: handler,
 		}
 		mcpServer.AddTools(serverTool)
 	}
 
+	// List the scripts as resources so clients can browse and read them
+	if scripts != nil {
+		if err := addScriptResources(mcpServer, scripts); err != nil {
+			return nil, err
+		}
+	}
+
 	server := &Server{
 		config:      cfg,
 		logger:      logger,
 		executor:    executor,
 		toolHandler: toolHandler,
 		mcpServer:   mcpServer,
+		closers:     closers,
 	}
 
 	return server, nil
 }
 
+// backend is the executor that runs or replays sqlpp, with the tool options
+// and resources that belong to it
+type backend struct {
+	executor sqlpp.ExecutorInterface
+	caps     *sqlpp.Capabilities
+	toolOpts []tools.Option
+	closers  []io.Closer
+
+	// transactions is set when sessions may keep transactions open
+	transactions *sqlpp.TransactionManager
+	// pool is set when calls run on long-lived sqlpp workers
+	pool *sqlpp.PooledExecutor
+}
+
+// newSqlppBackend creates the executor that runs the installed sqlpp
+func newSqlppBackend(cfg *config.Config, logger *logrus.Logger, o options, sessions *sessionTracker) (*backend, error) {
+	// Create sqlpp executor
+	baseExecutor := sqlpp.NewExecutor(cfg.Sqlpp.GetSqlppExecutablePath(), cfg.Sqlpp.Timeout, logger)
+
+	// Per-connection default timeouts, and the cap on per-call timeout_seconds
+	baseExecutor.SetTimeouts(func(connection string) time.Duration {
+		return time.Duration(cfg.Sqlpp.Connection(connection).Timeout) * time.Second
+	}, time.Duration(cfg.Sqlpp.MaxTimeout)*time.Second)
+
+	// Stop cancelled and timed-out sqlpp process groups with SIGINT, then
+	// SIGKILL once the grace period has passed
+	baseExecutor.SetCancelGracePeriod(time.Duration(cfg.Sqlpp.CancelGrace) * time.Second)
+
+	// Start sqlpp with an explicit config file, working directory and
+	// environment rather than whatever the server was launched with
+	baseExecutor.SetProcessOptions(func(connection string) sqlpp.ProcessOptions {
+		process := cfg.Sqlpp.Process(connection)
+		return sqlpp.ProcessOptions{
+			ConfigFile: process.ConfigFile,
+			WorkingDir: process.WorkingDir,
+			EnvAllow:   process.Env.Allow,
+			EnvSet:     process.Env.Set,
+		}
+	})
+
+	// Log every sqlpp run, then apply any caller-supplied interceptors
+	baseExecutor.Use(sqlpp.LoggingInterceptor(logger))
+	baseExecutor.Use(o.interceptors...)
+
+	// Refuse to start against a sqlpp older than the configured minimum
+	if cfg.Sqlpp.MinVersion != "" {
+		min, err := sqlpp.ParseVersion(cfg.Sqlpp.MinVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sqlpp min_version: %w", err)
+		}
+		baseExecutor.SetMinVersion(min)
+	}
+
+	// Validate sqlpp executable and detect its version and features
+	if err := baseExecutor.ValidateExecutable(context.Background()); err != nil {
+		return nil, fmt.Errorf("sqlpp validation failed: %w", err)
+	}
+	caps := baseExecutor.Capabilities()
+	if usesConfigFile(&cfg.Sqlpp) && !caps.Supports("--config") {
+		return nil, fmt.Errorf("sqlpp config_file is set but sqlpp %s does not support --config", caps.VersionString())
+	}
+
+	b := &backend{
+		caps: caps,
+		toolOpts: []tools.Option{
+			tools.WithQueryRegistry(baseExecutor.Queries()),
+			tools.WithCapabilities(caps),
+		},
+	}
+
+	// Let sessions keep a transaction open across tool calls. It is closed
+	// first so open transactions are rolled back while the rest still works.
+	if tx := cfg.Sqlpp.Transactions; tx.Enabled {
+		if !caps.Lists("--delimiter") {
+			return nil, fmt.Errorf("sqlpp transactions are enabled but sqlpp %s does not support --delimiter", caps.VersionString())
+		}
+		transactions := sqlpp.NewTransactionManager(baseExecutor, sqlpp.TransactionOptions{
+			IdleTimeout:       time.Duration(tx.IdleTimeout) * time.Second,
+			Delimiter:         cfg.Sqlpp.Pool.Delimiter,
+			BeginStatement:    tx.BeginStatement,
+			CommitStatement:   tx.CommitStatement,
+			RollbackStatement: tx.RollbackStatement,
+		})
+		sessions.OnEnd(transactions.EndSession)
+		b.transactions = transactions
+		b.closers = append(b.closers, transactions)
+		b.toolOpts = append(b.toolOpts, tools.WithTransactions(transactions))
+	}
+
+	// Bound in-memory output per call, spilling the excess to disk
+	if cfg.Sqlpp.MaxOutputBytes > 0 {
+		results, err := sqlpp.NewResultStore(cfg.Sqlpp.GetResultDir(), time.Duration(cfg.Sqlpp.ResultTTL)*time.Second, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create result store: %w", err)
+		}
+		baseExecutor.SetOutputLimit(int64(cfg.Sqlpp.MaxOutputBytes), results)
+		sessions.OnEnd(results.ReleaseSession)
+		b.closers = append(b.closers, results)
+		b.toolOpts = append(b.toolOpts, tools.WithResultStore(results), tools.WithCatalogLimit(int64(cfg.Sqlpp.MaxCatalogBytes)))
+	}
+
+	// Record every sqlpp run to a cassette for later replay
+	if cfg.Sqlpp.Cassette.Mode == config.CassetteRecord {
+		recorder, err := sqlpp.NewRecorder(cfg.Sqlpp.GetCassettePath(), logger)
+		if err != nil {
+			return nil, err
+		}
+		baseExecutor.Use(recorder.Interceptor())
+		b.closers = append(b.closers, recorder)
+		logger.WithField("cassette", cfg.Sqlpp.GetCassettePath()).Info("Recording sqlpp runs to cassette")
+	}
+
+	b.executor = baseExecutor
+
+	// Keep long-lived sqlpp workers per session and connection if enabled and
+	// sqlpp speaks the worker protocol; otherwise every call gets its own process
+	pool := cfg.Sqlpp.Pool
+	if pool.Enabled && !caps.Lists("--delimiter") {
+		logger.WithField("version", caps.VersionString()).Warn("sqlpp pool is enabled but sqlpp does not support --delimiter; running each call in a new process")
+	} else if pool.Enabled {
+		b.pool = sqlpp.NewPooledExecutor(baseExecutor, sqlpp.PoolOptions{
+			MaxWorkers:          pool.MaxWorkers,
+			MaxUses:             pool.MaxUses,
+			HealthCheckInterval: time.Duration(pool.HealthCheckInterval) * time.Second,
+			HealthCheckQuery:    pool.HealthCheckQuery,
+			Delimiter:           pool.Delimiter,
+		})
+		sessions.OnEnd(b.pool.EndSession)
+		b.executor = b.pool
+	}
+
+	return b, nil
+}
+
+// newReplayBackend creates an executor that serves responses recorded in a
+// cassette, so the server runs without sqlpp or a database
+func newReplayBackend(cfg *config.Config, logger *logrus.Logger, o options) (*backend, error) {
+	replay, err := sqlpp.NewReplayExecutor(cfg.Sqlpp.GetCassettePath(), logger)
+	if err != nil {
+		return nil, err
+	}
+	replay.Use(sqlpp.LoggingInterceptor(logger))
+	replay.Use(o.interceptors...)
+
+	return &backend{executor: replay}, nil
+}
+
+// addScriptResources registers each script in lib as an MCP resource
+func addScriptResources(mcpServer *mcp.Server, lib *sqlpp.ScriptLibrary) error {
+	scripts, err := lib.List()
+	if err != nil {
+		return err
+	}
+
+	for _, script := range scripts {
+		name := script.Name // Capture for closure
+		uri := (&url.URL{Scheme: scriptURIScheme, Path: "/" + name}).String()
+		mcpServer.AddResources(&mcp.ServerResource{
+			Resource: &mcp.Resource{
+				URI:         uri,
+				Name:        name,
+				Description: "SQL script; run it with execute_sql_file",
+				MIMEType:    "application/sql",
+				Size:        script.Size,
+			},
+			Handler: func(ctx context.Context, session *mcp.ServerSession, params *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error) {
+				_, content, err := lib.Read(name)
+				if errors.Is(err, sqlpp.ErrScriptNotFound) {
+					return nil, mcp.ResourceNotFoundError(params.URI)
+				}
+				if err != nil {
+					return nil, err
+				}
+				return &mcp.ReadResourceResult{
+					Contents: []*mcp.ResourceContents{{URI: params.URI, Text: content}},
+				}, nil
+			},
+		})
+	}
+	return nil
+}
+
+// usesConfigFile reports whether a sqlpp config file is set globally or for
+// any connection
+func usesConfigFile(cfg *config.SqlppConfig) bool {
+	if cfg.ConfigFile != "" {
+		return true
+	}
+	for _, conn := range cfg.Connections {
+		if conn.ConfigFile != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// serverVersion is the version reported in the MCP server info, e.g.
+// "1.0.0+sqlpp.2.3.1"
+func serverVersion(caps *sqlpp.Capabilities) string {
+	if caps == nil || caps.Version == nil {
+		return Version
+	}
+	return fmt.Sprintf("%s+sqlpp.%s", Version, caps.Version)
+}
+
+// progressNotifier forwards sqlpp progress to the client as MCP progress notifications
+func progressNotifier(ctx context.Context, session *mcp.ServerSession, token any, logger *logrus.Logger) sqlpp.ProgressFunc {
+	return func(p sqlpp.Progress) {
+		params := &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      float64(p.Rows),
+			Message:       fmt.Sprintf("%d rows, %d bytes, %s elapsed", p.Rows, p.Bytes, p.Elapsed.Round(time.Millisecond)),
+		}
+		if err := session.NotifyProgress(ctx, params); err != nil {
+			logger.WithError(err).Debug("Failed to send progress notification")
+		}
+	}
+}
+
 // Run starts the MCP server
 func (s *Server) Run(ctx context.Context) error {
 	s.logger.WithFields(logrus.Fields{
@@ -95,6 +460,9 @@ func (s *Server) Run(ctx context.Context) error {
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
+	// Release executor resources and stored results on exit
+	defer s.close()
+
 	switch s.config.Server.Transport {
 	case "stdio":
 		return s.runStdio(ctx)
@@ -179,6 +547,15 @@ func (s *Server) runHTTP(ctx context.Context) error {
 	}
 }
 
+// close releases resources held by the executor and result store
+func (s *Server) close() {
+	for _, closer := range s.closers {
+		if err := closer.Close(); err != nil {
+			s.logger.WithError(err).Warn("Error releasing server resources")
+		}
+	}
+}
+
 // Stop gracefully stops the server
 func (s *Server) Stop() error {
 	s.logger.Info("Stopping MCP server")
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sirupsen/logrus"
+)
+
+// sessionTracker assigns stable identifiers to MCP sessions and runs cleanup
+// hooks when a session ends. The stdio and SSE transports do not provide
+// session IDs of their own.
+type sessionTracker struct {
+	logger *logrus.Logger
+
+	mu    sync.Mutex
+	ids   map[*mcp.ServerSession]string
+	next  int
+	onEnd []func(id string)
+}
+
+func newSessionTracker(logger *logrus.Logger) *sessionTracker {
+	return &sessionTracker{
+		logger: logger,
+		ids:    make(map[*mcp.ServerSession]string),
+	}
+}
+
+// OnEnd registers fn to be called with the session ID when a session ends
+func (t *sessionTracker) OnEnd(fn func(id string)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onEnd = append(t.onEnd, fn)
+}
+
+// ID returns the identifier for session, starting to track it on first use
+func (t *sessionTracker) ID(session *mcp.ServerSession) string {
+	if session == nil {
+		return ""
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if id, ok := t.ids[session]; ok {
+		return id
+	}
+
+	id := session.ID()
+	if id == "" {
+		t.next++
+		id = fmt.Sprintf("session-%d", t.next)
+	}
+	t.ids[session] = id
+
+	go t.watch(session, id)
+
+	return id
+}
+
+// watch waits for session to close and then runs the cleanup hooks
+func (t *sessionTracker) watch(session *mcp.ServerSession, id string) {
+	_ = session.Wait()
+
+	t.mu.Lock()
+	delete(t.ids, session)
+	hooks := append([]func(string){}, t.onEnd...)
+	t.mu.Unlock()
+
+	t.logger.WithField("session", id).Debug("MCP session ended")
+
+	for _, hook := range hooks {
+		hook(id)
+	}
+}
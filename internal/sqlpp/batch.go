@@ -0,0 +1,73 @@
+package sqlpp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Batch is a block of SQL between GO separators
+type Batch struct {
+	SQL    string // batch text, without the separator line
+	Line   int    // 1-based line of the batch's first token
+	Repeat int    // times to run the batch, from "GO n" (1 without a count)
+}
+
+// SplitBatches splits sql on GO separator lines. GO inside strings, quoted
+// identifiers and comments, or sharing a line with other SQL, does not split.
+// Batches with nothing but whitespace and comments are dropped.
+func SplitBatches(sql string) []Batch {
+	var batches []Batch
+	tokens := lexSQL(sql)
+
+	start := 0  // offset where the current batch's text begins
+	first := -1 // index of the current batch's first token
+	for i, tok := range tokens {
+		if tok.start < start {
+			continue // repeat count on the separator line
+		}
+		if !isBatchSeparator(sql, tok) {
+			if first < 0 {
+				first = i
+			}
+			continue
+		}
+
+		lineEnd := indexFrom(sql, tok.end, "\n")
+		if first >= 0 && first < i {
+			batches = append(batches, Batch{
+				SQL:    strings.TrimSpace(sql[start:tok.start]),
+				Line:   lineOf(sql, tokens[first].start),
+				Repeat: repeatCount(sql[tok.end:lineEnd]),
+			})
+		}
+
+		start = lineEnd
+		first = -1
+	}
+
+	if first >= 0 {
+		batches = append(batches, Batch{
+			SQL:    strings.TrimSpace(sql[start:]),
+			Line:   lineOf(sql, tokens[first].start),
+			Repeat: 1,
+		})
+	}
+	return batches
+}
+
+// repeatCount reads the optional count after GO, ignoring a trailing comment
+func repeatCount(rest string) int {
+	if idx := strings.Index(rest, "--"); idx >= 0 {
+		rest = rest[:idx]
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(rest))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// lineOf returns the 1-based line number of offset in sql
+func lineOf(sql string, offset int) int {
+	return strings.Count(sql[:offset], "\n") + 1
+}
@@ -0,0 +1,26 @@
+package sqlpp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitBatches(t *testing.T) {
+	sql := "-- setup\nCREATE TABLE t (s varchar(10))\nGO\n" +
+		"INSERT INTO t VALUES ('a\nGO\nb')\n/* GO\n*/\ngo 3 -- seed rows\n" +
+		"\n-- only a comment\nGO\n" +
+		"SELECT 1 GO\nSELECT \"GO\"\n"
+
+	batches := SplitBatches(sql)
+	assert.Equal(t, []Batch{
+		{SQL: "-- setup\nCREATE TABLE t (s varchar(10))", Line: 2, Repeat: 1},
+		{SQL: "INSERT INTO t VALUES ('a\nGO\nb')\n/* GO\n*/", Line: 4, Repeat: 3},
+		{SQL: "SELECT 1 GO\nSELECT \"GO\"", Line: 13, Repeat: 1},
+	}, batches)
+}
+
+func TestSplitBatches_NoSeparator(t *testing.T) {
+	assert.Equal(t, []Batch{{SQL: "SELECT 1;\nSELECT 2;", Line: 1, Repeat: 1}}, SplitBatches("SELECT 1;\nSELECT 2;\n"))
+	assert.Empty(t, SplitBatches("GO\n-- nothing to run\nGO\n"))
+}
@@ -0,0 +1,262 @@
+package sqlpp
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass returns a context whose schema calls skip cached results.
+// The fresh result replaces the cached one.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// cacheBypassed reports whether the context asks to skip cached results
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// schemaKey identifies a schema command result
+type schemaKey struct {
+	schemaType string
+	connection string
+	filter     string
+	output     string
+}
+
+// callKey identifies a schema command in flight. Calls are only coalesced
+// within a session, since the run belongs to the session that started it.
+type callKey struct {
+	schemaKey
+	session string
+}
+
+// schemaEntry is a cached schema command result
+type schemaEntry struct {
+	result  *types.SqlppResult
+	expires time.Time
+}
+
+// schemaCall is a schema command in flight, shared by identical calls that
+// arrive while it runs
+type schemaCall struct {
+	done    chan struct{}
+	result  *types.SqlppResult
+	err     error
+	waiters int                // callers still waiting; the call is cancelled when none are left
+	cancel  context.CancelFunc // cancels the sqlpp run
+	epoch   uint64             // connection epoch when the call started
+}
+
+// CachingExecutor caches complete, successful schema command results for a
+// TTL, keyed by schema type, connection, filter and output format, and
+// coalesces identical schema calls that arrive while one is running into a
+// single sqlpp run. A connection's results are dropped when DDL runs on it.
+type CachingExecutor struct {
+	next   ExecutorInterface
+	ttl    time.Duration
+	logger *logrus.Logger
+
+	mu       sync.Mutex
+	entries  map[schemaKey]schemaEntry
+	inflight map[callKey]*schemaCall
+	epochs   map[string]uint64 // bumped per connection on invalidation
+
+	// now returns the current time; replaced in tests
+	now func() time.Time
+}
+
+// NewCachingExecutor wraps next with a schema result cache. With a zero ttl
+// results are not kept, but identical concurrent calls are still coalesced.
+func NewCachingExecutor(next ExecutorInterface, ttl time.Duration, logger *logrus.Logger) *CachingExecutor {
+	return &CachingExecutor{
+		next:     next,
+		ttl:      ttl,
+		logger:   logger,
+		entries:  make(map[schemaKey]schemaEntry),
+		inflight: make(map[callKey]*schemaCall),
+		epochs:   make(map[string]uint64),
+		now:      time.Now,
+	}
+}
+
+// ExecuteSchemaCommand returns a cached result if there is a fresh one, joins
+// an identical call in flight from the same session, or runs the command. A
+// shared run carries only the session, so it is bound by the connection
+// timeout and is cancelled only when every caller waiting for it has gone.
+// Calls with their own timeout or progress reporting are not shared.
+func (c *CachingExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	key := schemaKey{schemaType: schemaType, connection: connection, filter: filter, output: output}
+	bypass := cacheBypassed(ctx)
+	shared := callTimeoutFrom(ctx) == 0 && progressFrom(ctx) == nil
+	inflightKey := callKey{schemaKey: key, session: SessionIDFrom(ctx)}
+
+	c.mu.Lock()
+	if !bypass {
+		if entry, ok := c.entries[key]; ok && c.now().Before(entry.expires) {
+			c.mu.Unlock()
+			c.logger.WithFields(logrus.Fields{
+				"schema_type": schemaType,
+				"connection":  connection,
+			}).Debug("Schema cache hit")
+			result := *entry.result
+			result.Cached = true
+			return &result, nil
+		}
+		if call, ok := c.inflight[inflightKey]; ok && shared {
+			call.waiters++
+			c.mu.Unlock()
+			return c.wait(ctx, inflightKey, call)
+		}
+	}
+	epoch := c.epochs[connection]
+
+	if !shared {
+		c.mu.Unlock()
+		result, err := c.next.ExecuteSchemaCommand(ctx, schemaType, connection, filter, output)
+		c.mu.Lock()
+		c.store(key, epoch, result, err)
+		c.mu.Unlock()
+		return result, err
+	}
+
+	// Start the call detached from ctx, so one caller going away does not
+	// fail the others that joined it, and keep none of the caller's context
+	// values but its session
+	runCtx, cancel := context.WithCancel(WithSessionID(context.Background(), inflightKey.session))
+	call := &schemaCall{
+		done:    make(chan struct{}),
+		waiters: 1,
+		cancel:  cancel,
+		epoch:   epoch,
+	}
+	c.inflight[inflightKey] = call
+	c.mu.Unlock()
+
+	go func() {
+		result, err := c.next.ExecuteSchemaCommand(runCtx, schemaType, connection, filter, output)
+		cancel()
+
+		c.mu.Lock()
+		call.result, call.err = result, err
+		if c.inflight[inflightKey] == call {
+			delete(c.inflight, inflightKey)
+		}
+		c.store(key, call.epoch, result, err)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	return c.wait(ctx, inflightKey, call)
+}
+
+// store caches a result of a run started at connection epoch epoch; the
+// caller holds c.mu. Results started before DDL ran on the connection may be
+// stale, and spilled output belongs to the session that produced it.
+func (c *CachingExecutor) store(key schemaKey, epoch uint64, result *types.SqlppResult, err error) {
+	if err == nil && result.Success && !result.Truncated && c.ttl > 0 && c.epochs[key.connection] == epoch {
+		stored := *result
+		c.removeExpired()
+		c.entries[key] = schemaEntry{result: &stored, expires: c.now().Add(c.ttl)}
+	}
+}
+
+// wait waits for call to finish or ctx to end. Each caller gets its own copy
+// of the result, since callers annotate results.
+func (c *CachingExecutor) wait(ctx context.Context, key callKey, call *schemaCall) (*types.SqlppResult, error) {
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		result := *call.result
+		return &result, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// A cancelled call may take a while to end; calls arriving
+			// meanwhile must start their own run rather than join it
+			call.cancel()
+			if c.inflight[key] == call {
+				delete(c.inflight, key)
+			}
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// ExecuteSQLCommand implements ExecutorInterface, dropping the connection's
+// cached schema results when the command contains DDL
+func (c *CachingExecutor) ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error) {
+	result, err := c.next.ExecuteSQLCommand(ctx, connection, command, output)
+	// Failed DDL may still have changed the schema, e.g. part of a batch
+	if IsDDL(command) {
+		c.Invalidate(connection)
+	}
+	return result, err
+}
+
+// Invalidate drops the cached schema results of connection. Calls already
+// running for it finish, but their results are not cached.
+func (c *CachingExecutor) Invalidate(connection string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epochs[connection]++
+	dropped := 0
+	for key := range c.entries {
+		if key.connection == connection {
+			delete(c.entries, key)
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		c.logger.WithFields(logrus.Fields{
+			"connection": connection,
+			"entries":    dropped,
+		}).Debug("Schema cache invalidated")
+	}
+}
+
+// removeExpired drops expired entries; the caller holds c.mu
+func (c *CachingExecutor) removeExpired() {
+	now := c.now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// ListConnections implements ExecutorInterface
+func (c *CachingExecutor) ListConnections(ctx context.Context) (*types.SqlppResult, error) {
+	return c.next.ListConnections(ctx)
+}
+
+// ListDrivers implements ExecutorInterface
+func (c *CachingExecutor) ListDrivers(ctx context.Context) (*types.SqlppResult, error) {
+	return c.next.ListDrivers(ctx)
+}
+
+// ValidateExecutable implements ExecutorInterface
+func (c *CachingExecutor) ValidateExecutable(ctx context.Context) error {
+	return c.next.ValidateExecutable(ctx)
+}
+
+// Close releases the resources of the wrapped executor
+func (c *CachingExecutor) Close() error {
+	if closer, ok := c.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
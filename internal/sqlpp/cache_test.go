@@ -0,0 +1,285 @@
+package sqlpp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(next ExecutorInterface, ttl time.Duration) (*CachingExecutor, *time.Time) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	c := NewCachingExecutor(next, ttl, logger)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+// waiters returns how many callers wait for the schema call in flight for key
+// outside any session
+func (c *CachingExecutor) waiters(key schemaKey) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if call, ok := c.inflight[callKey{schemaKey: key}]; ok {
+		return call.waiters
+	}
+	return 0
+}
+
+func TestCachingExecutor_TTLAndRefresh(t *testing.T) {
+	backend := &scriptedExecutor{results: []*types.SqlppResult{
+		{Success: true, Output: "v1"},
+		{Success: true, Output: "v2"},
+		{Success: true, Output: "v3"},
+	}}
+	cache, now := newTestCache(backend, time.Minute)
+	ctx := context.Background()
+
+	result, err := cache.ExecuteSchemaCommand(ctx, "tables", "main", "", "json")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", result.Output)
+	assert.False(t, result.Cached)
+
+	result, err = cache.ExecuteSchemaCommand(ctx, "tables", "main", "", "json")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", result.Output)
+	assert.True(t, result.Cached)
+	assert.Equal(t, 1, backend.calls)
+
+	// Filter and output are part of the key
+	_, err = cache.ExecuteSchemaCommand(ctx, "tables", "main", "user%", "json")
+	require.NoError(t, err)
+	assert.Equal(t, 2, backend.calls)
+
+	// Refresh runs sqlpp and replaces the cached result
+	result, err = cache.ExecuteSchemaCommand(WithCacheBypass(ctx), "tables", "main", "", "json")
+	require.NoError(t, err)
+	assert.Equal(t, "v3", result.Output)
+	assert.False(t, result.Cached)
+
+	result, err = cache.ExecuteSchemaCommand(ctx, "tables", "main", "", "json")
+	require.NoError(t, err)
+	assert.Equal(t, "v3", result.Output)
+	assert.Equal(t, 3, backend.calls)
+
+	// Expired results are fetched again
+	*now = now.Add(time.Minute)
+	_, err = cache.ExecuteSchemaCommand(ctx, "tables", "main", "", "json")
+	require.NoError(t, err)
+	assert.Equal(t, 4, backend.calls)
+}
+
+func TestCachingExecutor_SkipsFailures(t *testing.T) {
+	backend := &scriptedExecutor{results: []*types.SqlppResult{syntaxResult, {Success: true, Output: "big", Truncated: true}, okResult}}
+	cache, _ := newTestCache(backend, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.ExecuteSchemaCommand(context.Background(), "tables", "main", "", "")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, backend.calls)
+}
+
+func TestCachingExecutor_DDLInvalidates(t *testing.T) {
+	backend := &scriptedExecutor{results: []*types.SqlppResult{okResult}}
+	cache, _ := newTestCache(backend, time.Minute)
+	ctx := context.Background()
+
+	for _, connection := range []string{"main", "reporting"} {
+		_, err := cache.ExecuteSchemaCommand(ctx, "tables", connection, "", "")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, backend.calls)
+
+	// Queries leave the cache alone
+	_, err := cache.ExecuteSQLCommand(ctx, "main", "SELECT * FROM users", "")
+	require.NoError(t, err)
+	_, err = cache.ExecuteSchemaCommand(ctx, "tables", "main", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, backend.calls)
+
+	// DDL drops the results of its connection only
+	_, err = cache.ExecuteSQLCommand(ctx, "main", "ALTER TABLE users ADD email text", "")
+	require.NoError(t, err)
+	assert.Equal(t, 4, backend.calls)
+
+	result, err := cache.ExecuteSchemaCommand(ctx, "tables", "main", "", "")
+	require.NoError(t, err)
+	assert.False(t, result.Cached)
+	result, err = cache.ExecuteSchemaCommand(ctx, "tables", "reporting", "", "")
+	require.NoError(t, err)
+	assert.True(t, result.Cached)
+	assert.Equal(t, 5, backend.calls)
+}
+
+func TestCachingExecutor_CoalescesConcurrentCalls(t *testing.T) {
+	backend := newBlockingExecutor()
+	cache, _ := newTestCache(backend, time.Minute)
+	key := schemaKey{schemaType: "tables", connection: "main"}
+
+	var wg sync.WaitGroup
+	results := make([]*types.SqlppResult, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := cache.ExecuteSchemaCommand(context.Background(), "tables", "main", "", "")
+			assert.NoError(t, err)
+			results[i] = result
+		}(i)
+	}
+
+	assert.Equal(t, "main:tables", <-backend.started)
+	require.Eventually(t, func() bool { return cache.waiters(key) == 3 }, time.Second, time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+
+	assert.Empty(t, backend.started, "only one sqlpp run")
+	for _, result := range results {
+		require.NotNil(t, result)
+		assert.Equal(t, "main:tables", result.Output)
+	}
+	// Each caller gets its own copy
+	results[0].Attempts = 5
+	assert.Zero(t, results[1].Attempts)
+}
+
+func TestCachingExecutor_InvalidateDuringCall(t *testing.T) {
+	backend := newBlockingExecutor()
+	cache, _ := newTestCache(backend, time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := cache.ExecuteSchemaCommand(context.Background(), "tables", "main", "", "")
+		assert.NoError(t, err)
+	}()
+	<-backend.started
+
+	// The schema changed while the call ran, so its result is not kept
+	cache.Invalidate("main")
+	close(backend.release)
+	<-done
+
+	result, err := cache.ExecuteSchemaCommand(context.Background(), "tables", "main", "", "")
+	require.NoError(t, err)
+	assert.False(t, result.Cached)
+	assert.Len(t, backend.started, 1)
+}
+
+// cancellableExecutor blocks schema calls until their context ends, and
+// then until released
+type cancellableExecutor struct {
+	blockingExecutor
+	cancelled chan struct{}
+}
+
+func (c *cancellableExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	c.started <- connection
+	<-ctx.Done()
+	close(c.cancelled)
+	<-c.release
+	return nil, ctx.Err()
+}
+
+func TestCachingExecutor_CancelsWhenAllCallersLeave(t *testing.T) {
+	backend := &cancellableExecutor{blockingExecutor: *newBlockingExecutor(), cancelled: make(chan struct{})}
+	cache, _ := newTestCache(backend, time.Minute)
+	key := schemaKey{schemaType: "tables", connection: "main"}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	for _, ctx := range []context.Context{first, second} {
+		go func(ctx context.Context) {
+			_, err := cache.ExecuteSchemaCommand(ctx, "tables", "main", "", "")
+			errs <- err
+		}(ctx)
+	}
+	<-backend.started
+	require.Eventually(t, func() bool { return cache.waiters(key) == 2 }, time.Second, time.Millisecond)
+
+	// The first caller leaving does not stop the run for the second
+	cancelFirst()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	select {
+	case <-backend.cancelled:
+		t.Fatal("run cancelled while a caller was still waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelSecond()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	select {
+	case <-backend.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("run not cancelled after every caller left")
+	}
+
+	// The cancelled run is still ending, but new calls do not join it
+	cache.mu.Lock()
+	_, joinable := cache.inflight[callKey{schemaKey: key}]
+	cache.mu.Unlock()
+	assert.False(t, joinable)
+	close(backend.release)
+}
+
+// contextExecutor records the context of each schema run
+type contextExecutor struct {
+	blockingExecutor
+	contexts chan context.Context
+}
+
+func (c *contextExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	c.contexts <- ctx
+	return c.blockingExecutor.ExecuteSchemaCommand(ctx, schemaType, connection, filter, output)
+}
+
+func TestCachingExecutor_SharedRunContext(t *testing.T) {
+	backend := &contextExecutor{blockingExecutor: *newBlockingExecutor(), contexts: make(chan context.Context, 10)}
+	cache, _ := newTestCache(backend, 0)
+
+	calls := []context.Context{
+		WithSessionID(context.Background(), "session-1"),
+		WithSessionID(context.Background(), "session-1"),
+		WithSessionID(context.Background(), "session-2"),
+		WithCallTimeout(WithSessionID(context.Background(), "session-1"), time.Second),
+	}
+	var wg sync.WaitGroup
+	for _, ctx := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.ExecuteSchemaCommand(ctx, "tables", "main", "", "")
+			assert.NoError(t, err)
+		}()
+	}
+
+	// Sessions do not share runs, and a call with its own timeout runs alone
+	var runs []context.Context
+	for range 3 {
+		runs = append(runs, <-backend.contexts)
+	}
+	close(backend.release)
+	wg.Wait()
+	assert.Empty(t, backend.contexts, "the session-1 calls share one run")
+
+	// Shared runs carry the session and nothing else of the caller's context
+	var sessions, timeouts []string
+	for _, ctx := range runs {
+		if callTimeoutFrom(ctx) > 0 {
+			timeouts = append(timeouts, SessionIDFrom(ctx))
+		} else {
+			sessions = append(sessions, SessionIDFrom(ctx))
+		}
+	}
+	assert.Equal(t, []string{"session-1"}, timeouts)
+	assert.ElementsMatch(t, []string{"session-1", "session-2"}, sessions)
+}
@@ -0,0 +1,274 @@
+package sqlpp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// ErrNotRecorded is returned in replay mode for a call the cassette has no
+// response for
+var ErrNotRecorded = errors.New("no recorded sqlpp response")
+
+// maxCassetteLine bounds a single recorded interaction when loading a cassette
+const maxCassetteLine = 64 * 1024 * 1024
+
+// Interaction is one recorded sqlpp run. A cassette file holds one
+// interaction per line as JSON.
+type Interaction struct {
+	Operation  Operation `json:"operation"`
+	Connection string    `json:"connection,omitempty"`
+	Args       []string  `json:"args"`
+	Stdin      string    `json:"stdin,omitempty"`
+	Stdout     string    `json:"stdout"`
+	Stderr     string    `json:"stderr,omitempty"`
+	ExitCode   int       `json:"exit_code"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	ErrorCode  string    `json:"error_code,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// key identifies the call an interaction answers
+func (i *Interaction) key() string {
+	return interactionKey(i.Args, i.Stdin)
+}
+
+func interactionKey(args []string, stdin string) string {
+	return strings.Join(args, "\x00") + "\x01" + stdin
+}
+
+// result rebuilds the sqlpp result the interaction recorded
+func (i *Interaction) result() *types.SqlppResult {
+	return &types.SqlppResult{
+		Success:    i.Error == "",
+		Output:     i.Stdout,
+		OutputSize: int64(len(i.Stdout)),
+		Error:      i.Error,
+		ErrorCode:  i.ErrorCode,
+		ExitCode:   i.ExitCode,
+		DurationMs: i.DurationMs,
+		Args:       i.Args,
+		Stderr:     i.Stderr,
+	}
+}
+
+// Recorder appends every sqlpp run it sees to a cassette file
+type Recorder struct {
+	logger *logrus.Logger
+
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewRecorder opens path for appending recorded interactions, creating it if
+// needed. Cassettes hold query text and results, so the file is private to
+// the server's user.
+func NewRecorder(path string, logger *logrus.Logger) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	return &Recorder{logger: logger, file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Interceptor returns an interceptor that records each completed run.
+// Output spilled to disk beyond the in-memory limit is not recorded.
+func (r *Recorder) Interceptor() Interceptor {
+	return func(next Runner) Runner {
+		return func(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+			result, err := next(ctx, req)
+			if err != nil {
+				return result, err
+			}
+
+			interaction := Interaction{
+				Operation:  req.Operation,
+				Connection: req.Connection,
+				Args:       req.Args,
+				Stdout:     result.Output,
+				Stderr:     result.Stderr,
+				ExitCode:   result.ExitCode,
+				DurationMs: result.DurationMs,
+				Error:      result.Error,
+				ErrorCode:  result.ErrorCode,
+				RecordedAt: time.Now().UTC(),
+			}
+			if req.Stdin {
+				interaction.Stdin = req.Input
+			}
+
+			if err := r.Record(interaction); err != nil {
+				r.logger.WithError(err).Warn("Failed to record sqlpp interaction")
+			}
+			return result, nil
+		}
+	}
+}
+
+// Record appends an interaction to the cassette
+func (r *Recorder) Record(interaction Interaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return errors.New("cassette is closed")
+	}
+	return r.enc.Encode(interaction)
+}
+
+// Close closes the cassette file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// LoadCassette reads the interactions recorded in a cassette file
+func LoadCassette(path string) ([]Interaction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer file.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxCassetteLine)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("invalid cassette entry on line %d: %w", line, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	return interactions, nil
+}
+
+// ReplayExecutor serves sqlpp responses from a cassette without running
+// sqlpp. Calls are matched on their arguments and input; repeated calls get
+// the recorded responses in order, and the last one once they run out.
+type ReplayExecutor struct {
+	logger       *logrus.Logger
+	interceptors []Interceptor
+
+	mu        sync.Mutex
+	responses map[string][]Interaction
+	served    map[string]int
+}
+
+// NewReplayExecutor loads the cassette at path
+func NewReplayExecutor(path string, logger *logrus.Logger) (*ReplayExecutor, error) {
+	interactions, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make(map[string][]Interaction)
+	for _, interaction := range interactions {
+		key := interaction.key()
+		responses[key] = append(responses[key], interaction)
+	}
+
+	logger.WithFields(logrus.Fields{
+		"cassette":     path,
+		"interactions": len(interactions),
+	}).Info("Replaying sqlpp responses from cassette")
+
+	return &ReplayExecutor{
+		logger:    logger,
+		responses: responses,
+		served:    make(map[string]int),
+	}, nil
+}
+
+// Use appends interceptors around every replayed run, as Executor.Use does
+func (r *ReplayExecutor) Use(interceptors ...Interceptor) {
+	r.interceptors = append(r.interceptors, interceptors...)
+}
+
+// ExecuteSchemaCommand implements ExecutorInterface
+func (r *ReplayExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	return r.invoke(ctx, stdinRequest(OpSchema, schemaCommand(schemaType, filter), connection, output))
+}
+
+// ExecuteSQLCommand implements ExecutorInterface
+func (r *ReplayExecutor) ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error) {
+	return r.invoke(ctx, stdinRequest(OpSQL, command, connection, output))
+}
+
+// ListConnections implements ExecutorInterface
+func (r *ReplayExecutor) ListConnections(ctx context.Context) (*types.SqlppResult, error) {
+	return r.invoke(ctx, &Request{Operation: OpListConnections, Args: []string{"--list-connections"}})
+}
+
+// ListDrivers implements ExecutorInterface
+func (r *ReplayExecutor) ListDrivers(ctx context.Context) (*types.SqlppResult, error) {
+	return r.invoke(ctx, stdinRequest(OpListDrivers, "@drivers", "", ""))
+}
+
+// ValidateExecutable implements ExecutorInterface; replay needs no sqlpp
+func (r *ReplayExecutor) ValidateExecutable(ctx context.Context) error {
+	return nil
+}
+
+// invoke runs req through the interceptor chain around the replay lookup
+func (r *ReplayExecutor) invoke(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+	return chain(r.interceptors, r.replay)(ctx, req)
+}
+
+// replay is the core runner: it returns the next recorded response for req
+func (r *ReplayExecutor) replay(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	input := ""
+	if req.Stdin {
+		input = req.Input
+	}
+	key := interactionKey(req.Args, input)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recorded := r.responses[key]
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("%w for sqlpp %s", ErrNotRecorded, describeRequest(req))
+	}
+
+	i := min(r.served[key], len(recorded)-1)
+	r.served[key]++
+	return recorded[i].result(), nil
+}
+
+// describeRequest summarises a request for error messages
+func describeRequest(req *Request) string {
+	description := strings.Join(req.Args, " ")
+	if req.Stdin {
+		description += fmt.Sprintf(" with input %q", truncateForLogging(req.Input))
+	}
+	return description
+}
@@ -0,0 +1,123 @@
+package sqlpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	// Create a mock sqlpp executable
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+	cassette := filepath.Join(tmpDir, "session.cassette")
+
+	mockScript := `#!/bin/bash
+if [[ "$1" == "--list-connections" ]]; then
+    echo '["main"]'
+    exit 0
+fi
+input=$(cat)
+if [[ "$input" == "SELECT broken" ]]; then
+    echo "syntax error at or near broken" >&2
+    exit 1
+fi
+echo "result for: $input"
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	recorder, err := NewRecorder(cassette, logger)
+	require.NoError(t, err)
+
+	executor := NewExecutor(mockSqlpp, 30, logger)
+	executor.Use(recorder.Interceptor())
+
+	ctx := context.Background()
+	_, err = executor.ExecuteSQLCommand(ctx, "main", "SELECT 1", "json")
+	require.NoError(t, err)
+	_, err = executor.ExecuteSQLCommand(ctx, "main", "SELECT broken", "")
+	require.NoError(t, err)
+	_, err = executor.ListConnections(ctx)
+	require.NoError(t, err)
+	require.NoError(t, recorder.Close())
+
+	interactions, err := LoadCassette(cassette)
+	require.NoError(t, err)
+	require.Len(t, interactions, 3)
+	assert.Equal(t, OpSQL, interactions[0].Operation)
+	assert.Equal(t, "main", interactions[0].Connection)
+	assert.Equal(t, []string{"--stdin", "--connection", "main", "--output", "json"}, interactions[0].Args)
+	assert.Equal(t, "SELECT 1", interactions[0].Stdin)
+	assert.Equal(t, "result for: SELECT 1", interactions[0].Stdout)
+	assert.Equal(t, 1, interactions[1].ExitCode)
+	assert.Equal(t, "syntax error at or near broken", interactions[1].Stderr)
+
+	// Replay serves the same results without sqlpp
+	replay, err := NewReplayExecutor(cassette, logger)
+	require.NoError(t, err)
+	require.NoError(t, replay.ValidateExecutable(ctx))
+
+	result, err := replay.ExecuteSQLCommand(ctx, "main", "SELECT 1", "json")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "result for: SELECT 1", result.Output)
+
+	result, err = replay.ExecuteSQLCommand(ctx, "main", "SELECT broken", "")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, string(CodeSyntaxError), result.ErrorCode)
+
+	result, err = replay.ListConnections(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, `["main"]`, result.Output)
+
+	// Calls that were never recorded fail clearly
+	_, err = replay.ExecuteSQLCommand(ctx, "main", "SELECT 2", "json")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotRecorded)
+	assert.Contains(t, err.Error(), `with input "SELECT 2"`)
+}
+
+func TestReplayExecutor_RepeatedCalls(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "session.cassette")
+
+	recorder, err := NewRecorder(cassette, logrus.New())
+	require.NoError(t, err)
+	args := []string{"--stdin", "--connection", "main"}
+	require.NoError(t, recorder.Record(Interaction{Operation: OpSQL, Args: args, Stdin: "SELECT count(*) FROM t", Stdout: "1"}))
+	require.NoError(t, recorder.Record(Interaction{Operation: OpSQL, Args: args, Stdin: "SELECT count(*) FROM t", Stdout: "2"}))
+	require.NoError(t, recorder.Close())
+
+	replay, err := NewReplayExecutor(cassette, logrus.New())
+	require.NoError(t, err)
+
+	// Responses are served in recorded order, then the last one repeats
+	for _, expected := range []string{"1", "2", "2"} {
+		result, err := replay.ExecuteSQLCommand(context.Background(), "main", "SELECT count(*) FROM t", "")
+		require.NoError(t, err)
+		assert.Equal(t, expected, result.Output)
+	}
+}
+
+func TestLoadCassette_Invalid(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "broken.cassette")
+	err := os.WriteFile(cassette, []byte("{\"operation\":\"sql\",\"args\":[]}\nnot json\n"), 0600)
+	require.NoError(t, err)
+
+	_, err = LoadCassette(cassette)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid cassette entry on line 2")
+
+	_, err = NewReplayExecutor(filepath.Join(t.TempDir(), "missing.cassette"), logrus.New())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to open cassette")
+}
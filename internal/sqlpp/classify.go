@@ -0,0 +1,114 @@
+package sqlpp
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os/exec"
+	"regexp"
+
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// ErrorCode is a stable, machine-readable category for a failed sqlpp call
+type ErrorCode string
+
+const (
+	CodeUnknownConnection ErrorCode = "unknown_connection"
+	CodeAuthFailed        ErrorCode = "auth_failed"
+	CodeSyntaxError       ErrorCode = "syntax_error"
+	CodePermissionDenied  ErrorCode = "permission_denied"
+	CodeTimeout           ErrorCode = "timeout"
+	CodeCancelled         ErrorCode = "cancelled"
+	CodeExecutableMissing ErrorCode = "executable_missing"
+	CodeDriverError       ErrorCode = "driver_error"
+	CodeConnectionFailed  ErrorCode = "connection_failed"
+	CodeDeadlock          ErrorCode = "deadlock"
+	CodeSerialization     ErrorCode = "serialization_failure"
+	CodeBusy              ErrorCode = "busy"
+	CodeUnknown           ErrorCode = "unknown"
+)
+
+// Transient reports whether a failure in this category may succeed if the
+// same command is simply run again
+func (c ErrorCode) Transient() bool {
+	switch c {
+	case CodeConnectionFailed, CodeDeadlock, CodeSerialization:
+		return true
+	default:
+		return false
+	}
+}
+
+// exitCodeNotFound is the status shells and wrappers use when a command
+// cannot be found
+const exitCodeNotFound = 127
+
+// stderrPattern maps a stderr pattern to a category
+type stderrPattern struct {
+	code    ErrorCode
+	pattern *regexp.Regexp
+}
+
+// stderrPatterns are checked in order, so more specific patterns come first.
+// They cover the wording of sqlpp itself and of the common database drivers.
+var stderrPatterns = []stderrPattern{
+	{CodeUnknownConnection, regexp.MustCompile(`(?i)(unknown|undefined|no such|invalid) connection|connection\s+\S+\s+(is\s+)?(not found|not defined|not configured|does not exist)`)},
+	{CodeDriverError, regexp.MustCompile(`(?i)unknown driver|driver\s+\S*\s*(not found|not supported|not registered|is not available)|no driver|failed to load driver`)},
+	{CodeAuthFailed, regexp.MustCompile(`(?i)authentication failed|login failed|access denied for user|invalid (username|user name|password|credentials)|ORA-01017|SQLSTATE 28(000|P01)`)},
+	{CodeDeadlock, regexp.MustCompile(`(?i)deadlock|ORA-00060|SQLSTATE 40P01`)},
+	{CodeSerialization, regexp.MustCompile(`(?i)could not serialize access|serialization failure|ORA-08177|SQLSTATE 40001`)},
+	{CodePermissionDenied, regexp.MustCompile(`(?i)permission denied|insufficient privilege|not authorized|command denied|access denied|ORA-01031|SQLSTATE 42501`)},
+	{CodeSyntaxError, regexp.MustCompile(`(?i)syntax error|incorrect syntax|error in your SQL syntax|ORA-009\d\d|SQLSTATE 42601|unterminated|unexpected token`)},
+	{CodeConnectionFailed, regexp.MustCompile(`(?i)connection refused|connection reset|could not connect|unable to connect|failed to connect|no route to host|network is unreachable|server closed the connection|too many connections|i/o timeout|SQLSTATE 08\d{3}`)},
+}
+
+// ClassifyResult returns the category of a failed sqlpp result, or an empty
+// code if the result succeeded
+func ClassifyResult(ctx context.Context, result *types.SqlppResult) ErrorCode {
+	if result.Success {
+		return ""
+	}
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return CodeTimeout
+	case context.Canceled:
+		return CodeCancelled
+	}
+
+	if result.ExitCode == exitCodeNotFound {
+		return CodeExecutableMissing
+	}
+
+	message := result.Stderr
+	if message == "" {
+		message = result.Error
+	}
+	for _, p := range stderrPatterns {
+		if p.pattern.MatchString(message) {
+			return p.code
+		}
+	}
+
+	return CodeUnknown
+}
+
+// ClassifyError returns the category of an error returned instead of a
+// result, or an empty code if the error is not a recognised sqlpp failure
+func ClassifyError(err error) ErrorCode {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrPermission):
+		return CodeExecutableMissing
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrQueueTimeout):
+		return CodeBusy
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, context.Canceled):
+		return CodeCancelled
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,76 @@
+package sqlpp
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		stderr   string
+		expected ErrorCode
+	}{
+		{"unknown connection", 1, "Error: connection 'reporting' not found in configuration", CodeUnknownConnection},
+		{"unknown connection wording", 1, "unknown connection: reporting", CodeUnknownConnection},
+		{"postgres auth", 1, `pq: password authentication failed for user "app"`, CodeAuthFailed},
+		{"mysql auth", 1, "Error 1045: Access denied for user 'app'@'localhost' (using password: YES)", CodeAuthFailed},
+		{"sql server auth", 1, "mssql: Login failed for user 'app'.", CodeAuthFailed},
+		{"postgres permission", 1, "pq: permission denied for table users", CodePermissionDenied},
+		{"mysql permission", 1, "Error 1142: SELECT command denied to user 'app'", CodePermissionDenied},
+		{"postgres syntax", 1, `pq: syntax error at or near "SELEC"`, CodeSyntaxError},
+		{"mysql syntax", 1, "Error 1064: You have an error in your SQL syntax", CodeSyntaxError},
+		{"sql server syntax", 1, "mssql: Incorrect syntax near 'FROM'.", CodeSyntaxError},
+		{"driver", 1, "sql: unknown driver \"oracle\" (forgotten import?)", CodeDriverError},
+		{"connection refused", 1, "dial tcp 127.0.0.1:5432: connect: connection refused", CodeConnectionFailed},
+		{"postgres deadlock", 1, "pq: deadlock detected", CodeDeadlock},
+		{"sql server deadlock", 1, "mssql: Transaction (Process ID 52) was deadlocked on lock resources with another process and has been chosen as the deadlock victim.", CodeDeadlock},
+		{"postgres serialization", 1, "pq: could not serialize access due to concurrent update", CodeSerialization},
+		{"command not found", 127, "", CodeExecutableMissing},
+		{"unrecognised", 1, "something unexpected happened", CodeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &types.SqlppResult{ExitCode: tt.exitCode, Stderr: tt.stderr, Error: tt.stderr}
+			assert.Equal(t, tt.expected, ClassifyResult(context.Background(), result))
+		})
+	}
+}
+
+func TestClassifyResult_Context(t *testing.T) {
+	result := &types.SqlppResult{ExitCode: -1, Error: "sqlpp command timed out"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	assert.Equal(t, CodeTimeout, ClassifyResult(ctx, result))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, CodeCancelled, ClassifyResult(ctx, result))
+
+	assert.Empty(t, ClassifyResult(ctx, &types.SqlppResult{Success: true}))
+}
+
+func TestErrorCode_Transient(t *testing.T) {
+	assert.True(t, CodeConnectionFailed.Transient())
+	assert.True(t, CodeDeadlock.Transient())
+	assert.True(t, CodeSerialization.Transient())
+	assert.False(t, CodeSyntaxError.Transient())
+	assert.False(t, CodeTimeout.Transient())
+}
+
+func TestClassifyError(t *testing.T) {
+	assert.Empty(t, ClassifyError(nil))
+	assert.Equal(t, CodeExecutableMissing, ClassifyError(fmt.Errorf("failed to start command: %w", exec.ErrNotFound)))
+	assert.Equal(t, CodeBusy, ClassifyError(fmt.Errorf("wrapped: %w", ErrQueueFull)))
+	assert.Equal(t, CodeBusy, ClassifyError(ErrQueueTimeout))
+	assert.Equal(t, CodeCancelled, ClassifyError(fmt.Errorf("cancelled: %w", context.Canceled)))
+	assert.Empty(t, ClassifyError(fmt.Errorf("some other failure")))
+}
@@ -0,0 +1,123 @@
+package sqlpp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// ParseConnections reads the connections from sqlpp --list-connections output
+func ParseConnections(output string) ([]types.Connection, error) {
+	records, err := parseRecords(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection list: %w", err)
+	}
+
+	connections := make([]types.Connection, 0, len(records))
+	for _, record := range records {
+		if record["name"] == "" {
+			continue
+		}
+		connections = append(connections, types.Connection{
+			Name:   record["name"],
+			Driver: record["driver"],
+			Status: record["status"],
+		})
+	}
+	return connections, nil
+}
+
+// ParseDrivers reads the drivers from sqlpp @drivers output
+func ParseDrivers(output string) ([]types.Driver, error) {
+	records, err := parseRecords(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse driver list: %w", err)
+	}
+
+	drivers := make([]types.Driver, 0, len(records))
+	for _, record := range records {
+		if record["name"] == "" {
+			continue
+		}
+		drivers = append(drivers, types.Driver{
+			Name:        record["name"],
+			Description: record["description"],
+			Version:     record["version"],
+		})
+	}
+	return drivers, nil
+}
+
+// parseRecords reads sqlpp list output, either a JSON array of objects or a
+// table with a header row, into records keyed by lower-case column name
+func parseRecords(output string) ([]map[string]string, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(output, "[") {
+		var rows []map[string]interface{}
+		if err := json.Unmarshal([]byte(output), &rows); err != nil {
+			return nil, err
+		}
+		records := make([]map[string]string, len(rows))
+		for i, row := range rows {
+			records[i] = make(map[string]string, len(row))
+			for key, value := range row {
+				if value != nil {
+					records[i][strings.ToLower(key)] = fmt.Sprint(value)
+				}
+			}
+		}
+		return records, nil
+	}
+
+	var header []string
+	var records []map[string]string
+	for _, line := range strings.Split(output, "\n") {
+		cells := tableCells(line)
+		if cells == nil {
+			continue
+		}
+		if header == nil {
+			for _, cell := range cells {
+				header = append(header, strings.ToLower(cell))
+			}
+			continue
+		}
+		record := make(map[string]string, len(header))
+		for i, cell := range cells {
+			if i < len(header) {
+				record[header[i]] = cell
+			}
+		}
+		records = append(records, record)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("no header row")
+	}
+	return records, nil
+}
+
+// tableCells splits a table row into trimmed cells. Rows with | separators
+// are split on them, others on whitespace. Blank lines and border lines
+// return nil.
+func tableCells(line string) []string {
+	if strings.Trim(line, " \t\r-+=|│─┼├┤┌┐└┘┬┴") == "" {
+		return nil
+	}
+
+	if !strings.ContainsAny(line, "|│") {
+		return strings.Fields(line)
+	}
+
+	line = strings.ReplaceAll(strings.TrimSpace(line), "│", "|")
+	cells := strings.Split(strings.Trim(line, "|"), "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
@@ -0,0 +1,37 @@
+package sqlpp
+
+import (
+	"testing"
+
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConnections(t *testing.T) {
+	expected := []types.Connection{
+		{Name: "main", Driver: "sqlite3"},
+		{Name: "reporting", Driver: "postgres"},
+	}
+
+	outputs := map[string]string{
+		"json":  `[{"name": "main", "driver": "sqlite3", "is_default": true}, {"name": "reporting", "driver": "postgres", "notes": null}]`,
+		"plain": "NAME       DRIVER     NOTES\nmain       sqlite3    Local database\nreporting  postgres   Reporting replica\n",
+		"table": "+-----------+----------+-------+\n| name      | driver   | notes |\n+-----------+----------+-------+\n| main      | sqlite3  |       |\n| reporting | postgres | dev   |\n+-----------+----------+-------+\n",
+	}
+
+	for name, output := range outputs {
+		connections, err := ParseConnections(output)
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, connections, name)
+	}
+
+	_, err := ParseConnections("[not json")
+	assert.Error(t, err)
+}
+
+func TestParseDrivers(t *testing.T) {
+	drivers, err := ParseDrivers(`[{"name": "pgx", "description": "PostgreSQL driver", "version": "5.5"}]`)
+	require.NoError(t, err)
+	assert.Equal(t, []types.Driver{{Name: "pgx", Description: "PostgreSQL driver", Version: "5.5"}}, drivers)
+}
@@ -0,0 +1,16 @@
+package sqlpp
+
+import "context"
+
+type sessionIDKey struct{}
+
+// WithSessionID returns a context that attributes sqlpp work to the given MCP session
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, sessionID)
+}
+
+// SessionIDFrom returns the MCP session the context is attributed to, if any
+func SessionIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey{}).(string)
+	return id
+}
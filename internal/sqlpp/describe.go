@@ -0,0 +1,464 @@
+package sqlpp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TableDescription describes the columns, keys, indexes and check
+// constraints of a table
+type TableDescription struct {
+	Schema           string            `json:"schema,omitempty"`
+	Table            string            `json:"table"`
+	Columns          []TableColumn     `json:"columns"`
+	PrimaryKey       *KeyConstraint    `json:"primary_key,omitempty"`
+	UniqueKeys       []KeyConstraint   `json:"unique_keys,omitempty"`
+	ForeignKeys      []ForeignKey      `json:"foreign_keys,omitempty"`
+	Indexes          []TableIndex      `json:"indexes,omitempty"`
+	CheckConstraints []CheckConstraint `json:"check_constraints,omitempty"`
+}
+
+// TableColumn is a column of a table, in table order
+type TableColumn struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default,omitempty"` // default expression; nil if there is none
+}
+
+// KeyConstraint is a primary key or unique constraint
+type KeyConstraint struct {
+	Name    string   `json:"name,omitempty"` // empty where the database does not name it, as in SQLite
+	Columns []string `json:"columns"`
+}
+
+// ForeignKey is a foreign key constraint. Columns and ReferencedColumns
+// correspond by position.
+type ForeignKey struct {
+	Name              string   `json:"name,omitempty"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema,omitempty"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete,omitempty"`
+	OnUpdate          string   `json:"on_update,omitempty"`
+}
+
+// TableIndex is an index on a table. Columns holds the expression for
+// expression indexes where the database reports it.
+type TableIndex struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary"`
+}
+
+// CheckConstraint is a check constraint
+type CheckConstraint struct {
+	Name       string `json:"name,omitempty"`
+	Definition string `json:"definition"`
+}
+
+// TableQueries are the catalog queries describing a table. Each is a single
+// SELECT whose result columns are named the same way for every dialect:
+//
+//	Columns:     name, type, nullable, default_value
+//	Constraints: constraint_name, constraint_type, position, column_name,
+//	             ref_schema, ref_table, ref_column, on_delete, on_update, definition
+//	Indexes:     index_name, is_unique, is_primary, position, column_name
+//
+// ConstraintsFallback, if set, is run when Constraints fails: it reads the
+// same columns from an older catalog that lacks check constraint definitions,
+// and leaves check constraints out.
+type TableQueries struct {
+	Columns             string
+	Constraints         string
+	ConstraintsFallback string
+	Indexes             string
+}
+
+// DescribeTableQueries returns the catalog queries that describe table in
+// dialect. An empty schema means the connection's current schema; SQLite
+// takes the schema as the attached database name. Names are matched exactly
+// as the catalog stores them and are passed as string literals, never as SQL.
+func DescribeTableQueries(dialect Dialect, schema, table string) (TableQueries, error) {
+	if table == "" {
+		return TableQueries{}, fmt.Errorf("table name is required")
+	}
+	if !hasCatalog(dialect) {
+		return TableQueries{}, fmt.Errorf("describing tables is not supported for %s", dialect)
+	}
+	tbl, err := quoteString(dialect, table)
+	if err != nil {
+		return TableQueries{}, fmt.Errorf("invalid table name: %v", err)
+	}
+	sch, err := schemaExpression(dialect, schema)
+	if err != nil {
+		return TableQueries{}, err
+	}
+
+	switch dialect {
+	case DialectPostgres:
+		return TableQueries{
+			Columns: `SELECT a.attname AS name, format_type(a.atttypid, a.atttypmod) AS type, NOT a.attnotnull AS nullable, pg_get_expr(d.adbin, d.adrelid) AS default_value
+FROM pg_attribute a
+JOIN pg_class t ON t.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = ` + sch + ` AND t.relname = ` + tbl + ` AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum`,
+			Constraints: `SELECT con.conname AS constraint_name, con.contype AS constraint_type, k.ord AS position, a.attname AS column_name,
+  fn.nspname AS ref_schema, ft.relname AS ref_table, fa.attname AS ref_column,
+  con.confdeltype AS on_delete, con.confupdtype AS on_update, pg_get_constraintdef(con.oid) AS definition
+FROM pg_constraint con
+JOIN pg_class t ON t.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+LEFT JOIN LATERAL unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord) ON true
+LEFT JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+LEFT JOIN pg_class ft ON ft.oid = con.confrelid
+LEFT JOIN pg_namespace fn ON fn.oid = ft.relnamespace
+LEFT JOIN pg_attribute fa ON fa.attrelid = con.confrelid AND fa.attnum = con.confkey[k.ord]
+WHERE n.nspname = ` + sch + ` AND t.relname = ` + tbl + ` AND con.contype IN ('p', 'u', 'f', 'c')
+ORDER BY con.conname, k.ord`,
+			Indexes: `SELECT i.relname AS index_name, ix.indisunique AS is_unique, ix.indisprimary AS is_primary, k.ord AS position,
+  CASE WHEN k.attnum = 0 THEN pg_get_indexdef(ix.indexrelid, k.ord::int, true) ELSE a.attname END AS column_name
+FROM pg_index ix
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN pg_class i ON i.oid = ix.indexrelid
+CROSS JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE n.nspname = ` + sch + ` AND t.relname = ` + tbl + `
+ORDER BY i.relname, k.ord`,
+		}, nil
+	case DialectMySQL:
+		return TableQueries{
+			Columns: `SELECT column_name AS name, column_type AS type, is_nullable = 'YES' AS nullable, column_default AS default_value
+FROM information_schema.columns
+WHERE table_schema = ` + sch + ` AND table_name = ` + tbl + `
+ORDER BY ordinal_position`,
+			Constraints: `SELECT tc.constraint_name AS constraint_name, tc.constraint_type AS constraint_type, k.ordinal_position AS position, k.column_name AS column_name,
+  k.referenced_table_schema AS ref_schema, k.referenced_table_name AS ref_table, k.referenced_column_name AS ref_column,
+  r.delete_rule AS on_delete, r.update_rule AS on_update, cc.check_clause AS definition
+FROM information_schema.table_constraints tc
+LEFT JOIN information_schema.key_column_usage k ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name AND k.table_name = tc.table_name
+LEFT JOIN information_schema.referential_constraints r ON r.constraint_schema = tc.constraint_schema AND r.constraint_name = tc.constraint_name AND r.table_name = tc.table_name
+LEFT JOIN information_schema.check_constraints cc ON cc.constraint_schema = tc.constraint_schema AND cc.constraint_name = tc.constraint_name
+WHERE tc.table_schema = ` + sch + ` AND tc.table_name = ` + tbl + `
+ORDER BY tc.constraint_type, tc.constraint_name, k.ordinal_position`,
+			// information_schema.check_constraints is new in MySQL 8.0.16 and
+			// MariaDB 10.2.22
+			ConstraintsFallback: `SELECT tc.constraint_name AS constraint_name, tc.constraint_type AS constraint_type, k.ordinal_position AS position, k.column_name AS column_name,
+  k.referenced_table_schema AS ref_schema, k.referenced_table_name AS ref_table, k.referenced_column_name AS ref_column,
+  r.delete_rule AS on_delete, r.update_rule AS on_update, NULL AS definition
+FROM information_schema.table_constraints tc
+LEFT JOIN information_schema.key_column_usage k ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name AND k.table_name = tc.table_name
+LEFT JOIN information_schema.referential_constraints r ON r.constraint_schema = tc.constraint_schema AND r.constraint_name = tc.constraint_name AND r.table_name = tc.table_name
+WHERE tc.table_schema = ` + sch + ` AND tc.table_name = ` + tbl + ` AND tc.constraint_type <> 'CHECK'
+ORDER BY tc.constraint_type, tc.constraint_name, k.ordinal_position`,
+			Indexes: `SELECT index_name AS index_name, non_unique = 0 AS is_unique, index_name = 'PRIMARY' AS is_primary, seq_in_index AS position, column_name AS column_name
+FROM information_schema.statistics
+WHERE table_schema = ` + sch + ` AND table_name = ` + tbl + `
+ORDER BY index_name, seq_in_index`,
+		}, nil
+	case DialectSQLite:
+		return TableQueries{
+			Columns: `SELECT name, type, "notnull" = 0 AS nullable, dflt_value AS default_value
+FROM pragma_table_info(` + tbl + `, ` + sch + `)
+ORDER BY cid`,
+			// SQLite does not name keys or keep check constraints in its catalog
+			Constraints: `SELECT NULL AS constraint_name, 'PRIMARY KEY' AS constraint_type, pk AS position, name AS column_name,
+  NULL AS ref_schema, NULL AS ref_table, NULL AS ref_column, NULL AS on_delete, NULL AS on_update, NULL AS definition, -1 AS id
+FROM pragma_table_info(` + tbl + `, ` + sch + `) WHERE pk > 0
+UNION ALL
+SELECT NULL, 'FOREIGN KEY', seq + 1, "from", NULL, "table", "to", on_delete, on_update, NULL, id
+FROM pragma_foreign_key_list(` + tbl + `, ` + sch + `)
+ORDER BY id, position`,
+			Indexes: `SELECT il.name AS index_name, il."unique" AS is_unique, il.origin = 'pk' AS is_primary, ii.seqno + 1 AS position, ii.name AS column_name
+FROM pragma_index_list(` + tbl + `, ` + sch + `) AS il
+JOIN pragma_index_info(il.name, ` + sch + `) AS ii
+ORDER BY il.name, ii.seqno`,
+		}, nil
+	case DialectSQLServer:
+		return TableQueries{
+			Columns: `SELECT COLUMN_NAME AS name, ` + sqlServerColumnType + ` AS type,
+  CASE WHEN IS_NULLABLE = 'YES' THEN 1 ELSE 0 END AS nullable, COLUMN_DEFAULT AS default_value
+FROM INFORMATION_SCHEMA.COLUMNS
+WHERE TABLE_SCHEMA = ` + sch + ` AND TABLE_NAME = ` + tbl + `
+ORDER BY ORDINAL_POSITION`,
+			Constraints: `SELECT tc.CONSTRAINT_NAME AS constraint_name, tc.CONSTRAINT_TYPE AS constraint_type, k.ORDINAL_POSITION AS position, k.COLUMN_NAME AS column_name,
+  rk.TABLE_SCHEMA AS ref_schema, rk.TABLE_NAME AS ref_table, rk.COLUMN_NAME AS ref_column,
+  rc.DELETE_RULE AS on_delete, rc.UPDATE_RULE AS on_update, cc.CHECK_CLAUSE AS definition
+FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+LEFT JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE k ON k.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND k.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+LEFT JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS rc ON rc.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND rc.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+LEFT JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE rk ON rk.CONSTRAINT_SCHEMA = rc.UNIQUE_CONSTRAINT_SCHEMA AND rk.CONSTRAINT_NAME = rc.UNIQUE_CONSTRAINT_NAME AND rk.ORDINAL_POSITION = k.ORDINAL_POSITION
+LEFT JOIN INFORMATION_SCHEMA.CHECK_CONSTRAINTS cc ON cc.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA AND cc.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+WHERE tc.TABLE_SCHEMA = ` + sch + ` AND tc.TABLE_NAME = ` + tbl + `
+ORDER BY tc.CONSTRAINT_NAME, k.ORDINAL_POSITION`,
+			Indexes: `SELECT i.name AS index_name, i.is_unique AS is_unique, i.is_primary_key AS is_primary, ic.key_ordinal AS position, c.name AS column_name
+FROM sys.indexes i
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE i.object_id = OBJECT_ID(QUOTENAME(` + sch + `) + '.' + QUOTENAME(` + tbl + `)) AND i.name IS NOT NULL AND ic.key_ordinal > 0
+ORDER BY i.name, ic.key_ordinal`,
+		}, nil
+	case DialectOracle:
+		return TableQueries{
+			Columns: `SELECT column_name AS name, ` + oracleColumnType + ` AS type,
+  CASE nullable WHEN 'Y' THEN 1 ELSE 0 END AS nullable, data_default AS default_value
+FROM all_tab_columns
+WHERE owner = ` + sch + ` AND table_name = ` + tbl + `
+ORDER BY column_id`,
+			// System-named check constraints are the NOT NULL columns
+			Constraints: `SELECT c.constraint_name AS constraint_name, c.constraint_type AS constraint_type, cc.position AS position, cc.column_name AS column_name,
+  rc.owner AS ref_schema, rc.table_name AS ref_table, rcc.column_name AS ref_column,
+  c.delete_rule AS on_delete, NULL AS on_update, c.search_condition AS definition
+FROM all_constraints c
+LEFT JOIN all_cons_columns cc ON cc.owner = c.owner AND cc.constraint_name = c.constraint_name
+LEFT JOIN all_constraints rc ON rc.owner = c.r_owner AND rc.constraint_name = c.r_constraint_name
+LEFT JOIN all_cons_columns rcc ON rcc.owner = rc.owner AND rcc.constraint_name = rc.constraint_name AND rcc.position = cc.position
+WHERE c.owner = ` + sch + ` AND c.table_name = ` + tbl + ` AND c.constraint_type IN ('P', 'U', 'R', 'C')
+  AND NOT (c.constraint_type = 'C' AND c.generated = 'GENERATED NAME')
+ORDER BY c.constraint_name, cc.position`,
+			Indexes: `SELECT i.index_name AS index_name, CASE i.uniqueness WHEN 'UNIQUE' THEN 1 ELSE 0 END AS is_unique,
+  CASE WHEN pk.constraint_name IS NULL THEN 0 ELSE 1 END AS is_primary, ic.column_position AS position, ic.column_name AS column_name
+FROM all_indexes i
+JOIN all_ind_columns ic ON ic.index_owner = i.owner AND ic.index_name = i.index_name
+LEFT JOIN all_constraints pk ON pk.owner = i.table_owner AND pk.index_name = i.index_name AND pk.constraint_type = 'P'
+WHERE i.table_owner = ` + sch + ` AND i.table_name = ` + tbl + `
+ORDER BY i.index_name, ic.column_position`,
+		}, nil
+	default:
+		return TableQueries{}, fmt.Errorf("describing tables is not supported for %s", dialect)
+	}
+}
+
+// sqlServerColumnType spells out the type of an INFORMATION_SCHEMA.COLUMNS
+// row with its length or precision, as in varchar(50) or decimal(10,2)
+const sqlServerColumnType = `DATA_TYPE + CASE
+    WHEN CHARACTER_MAXIMUM_LENGTH = -1 THEN '(max)'
+    WHEN CHARACTER_MAXIMUM_LENGTH IS NOT NULL THEN '(' + CAST(CHARACTER_MAXIMUM_LENGTH AS varchar(10)) + ')'
+    WHEN DATA_TYPE IN ('decimal', 'numeric') THEN '(' + CAST(NUMERIC_PRECISION AS varchar(10)) + ',' + CAST(NUMERIC_SCALE AS varchar(10)) + ')'
+    ELSE '' END`
+
+// oracleColumnType does the same for an all_tab_columns row
+const oracleColumnType = `data_type || CASE
+    WHEN data_type IN ('VARCHAR2', 'NVARCHAR2', 'CHAR', 'NCHAR') THEN '(' || char_length || ')'
+    WHEN data_type = 'NUMBER' AND data_precision IS NOT NULL THEN '(' || data_precision || ',' || data_scale || ')'
+    END`
+
+// schemaExpression returns schema as a string literal, or the dialect's
+// expression for the current schema when it is empty
+func schemaExpression(dialect Dialect, schema string) (string, error) {
+	if schema != "" {
+		literal, err := quoteString(dialect, schema)
+		if err != nil {
+			return "", fmt.Errorf("invalid schema name: %v", err)
+		}
+		return literal, nil
+	}
+
+	switch dialect {
+	case DialectPostgres:
+		return "current_schema()", nil
+	case DialectMySQL:
+		return "DATABASE()", nil
+	case DialectSQLite:
+		return "'main'", nil
+	case DialectSQLServer:
+		return "SCHEMA_NAME()", nil
+	case DialectOracle:
+		return "SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA')", nil
+	default:
+		return "", fmt.Errorf("the current schema of %s is not known", dialect)
+	}
+}
+
+// hasCatalog reports whether the table tools know the catalog of dialect
+func hasCatalog(dialect Dialect) bool {
+	switch dialect {
+	case DialectPostgres, DialectMySQL, DialectSQLite, DialectSQLServer, DialectOracle:
+		return true
+	default:
+		return false
+	}
+}
+
+// Constraint kinds, normalised from each catalog's codes
+const (
+	constraintPrimary = "PRIMARY KEY"
+	constraintUnique  = "UNIQUE"
+	constraintForeign = "FOREIGN KEY"
+	constraintCheck   = "CHECK"
+)
+
+// constraintKinds maps catalog constraint type codes to constraint kinds:
+// Postgres uses p, u, f and c; Oracle P, U, R and C; the others spell them out
+var constraintKinds = map[string]string{
+	"p":           constraintPrimary,
+	"primary key": constraintPrimary,
+	"u":           constraintUnique,
+	"unique":      constraintUnique,
+	"f":           constraintForeign,
+	"r":           constraintForeign,
+	"foreign key": constraintForeign,
+	"c":           constraintCheck,
+	"check":       constraintCheck,
+}
+
+// postgresActions maps Postgres foreign key action codes to SQL
+var postgresActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+// BuildTableDescription assembles the description of table from the JSON
+// output of the TableQueries for dialect. A table without columns does not
+// exist, so it is reported as not found.
+func BuildTableDescription(dialect Dialect, schema, table, columns, constraints, indexes string) (*TableDescription, error) {
+	desc := &TableDescription{Schema: schema, Table: table}
+
+	columnRecords, err := catalogRecords(columns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse columns: %w", err)
+	}
+	for _, record := range columnRecords {
+		column := TableColumn{
+			Name:     record["name"],
+			Type:     record["type"],
+			Nullable: catalogBool(record["nullable"]),
+		}
+		if value, ok := record["default_value"]; ok {
+			column.Default = &value
+		}
+		desc.Columns = append(desc.Columns, column)
+	}
+	if len(desc.Columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", qualifiedName(schema, table))
+	}
+
+	constraintRecords, err := catalogRecords(constraints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse constraints: %w", err)
+	}
+	for _, group := range groupRecords(constraintRecords, "constraint_name", "constraint_type") {
+		first := group[0]
+		name := first["constraint_name"]
+		switch constraintKinds[strings.ToLower(first["constraint_type"])] {
+		case constraintPrimary:
+			desc.PrimaryKey = &KeyConstraint{Name: name, Columns: recordValues(group, "column_name")}
+		case constraintUnique:
+			desc.UniqueKeys = append(desc.UniqueKeys, KeyConstraint{Name: name, Columns: recordValues(group, "column_name")})
+		case constraintForeign:
+			fk := ForeignKey{
+				Name:              name,
+				Columns:           recordValues(group, "column_name"),
+				ReferencedSchema:  first["ref_schema"],
+				ReferencedTable:   first["ref_table"],
+				ReferencedColumns: recordValues(group, "ref_column"),
+				OnDelete:          first["on_delete"],
+				OnUpdate:          first["on_update"],
+			}
+			if dialect == DialectPostgres {
+				fk.OnDelete, fk.OnUpdate = postgresActions[fk.OnDelete], postgresActions[fk.OnUpdate]
+			}
+			desc.ForeignKeys = append(desc.ForeignKeys, fk)
+		case constraintCheck:
+			desc.CheckConstraints = append(desc.CheckConstraints, CheckConstraint{Name: name, Definition: first["definition"]})
+		}
+	}
+
+	indexRecords, err := catalogRecords(indexes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse indexes: %w", err)
+	}
+	for _, group := range groupRecords(indexRecords, "index_name") {
+		desc.Indexes = append(desc.Indexes, TableIndex{
+			Name:    group[0]["index_name"],
+			Columns: recordValues(group, "column_name"),
+			Unique:  catalogBool(group[0]["is_unique"]),
+			Primary: catalogBool(group[0]["is_primary"]),
+		})
+	}
+
+	return desc, nil
+}
+
+// catalogRecords reads the rows of catalog query output into records keyed
+// by lower-case column name. NULL columns are left out.
+func catalogRecords(output string) ([]map[string]string, error) {
+	rows, err := resultRows(output)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		records[i] = make(map[string]string, len(row))
+		for key, value := range row {
+			if value != nil {
+				records[i][strings.ToLower(key)] = fmt.Sprint(value)
+			}
+		}
+	}
+	return records, nil
+}
+
+// groupRecords splits per-column catalog rows into one group per constraint
+// or index. A group ends when any of the key fields change or the position
+// starts again, which separates unnamed SQLite foreign keys.
+func groupRecords(records []map[string]string, keys ...string) [][]map[string]string {
+	var groups [][]map[string]string
+	for i, record := range records {
+		if i > 0 && sameGroup(records[i-1], record, keys) {
+			groups[len(groups)-1] = append(groups[len(groups)-1], record)
+			continue
+		}
+		groups = append(groups, []map[string]string{record})
+	}
+	return groups
+}
+
+func sameGroup(prev, record map[string]string, keys []string) bool {
+	for _, key := range keys {
+		if prev[key] != record[key] {
+			return false
+		}
+	}
+	prevPos, err1 := strconv.Atoi(prev["position"])
+	pos, err2 := strconv.Atoi(record["position"])
+	return err1 != nil || err2 != nil || pos > prevPos
+}
+
+// recordValues returns the non-empty values of key in records
+func recordValues(records []map[string]string, key string) []string {
+	values := make([]string, 0, len(records))
+	for _, record := range records {
+		if value := record[key]; value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// catalogBool reads the spellings of true that catalogs return
+func catalogBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "true", "t", "yes", "y":
+		return true
+	default:
+		return false
+	}
+}
+
+// qualifiedName returns schema.table, or table when schema is empty
+func qualifiedName(schema, table string) string {
+	if schema == "" {
+		return table
+	}
+	return schema + "." + table
+}
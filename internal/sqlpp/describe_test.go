@@ -0,0 +1,137 @@
+package sqlpp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeTableQueries(t *testing.T) {
+	for _, dialect := range []Dialect{DialectPostgres, DialectMySQL, DialectSQLite, DialectSQLServer, DialectOracle} {
+		queries, err := DescribeTableQueries(dialect, "", "order's")
+		require.NoError(t, err, dialect)
+		for _, query := range []string{queries.Columns, queries.Constraints, queries.ConstraintsFallback, queries.Indexes} {
+			if query == "" {
+				continue
+			}
+			// Names are bound as literals, never spliced in as SQL
+			assert.Contains(t, query, "'order''s'", dialect)
+			assert.Len(t, splitStatements(query, lexSQL(query)), 1, dialect)
+			assert.True(t, IsReadOnlySQL(query), dialect)
+		}
+	}
+
+	queries, err := DescribeTableQueries(DialectPostgres, "", "orders")
+	require.NoError(t, err)
+	assert.Contains(t, queries.Columns, "n.nspname = current_schema()")
+
+	queries, err = DescribeTableQueries(DialectSQLServer, "sales", "orders")
+	require.NoError(t, err)
+	assert.Contains(t, queries.Columns, "TABLE_SCHEMA = N'sales' AND TABLE_NAME = N'orders'")
+
+	queries, err = DescribeTableQueries(DialectMySQL, `a\b`, "orders")
+	require.NoError(t, err)
+	assert.Contains(t, queries.Columns, `table_schema = 'a\\b'`)
+	assert.Contains(t, queries.Constraints, "information_schema.check_constraints")
+	assert.NotContains(t, queries.ConstraintsFallback, "check_constraints")
+
+	_, err = DescribeTableQueries(DialectPostgres, "", "")
+	assert.ErrorContains(t, err, "table name is required")
+
+	_, err = DescribeTableQueries(Dialect("acme"), "", "orders")
+	assert.ErrorContains(t, err, "describing tables is not supported for acme")
+}
+
+func TestBuildTableDescription_Postgres(t *testing.T) {
+	columns := `[
+		{"name": "id", "type": "integer", "nullable": false, "default_value": "nextval('orders_id_seq'::regclass)"},
+		{"name": "customer_id", "type": "integer", "nullable": false, "default_value": null},
+		{"name": "code", "type": "character varying(20)", "nullable": true, "default_value": null},
+		{"name": "total", "type": "numeric(10,2)", "nullable": false, "default_value": "0"}
+	]`
+	constraints := `[
+		{"constraint_name": "orders_code_key", "constraint_type": "u", "position": 1, "column_name": "code", "definition": "UNIQUE (code)"},
+		{"constraint_name": "orders_customer_fk", "constraint_type": "f", "position": 1, "column_name": "customer_id",
+		 "ref_schema": "public", "ref_table": "customers", "ref_column": "id", "on_delete": "c", "on_update": "a",
+		 "definition": "FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE"},
+		{"constraint_name": "orders_pkey", "constraint_type": "p", "position": 1, "column_name": "id", "on_delete": " ", "on_update": " ", "definition": "PRIMARY KEY (id)"},
+		{"constraint_name": "orders_total_check", "constraint_type": "c", "position": 1, "column_name": "total", "definition": "CHECK ((total >= (0)::numeric))"}
+	]`
+	indexes := `[
+		{"index_name": "orders_code_key", "is_unique": true, "is_primary": false, "position": 1, "column_name": "code"},
+		{"index_name": "orders_customer_idx", "is_unique": false, "is_primary": false, "position": 1, "column_name": "customer_id"},
+		{"index_name": "orders_customer_idx", "is_unique": false, "is_primary": false, "position": 2, "column_name": "lower((code)::text)"},
+		{"index_name": "orders_pkey", "is_unique": true, "is_primary": true, "position": 1, "column_name": "id"}
+	]`
+
+	desc, err := BuildTableDescription(DialectPostgres, "", "orders", columns, constraints, indexes)
+	require.NoError(t, err)
+
+	require.Len(t, desc.Columns, 4)
+	assert.Equal(t, "id", desc.Columns[0].Name)
+	assert.False(t, desc.Columns[0].Nullable)
+	require.NotNil(t, desc.Columns[0].Default)
+	assert.Equal(t, "nextval('orders_id_seq'::regclass)", *desc.Columns[0].Default)
+	assert.Nil(t, desc.Columns[1].Default)
+	assert.True(t, desc.Columns[2].Nullable)
+	assert.Equal(t, "numeric(10,2)", desc.Columns[3].Type)
+
+	assert.Equal(t, &KeyConstraint{Name: "orders_pkey", Columns: []string{"id"}}, desc.PrimaryKey)
+	assert.Equal(t, []KeyConstraint{{Name: "orders_code_key", Columns: []string{"code"}}}, desc.UniqueKeys)
+	assert.Equal(t, []ForeignKey{{
+		Name:              "orders_customer_fk",
+		Columns:           []string{"customer_id"},
+		ReferencedSchema:  "public",
+		ReferencedTable:   "customers",
+		ReferencedColumns: []string{"id"},
+		OnDelete:          "CASCADE",
+		OnUpdate:          "NO ACTION",
+	}}, desc.ForeignKeys)
+	assert.Equal(t, []CheckConstraint{{Name: "orders_total_check", Definition: "CHECK ((total >= (0)::numeric))"}}, desc.CheckConstraints)
+
+	require.Len(t, desc.Indexes, 3)
+	assert.Equal(t, TableIndex{Name: "orders_customer_idx", Columns: []string{"customer_id", "lower((code)::text)"}}, desc.Indexes[1])
+	assert.True(t, desc.Indexes[2].Primary)
+	assert.True(t, desc.Indexes[2].Unique)
+}
+
+func TestBuildTableDescription_SQLite(t *testing.T) {
+	columns := `[
+		{"name": "order_id", "type": "INTEGER", "nullable": 0, "default_value": null},
+		{"name": "line", "type": "INTEGER", "nullable": 0, "default_value": null},
+		{"name": "sku", "type": "TEXT", "nullable": 1, "default_value": "''"}
+	]`
+	// Two unnamed foreign keys in a row are told apart by their positions
+	constraints := `[
+		{"constraint_name": null, "constraint_type": "PRIMARY KEY", "position": 1, "column_name": "order_id", "id": -1},
+		{"constraint_name": null, "constraint_type": "PRIMARY KEY", "position": 2, "column_name": "line", "id": -1},
+		{"constraint_name": null, "constraint_type": "FOREIGN KEY", "position": 1, "column_name": "sku", "ref_table": "products", "ref_column": "sku", "on_delete": "NO ACTION", "on_update": "NO ACTION", "id": 0},
+		{"constraint_name": null, "constraint_type": "FOREIGN KEY", "position": 1, "column_name": "order_id", "ref_table": "orders", "ref_column": "id", "on_delete": "CASCADE", "on_update": "NO ACTION", "id": 1}
+	]`
+	indexes := `[
+		{"index_name": "sqlite_autoindex_order_lines_1", "is_unique": 1, "is_primary": 1, "position": 1, "column_name": "order_id"},
+		{"index_name": "sqlite_autoindex_order_lines_1", "is_unique": 1, "is_primary": 1, "position": 2, "column_name": "line"}
+	]`
+
+	desc, err := BuildTableDescription(DialectSQLite, "", "order_lines", columns, constraints, indexes)
+	require.NoError(t, err)
+
+	require.Len(t, desc.Columns, 3)
+	assert.True(t, desc.Columns[2].Nullable)
+	assert.Equal(t, &KeyConstraint{Columns: []string{"order_id", "line"}}, desc.PrimaryKey)
+	require.Len(t, desc.ForeignKeys, 2)
+	assert.Equal(t, "products", desc.ForeignKeys[0].ReferencedTable)
+	assert.Equal(t, []string{"order_id"}, desc.ForeignKeys[1].Columns)
+	assert.Equal(t, "CASCADE", desc.ForeignKeys[1].OnDelete)
+	require.Len(t, desc.Indexes, 1)
+	assert.Equal(t, []string{"order_id", "line"}, desc.Indexes[0].Columns)
+}
+
+func TestBuildTableDescription_NotFound(t *testing.T) {
+	_, err := BuildTableDescription(DialectMySQL, "shop", "missing", "[]", "[]", "[]")
+	assert.ErrorContains(t, err, "table shop.missing not found")
+
+	_, err = BuildTableDescription(DialectMySQL, "", "orders", `[{"name": "id", "type": "int"}]`, "not json", "[]")
+	assert.ErrorContains(t, err, "failed to parse constraints")
+}
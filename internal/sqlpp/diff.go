@@ -0,0 +1,540 @@
+package sqlpp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaSnapshot is the shape of a schema as compared by DiffSchemas
+type SchemaSnapshot struct {
+	Tables     []string
+	Views      []string
+	Procedures []string
+	Functions  []string
+	Columns    map[string][]TableColumn // by table or view name
+	Indexes    map[string][]TableIndex  // by table name
+}
+
+// SchemaDiff lists the differences between a source and a target schema.
+// Added objects are only in the target, removed objects only in the source.
+type SchemaDiff struct {
+	Added   []SchemaChange `json:"added"`
+	Removed []SchemaChange `json:"removed"`
+	Changed []SchemaChange `json:"changed"`
+}
+
+// SchemaChange is an object that was added, removed or changed
+type SchemaChange struct {
+	Type    string        `json:"type"`              // table, view, column, index, procedure or function
+	Table   string        `json:"table,omitempty"`   // table or view of a column or index
+	Name    string        `json:"name"`              // object name
+	Detail  string        `json:"detail,omitempty"`  // column type or index columns of added and removed objects
+	Changes []FieldChange `json:"changes,omitempty"` // what changed, for changed objects
+}
+
+// FieldChange is a property of an object that differs between the schemas
+type FieldChange struct {
+	Field  string `json:"field"` // type, nullable, default, columns, unique, primary or name
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Empty reports whether the schemas are the same
+func (d SchemaDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// SchemaObjectsQuery returns a catalog query for the tables, views,
+// procedures and functions in schema, as object_type (one of the Object
+// constants) and name columns. An empty schema means the connection's
+// current schema.
+func SchemaObjectsQuery(dialect Dialect, schema string) (string, error) {
+	if !hasCatalog(dialect) {
+		return "", fmt.Errorf("schema comparison is not supported for %s", dialect)
+	}
+	if dialect == DialectSQLite {
+		master, _, err := sqliteMaster(schema)
+		if err != nil {
+			return "", err
+		}
+		return `SELECT m.type AS object_type, m.name AS name
+FROM ` + master + ` m
+WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite\_%' ESCAPE '\'
+ORDER BY 1, 2`, nil
+	}
+
+	sch, err := schemaExpression(dialect, schema)
+	if err != nil {
+		return "", err
+	}
+
+	switch dialect {
+	case DialectPostgres:
+		return `SELECT CASE WHEN c.relkind IN ('v', 'm') THEN 'view' ELSE 'table' END AS object_type, c.relname AS name
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = ` + sch + ` AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+UNION ALL
+SELECT CASE p.prokind WHEN 'p' THEN 'procedure' ELSE 'function' END AS object_type, p.proname AS name
+FROM pg_proc p
+JOIN pg_namespace n ON n.oid = p.pronamespace
+WHERE n.nspname = ` + sch + ` AND p.prokind IN ('f', 'p')
+ORDER BY 1, 2`, nil
+	case DialectMySQL:
+		return `SELECT CASE table_type WHEN 'VIEW' THEN 'view' ELSE 'table' END AS object_type, table_name AS name
+FROM information_schema.tables
+WHERE table_schema = ` + sch + `
+UNION ALL
+SELECT LOWER(routine_type) AS object_type, routine_name AS name
+FROM information_schema.routines
+WHERE routine_schema = ` + sch + `
+ORDER BY 1, 2`, nil
+	case DialectSQLServer:
+		return `SELECT CASE o.type WHEN 'U' THEN 'table' WHEN 'V' THEN 'view' WHEN 'P' THEN 'procedure' ELSE 'function' END AS object_type, o.name AS name
+FROM sys.objects o
+JOIN sys.schemas s ON s.schema_id = o.schema_id
+WHERE s.name = ` + sch + ` AND o.is_ms_shipped = 0 AND o.type IN ('U', 'V', 'P', 'FN', 'IF', 'TF')
+ORDER BY 1, 2`, nil
+	case DialectOracle:
+		return `SELECT LOWER(object_type) AS object_type, object_name AS name
+FROM all_objects
+WHERE owner = ` + sch + ` AND object_type IN ('TABLE', 'VIEW', 'PROCEDURE', 'FUNCTION')
+ORDER BY 1, 2`, nil
+	default:
+		return "", fmt.Errorf("schema comparison is not supported for %s", dialect)
+	}
+}
+
+// ParseSchemaObjectNames reads the output of a SchemaObjectsQuery into the
+// object lists of a snapshot, sorted and without the duplicates overloaded
+// routines produce
+func ParseSchemaObjectNames(output string) (*SchemaSnapshot, error) {
+	records, err := catalogRecords(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse objects: %w", err)
+	}
+	snapshot := &SchemaSnapshot{}
+	lists := map[string]*[]string{
+		ObjectTable:     &snapshot.Tables,
+		ObjectView:      &snapshot.Views,
+		ObjectProcedure: &snapshot.Procedures,
+		ObjectFunction:  &snapshot.Functions,
+	}
+	for _, record := range records {
+		if list, ok := lists[strings.ToLower(record["object_type"])]; ok {
+			*list = append(*list, record["name"])
+		}
+	}
+	for _, list := range lists {
+		sort.Strings(*list)
+		*list = slices.Compact(*list)
+	}
+	return snapshot, nil
+}
+
+// SchemaColumnsQuery returns a catalog query for the columns of every table
+// and view in schema, with the result columns of TableQueries.Columns after
+// a table_name column, in table and column order. An empty schema means the
+// connection's current schema.
+func SchemaColumnsQuery(dialect Dialect, schema string) (string, error) {
+	if !hasCatalog(dialect) {
+		return "", fmt.Errorf("schema comparison is not supported for %s", dialect)
+	}
+	if dialect == DialectSQLite {
+		master, sch, err := sqliteMaster(schema)
+		if err != nil {
+			return "", err
+		}
+		return `SELECT m.name AS table_name, p.name AS name, p.type AS type, p."notnull" = 0 AS nullable, p.dflt_value AS default_value
+FROM ` + master + ` m JOIN pragma_table_info(m.name, ` + sch + `) p
+WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite\_%' ESCAPE '\'
+ORDER BY m.name, p.cid`, nil
+	}
+
+	sch, err := schemaExpression(dialect, schema)
+	if err != nil {
+		return "", err
+	}
+
+	switch dialect {
+	case DialectPostgres:
+		return `SELECT t.relname AS table_name, a.attname AS name, format_type(a.atttypid, a.atttypmod) AS type, NOT a.attnotnull AS nullable, pg_get_expr(d.adbin, d.adrelid) AS default_value
+FROM pg_attribute a
+JOIN pg_class t ON t.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = ` + sch + ` AND t.relkind IN ('r', 'p', 'v', 'm', 'f') AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY t.relname, a.attnum`, nil
+	case DialectMySQL:
+		return `SELECT table_name AS table_name, column_name AS name, column_type AS type, is_nullable = 'YES' AS nullable, column_default AS default_value
+FROM information_schema.columns
+WHERE table_schema = ` + sch + `
+ORDER BY table_name, ordinal_position`, nil
+	case DialectSQLServer:
+		return `SELECT TABLE_NAME AS table_name, COLUMN_NAME AS name, ` + sqlServerColumnType + ` AS type,
+  CASE WHEN IS_NULLABLE = 'YES' THEN 1 ELSE 0 END AS nullable, COLUMN_DEFAULT AS default_value
+FROM INFORMATION_SCHEMA.COLUMNS
+WHERE TABLE_SCHEMA = ` + sch + `
+ORDER BY TABLE_NAME, ORDINAL_POSITION`, nil
+	case DialectOracle:
+		return `SELECT table_name AS table_name, column_name AS name, ` + oracleColumnType + ` AS type,
+  CASE nullable WHEN 'Y' THEN 1 ELSE 0 END AS nullable, data_default AS default_value
+FROM all_tab_columns
+WHERE owner = ` + sch + `
+ORDER BY table_name, column_id`, nil
+	default:
+		return "", fmt.Errorf("schema comparison is not supported for %s", dialect)
+	}
+}
+
+// SchemaIndexesQuery returns a catalog query for the indexes of every table
+// in schema, with the result columns of TableQueries.Indexes after a
+// table_name column, in table, index and column order
+func SchemaIndexesQuery(dialect Dialect, schema string) (string, error) {
+	if !hasCatalog(dialect) {
+		return "", fmt.Errorf("schema comparison is not supported for %s", dialect)
+	}
+	if dialect == DialectSQLite {
+		master, sch, err := sqliteMaster(schema)
+		if err != nil {
+			return "", err
+		}
+		return `SELECT m.name AS table_name, il.name AS index_name, il."unique" AS is_unique, il.origin = 'pk' AS is_primary, ii.seqno + 1 AS position, ii.name AS column_name
+FROM ` + master + ` m
+JOIN pragma_index_list(m.name, ` + sch + `) AS il
+JOIN pragma_index_info(il.name, ` + sch + `) AS ii
+WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite\_%' ESCAPE '\'
+ORDER BY m.name, il.name, ii.seqno`, nil
+	}
+
+	sch, err := schemaExpression(dialect, schema)
+	if err != nil {
+		return "", err
+	}
+
+	switch dialect {
+	case DialectPostgres:
+		return `SELECT t.relname AS table_name, i.relname AS index_name, ix.indisunique AS is_unique, ix.indisprimary AS is_primary, k.ord AS position,
+  CASE WHEN k.attnum = 0 THEN pg_get_indexdef(ix.indexrelid, k.ord::int, true) ELSE a.attname END AS column_name
+FROM pg_index ix
+JOIN pg_class t ON t.oid = ix.indrelid
+JOIN pg_namespace n ON n.oid = t.relnamespace
+JOIN pg_class i ON i.oid = ix.indexrelid
+CROSS JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE n.nspname = ` + sch + `
+ORDER BY t.relname, i.relname, k.ord`, nil
+	case DialectMySQL:
+		return `SELECT table_name AS table_name, index_name AS index_name, non_unique = 0 AS is_unique, index_name = 'PRIMARY' AS is_primary, seq_in_index AS position, column_name AS column_name
+FROM information_schema.statistics
+WHERE table_schema = ` + sch + `
+ORDER BY table_name, index_name, seq_in_index`, nil
+	case DialectSQLServer:
+		return `SELECT o.name AS table_name, i.name AS index_name, i.is_unique AS is_unique, i.is_primary_key AS is_primary, ic.key_ordinal AS position, c.name AS column_name
+FROM sys.indexes i
+JOIN sys.objects o ON o.object_id = i.object_id
+JOIN sys.schemas s ON s.schema_id = o.schema_id
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE s.name = ` + sch + ` AND o.is_ms_shipped = 0 AND i.name IS NOT NULL AND ic.key_ordinal > 0
+ORDER BY o.name, i.name, ic.key_ordinal`, nil
+	case DialectOracle:
+		return `SELECT i.table_name AS table_name, i.index_name AS index_name, CASE i.uniqueness WHEN 'UNIQUE' THEN 1 ELSE 0 END AS is_unique,
+  CASE WHEN pk.constraint_name IS NULL THEN 0 ELSE 1 END AS is_primary, ic.column_position AS position, ic.column_name AS column_name
+FROM all_indexes i
+JOIN all_ind_columns ic ON ic.index_owner = i.owner AND ic.index_name = i.index_name
+LEFT JOIN all_constraints pk ON pk.owner = i.table_owner AND pk.index_name = i.index_name AND pk.constraint_type = 'P'
+WHERE i.table_owner = ` + sch + `
+ORDER BY i.table_name, i.index_name, ic.column_position`, nil
+	default:
+		return "", fmt.Errorf("schema comparison is not supported for %s", dialect)
+	}
+}
+
+// sqliteMaster returns the sqlite_master table of the attached database
+// schema, and schema as the string literal the pragma functions take
+func sqliteMaster(schema string) (master, literal string, err error) {
+	if schema == "" {
+		return "sqlite_master", "'main'", nil
+	}
+	quoted, err := QuoteIdentifier(DialectSQLite, schema)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid schema name: %v", err)
+	}
+	literal, err = quoteString(DialectSQLite, schema)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid schema name: %v", err)
+	}
+	return quoted + ".sqlite_master", literal, nil
+}
+
+// ParseSchemaColumns reads the output of a SchemaColumnsQuery into columns
+// by table name
+func ParseSchemaColumns(output string) (map[string][]TableColumn, error) {
+	records, err := catalogRecords(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse columns: %w", err)
+	}
+	columns := make(map[string][]TableColumn)
+	for _, record := range records {
+		column := TableColumn{
+			Name:     record["name"],
+			Type:     record["type"],
+			Nullable: catalogBool(record["nullable"]),
+		}
+		if value, ok := record["default_value"]; ok {
+			column.Default = &value
+		}
+		table := record["table_name"]
+		columns[table] = append(columns[table], column)
+	}
+	return columns, nil
+}
+
+// ParseSchemaIndexes reads the output of a SchemaIndexesQuery into indexes
+// by table name
+func ParseSchemaIndexes(output string) (map[string][]TableIndex, error) {
+	records, err := catalogRecords(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse indexes: %w", err)
+	}
+	indexes := make(map[string][]TableIndex)
+	for _, group := range groupRecords(records, "table_name", "index_name") {
+		table := group[0]["table_name"]
+		indexes[table] = append(indexes[table], TableIndex{
+			Name:    group[0]["index_name"],
+			Columns: recordValues(group, "column_name"),
+			Unique:  catalogBool(group[0]["is_unique"]),
+			Primary: catalogBool(group[0]["is_primary"]),
+		})
+	}
+	return indexes, nil
+}
+
+// DiffSchemas compares the target schema with the source. Objects are
+// matched by name. Columns and indexes are compared for the tables and
+// views that are in both schemas and whose columns both catalogs returned;
+// an index that was only renamed is reported as a change of name.
+func DiffSchemas(source, target *SchemaSnapshot) SchemaDiff {
+	var diff SchemaDiff
+	diff.diffNames(ObjectTable, source.Tables, target.Tables)
+	diff.diffNames(ObjectView, source.Views, target.Views)
+	diff.diffNames(ObjectProcedure, source.Procedures, target.Procedures)
+	diff.diffNames(ObjectFunction, source.Functions, target.Functions)
+
+	relations := append(intersect(source.Tables, target.Tables), intersect(source.Views, target.Views)...)
+	sort.Strings(relations)
+	for _, table := range relations {
+		sourceColumns, targetColumns := source.Columns[table], target.Columns[table]
+		if len(sourceColumns) == 0 || len(targetColumns) == 0 {
+			continue
+		}
+		diff.diffColumns(table, sourceColumns, targetColumns)
+		diff.diffIndexes(table, source.Indexes[table], target.Indexes[table])
+	}
+
+	diff.Added = sortChanges(diff.Added)
+	diff.Removed = sortChanges(diff.Removed)
+	diff.Changed = sortChanges(diff.Changed)
+	return diff
+}
+
+func (d *SchemaDiff) diffNames(objectType string, source, target []string) {
+	for _, name := range difference(target, source) {
+		d.Added = append(d.Added, SchemaChange{Type: objectType, Name: name})
+	}
+	for _, name := range difference(source, target) {
+		d.Removed = append(d.Removed, SchemaChange{Type: objectType, Name: name})
+	}
+}
+
+func (d *SchemaDiff) diffColumns(table string, source, target []TableColumn) {
+	sourceByName := make(map[string]TableColumn, len(source))
+	for _, column := range source {
+		sourceByName[column.Name] = column
+	}
+	targetNames := make(map[string]bool, len(target))
+
+	for _, column := range target {
+		targetNames[column.Name] = true
+		old, ok := sourceByName[column.Name]
+		if !ok {
+			d.Added = append(d.Added, SchemaChange{Type: ObjectColumn, Table: table, Name: column.Name, Detail: columnDetail(column)})
+			continue
+		}
+		var changes []FieldChange
+		if !strings.EqualFold(old.Type, column.Type) {
+			changes = append(changes, FieldChange{Field: "type", Source: old.Type, Target: column.Type})
+		}
+		if old.Nullable != column.Nullable {
+			changes = append(changes, FieldChange{Field: "nullable", Source: strconv.FormatBool(old.Nullable), Target: strconv.FormatBool(column.Nullable)})
+		}
+		if defaultText(old.Default) != defaultText(column.Default) {
+			changes = append(changes, FieldChange{Field: "default", Source: defaultText(old.Default), Target: defaultText(column.Default)})
+		}
+		if len(changes) > 0 {
+			d.Changed = append(d.Changed, SchemaChange{Type: ObjectColumn, Table: table, Name: column.Name, Changes: changes})
+		}
+	}
+
+	for _, column := range source {
+		if !targetNames[column.Name] {
+			d.Removed = append(d.Removed, SchemaChange{Type: ObjectColumn, Table: table, Name: column.Name, Detail: columnDetail(column)})
+		}
+	}
+}
+
+func (d *SchemaDiff) diffIndexes(table string, source, target []TableIndex) {
+	sourceByName := make(map[string]TableIndex, len(source))
+	for _, index := range source {
+		sourceByName[index.Name] = index
+	}
+	targetByName := make(map[string]TableIndex, len(target))
+	for _, index := range target {
+		targetByName[index.Name] = index
+	}
+
+	var added, removed []TableIndex
+	for _, index := range target {
+		old, ok := sourceByName[index.Name]
+		if !ok {
+			added = append(added, index)
+			continue
+		}
+		var changes []FieldChange
+		if !slices.Equal(old.Columns, index.Columns) {
+			changes = append(changes, FieldChange{Field: "columns", Source: strings.Join(old.Columns, ", "), Target: strings.Join(index.Columns, ", ")})
+		}
+		if old.Unique != index.Unique {
+			changes = append(changes, FieldChange{Field: "unique", Source: strconv.FormatBool(old.Unique), Target: strconv.FormatBool(index.Unique)})
+		}
+		if old.Primary != index.Primary {
+			changes = append(changes, FieldChange{Field: "primary", Source: strconv.FormatBool(old.Primary), Target: strconv.FormatBool(index.Primary)})
+		}
+		if len(changes) > 0 {
+			d.Changed = append(d.Changed, SchemaChange{Type: ObjectIndex, Table: table, Name: index.Name, Changes: changes})
+		}
+	}
+	for _, index := range source {
+		if _, ok := targetByName[index.Name]; !ok {
+			removed = append(removed, index)
+		}
+	}
+
+	// Indexes named by the database, such as SQL Server's PK__orders__3213E83F,
+	// differ between databases, so an added and a removed index with the same
+	// definition are one renamed index
+	for _, index := range added {
+		i := slices.IndexFunc(removed, func(old TableIndex) bool {
+			return slices.Equal(old.Columns, index.Columns) && old.Unique == index.Unique && old.Primary == index.Primary
+		})
+		if i < 0 {
+			d.Added = append(d.Added, SchemaChange{Type: ObjectIndex, Table: table, Name: index.Name, Detail: indexDetail(index)})
+			continue
+		}
+		d.Changed = append(d.Changed, SchemaChange{
+			Type:    ObjectIndex,
+			Table:   table,
+			Name:    index.Name,
+			Changes: []FieldChange{{Field: "name", Source: removed[i].Name, Target: index.Name}},
+		})
+		removed = slices.Delete(removed, i, i+1)
+	}
+	for _, index := range removed {
+		d.Removed = append(d.Removed, SchemaChange{Type: ObjectIndex, Table: table, Name: index.Name, Detail: indexDetail(index)})
+	}
+}
+
+// columnDetail describes a column as in a table definition, e.g.
+// numeric(10,2) NOT NULL DEFAULT 0
+func columnDetail(column TableColumn) string {
+	detail := column.Type
+	if !column.Nullable {
+		detail += " NOT NULL"
+	}
+	if column.Default != nil {
+		detail += " DEFAULT " + *column.Default
+	}
+	return detail
+}
+
+// indexDetail describes an index, e.g. UNIQUE (email)
+func indexDetail(index TableIndex) string {
+	detail := "(" + strings.Join(index.Columns, ", ") + ")"
+	switch {
+	case index.Primary:
+		detail = "PRIMARY KEY " + detail
+	case index.Unique:
+		detail = "UNIQUE " + detail
+	}
+	return detail
+}
+
+func defaultText(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// changeOrder ranks object types in diffs, containers first
+var changeOrder = map[string]int{
+	ObjectTable:     0,
+	ObjectView:      1,
+	ObjectColumn:    2,
+	ObjectIndex:     3,
+	ObjectProcedure: 4,
+	ObjectFunction:  5,
+}
+
+// sortChanges orders changes by object type, table and name, returning an
+// empty list rather than nil so that JSON shows []
+func sortChanges(changes []SchemaChange) []SchemaChange {
+	if changes == nil {
+		return []SchemaChange{}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Type != b.Type {
+			return changeOrder[a.Type] < changeOrder[b.Type]
+		}
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.Name < b.Name
+	})
+	return changes
+}
+
+// difference returns the names in a that are not in b
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, name := range b {
+		in[name] = true
+	}
+	var names []string
+	for _, name := range a {
+		if !in[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// intersect returns the names in both a and b
+func intersect(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, name := range b {
+		in[name] = true
+	}
+	var names []string
+	for _, name := range a {
+		if in[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,156 @@
+package sqlpp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaQueries(t *testing.T) {
+	for _, dialect := range []Dialect{DialectPostgres, DialectMySQL, DialectSQLite, DialectSQLServer, DialectOracle} {
+		objects, err := SchemaObjectsQuery(dialect, "")
+		require.NoError(t, err, dialect)
+		assert.Contains(t, objects, "AS object_type", dialect)
+
+		columns, err := SchemaColumnsQuery(dialect, "")
+		require.NoError(t, err, dialect)
+		assert.Contains(t, columns, "AS table_name", dialect)
+		assert.Contains(t, columns, "AS default_value", dialect)
+
+		indexes, err := SchemaIndexesQuery(dialect, "")
+		require.NoError(t, err, dialect)
+		assert.Contains(t, indexes, "AS table_name", dialect)
+		assert.Contains(t, indexes, "AS is_primary", dialect)
+	}
+
+	// SQLite reads the attached database's own sqlite_master
+	columns, err := SchemaColumnsQuery(DialectSQLite, "aux")
+	require.NoError(t, err)
+	assert.Contains(t, columns, `FROM "aux".sqlite_master m JOIN pragma_table_info(m.name, 'aux') p`)
+
+	columns, err = SchemaColumnsQuery(DialectMySQL, "shop")
+	require.NoError(t, err)
+	assert.Contains(t, columns, "WHERE table_schema = 'shop'")
+
+	objects, err := SchemaObjectsQuery(DialectMySQL, "shop")
+	require.NoError(t, err)
+	assert.Contains(t, objects, "WHERE routine_schema = 'shop'")
+
+	_, err = SchemaIndexesQuery(Dialect("db2"), "")
+	assert.ErrorContains(t, err, "schema comparison is not supported")
+}
+
+func TestParseSchemaObjectNames(t *testing.T) {
+	snapshot, err := ParseSchemaObjectNames(`[{"object_type": "table", "name": "orders"}, {"OBJECT_TYPE": "view", "NAME": "RECENT"},
+		{"object_type": "function", "name": "total"}, {"object_type": "function", "name": "total"}, {"object_type": "procedure", "name": "archive"}]`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orders"}, snapshot.Tables)
+	assert.Equal(t, []string{"RECENT"}, snapshot.Views)
+	assert.Equal(t, []string{"archive"}, snapshot.Procedures)
+	assert.Equal(t, []string{"total"}, snapshot.Functions)
+}
+
+func TestParseSchemaColumnsAndIndexes(t *testing.T) {
+	columns, err := ParseSchemaColumns(`[{"table_name": "orders", "name": "id", "type": "integer", "nullable": false, "default_value": null},
+		{"table_name": "orders", "name": "total", "type": "numeric(10,2)", "nullable": true, "default_value": "0"},
+		{"TABLE_NAME": "users", "NAME": "ID", "TYPE": "NUMBER(10,0)", "NULLABLE": 0, "DEFAULT_VALUE": null}]`)
+	require.NoError(t, err)
+	zero := "0"
+	assert.Equal(t, map[string][]TableColumn{
+		"orders": {{Name: "id", Type: "integer"}, {Name: "total", Type: "numeric(10,2)", Nullable: true, Default: &zero}},
+		"users":  {{Name: "ID", Type: "NUMBER(10,0)"}},
+	}, columns)
+
+	indexes, err := ParseSchemaIndexes(`[{"table_name": "orders", "index_name": "orders_pkey", "is_unique": true, "is_primary": true, "position": 1, "column_name": "id"},
+		{"table_name": "orders", "index_name": "orders_user_created", "is_unique": false, "is_primary": false, "position": 1, "column_name": "user_id"},
+		{"table_name": "orders", "index_name": "orders_user_created", "is_unique": false, "is_primary": false, "position": 2, "column_name": "created_at"},
+		{"table_name": "users", "index_name": "orders_user_created", "is_unique": true, "is_primary": false, "position": 1, "column_name": "email"}]`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]TableIndex{
+		"orders": {
+			{Name: "orders_pkey", Columns: []string{"id"}, Unique: true, Primary: true},
+			{Name: "orders_user_created", Columns: []string{"user_id", "created_at"}},
+		},
+		"users": {{Name: "orders_user_created", Columns: []string{"email"}, Unique: true}},
+	}, indexes)
+}
+
+func TestDiffSchemas(t *testing.T) {
+	zero, none := "0", "'none'"
+	source := &SchemaSnapshot{
+		Tables:     []string{"orders", "users", "legacy"},
+		Views:      []string{"active_users"},
+		Procedures: []string{"archive_orders"},
+		Columns: map[string][]TableColumn{
+			"orders": {
+				{Name: "id", Type: "integer"},
+				{Name: "total", Type: "numeric(10,2)", Nullable: true},
+				{Name: "note", Type: "text", Nullable: true},
+			},
+			"users":  {{Name: "id", Type: "integer"}, {Name: "status", Type: "text", Default: &none}},
+			"legacy": {{Name: "id", Type: "integer"}},
+		},
+		Indexes: map[string][]TableIndex{
+			"orders": {
+				{Name: "PK__orders__3213E83F", Columns: []string{"id"}, Unique: true, Primary: true},
+				{Name: "orders_total", Columns: []string{"total"}},
+			},
+			"users": {{Name: "users_status", Columns: []string{"status"}}},
+		},
+	}
+	target := &SchemaSnapshot{
+		Tables:     []string{"orders", "users", "audit_log"},
+		Views:      []string{"active_users"},
+		Procedures: []string{"archive_orders"},
+		Functions:  []string{"order_total"},
+		Columns: map[string][]TableColumn{
+			"orders": {
+				{Name: "id", Type: "integer"},
+				{Name: "total", Type: "NUMERIC(12,2)", Default: &zero},
+				{Name: "discount", Type: "numeric(5,2)", Nullable: true},
+			},
+			"users":     {{Name: "id", Type: "INTEGER"}, {Name: "status", Type: "text", Default: &none}},
+			"audit_log": {{Name: "id", Type: "bigint"}},
+		},
+		Indexes: map[string][]TableIndex{
+			"orders": {
+				{Name: "PK__orders__9F1C2A7B", Columns: []string{"id"}, Unique: true, Primary: true},
+				{Name: "orders_total", Columns: []string{"total"}, Unique: true},
+			},
+			"users": {{Name: "users_status", Columns: []string{"status", "id"}}},
+		},
+	}
+
+	diff := DiffSchemas(source, target)
+	assert.Equal(t, []SchemaChange{
+		{Type: ObjectTable, Name: "audit_log"},
+		{Type: ObjectColumn, Table: "orders", Name: "discount", Detail: "numeric(5,2)"},
+		{Type: ObjectFunction, Name: "order_total"},
+	}, diff.Added)
+	assert.Equal(t, []SchemaChange{
+		{Type: ObjectTable, Name: "legacy"},
+		{Type: ObjectColumn, Table: "orders", Name: "note", Detail: "text"},
+	}, diff.Removed)
+	assert.Equal(t, []SchemaChange{
+		{Type: ObjectColumn, Table: "orders", Name: "total", Changes: []FieldChange{
+			{Field: "type", Source: "numeric(10,2)", Target: "NUMERIC(12,2)"},
+			{Field: "nullable", Source: "true", Target: "false"},
+			{Field: "default", Source: "", Target: "0"},
+		}},
+		{Type: ObjectIndex, Table: "orders", Name: "PK__orders__9F1C2A7B", Changes: []FieldChange{
+			{Field: "name", Source: "PK__orders__3213E83F", Target: "PK__orders__9F1C2A7B"},
+		}},
+		{Type: ObjectIndex, Table: "orders", Name: "orders_total", Changes: []FieldChange{
+			{Field: "unique", Source: "false", Target: "true"},
+		}},
+		{Type: ObjectIndex, Table: "users", Name: "users_status", Changes: []FieldChange{
+			{Field: "columns", Source: "status", Target: "status, id"},
+		}},
+	}, diff.Changed)
+	assert.False(t, diff.Empty())
+
+	same := DiffSchemas(source, source)
+	assert.True(t, same.Empty())
+	assert.NotNil(t, same.Added, "empty lists show as [] in JSON")
+}
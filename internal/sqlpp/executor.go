@@ -3,7 +3,9 @@ package sqlpp
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 	"time"
@@ -15,6 +17,14 @@ import (
 const (
 	// MaxLogOutputLength is the maximum length of output to include in logs
 	MaxLogOutputLength = 500
+
+	// processWaitDelay bounds how long Wait blocks on output pipes after the
+	// sqlpp process has been killed by context cancellation
+	processWaitDelay = 5 * time.Second
+
+	// defaultCancelGrace is how long a stopped process group has to exit
+	// after SIGINT before it is sent SIGKILL
+	defaultCancelGrace = 5 * time.Second
 )
 
 // truncateForLogging truncates output for logging purposes to avoid overwhelming logs
@@ -25,11 +35,50 @@ func truncateForLogging(output string) string {
 	return output[:MaxLogOutputLength] + "... (truncated)"
 }
 
+// abortReason describes why the execution context ended early, or returns an
+// empty string if the command was not cut short by its context
+func abortReason(ctx context.Context) string {
+	if errors.Is(context.Cause(ctx), errQueryCancelled) {
+		return "sqlpp command cancelled by cancel_query"
+	}
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return timeoutMessage(ctx)
+	case context.Canceled:
+		return "sqlpp command cancelled by client"
+	default:
+		return ""
+	}
+}
+
 // Executor handles execution of sqlpp commands
 type Executor struct {
 	executablePath string
 	timeout        time.Duration
 	logger         *logrus.Logger
+
+	// Timeout overrides, see SetTimeouts
+	connectionTimeout func(connection string) time.Duration
+	maxTimeout        time.Duration
+
+	// Output capture limits; zero means unbounded
+	maxOutputBytes int64
+	results        *ResultStore
+
+	// Interceptors wrapped around every sqlpp run, outermost first
+	interceptors []Interceptor
+
+	// In-flight processes, and how long they get to exit after SIGINT
+	queries     *QueryRegistry
+	cancelGrace time.Duration
+
+	// Detected sqlpp version and features, and the oldest version accepted
+	capabilities *Capabilities
+	minVersion   *Version
+
+	// Config file, working directory and environment per connection
+	processOptions func(connection string) ProcessOptions
 }
 
 // NewExecutor creates a new sqlpp executor
@@ -38,284 +87,256 @@ func NewExecutor(executablePath string, timeoutSeconds int, logger *logrus.Logge
 		executablePath: executablePath,
 		timeout:        time.Duration(timeoutSeconds) * time.Second,
 		logger:         logger,
+		queries:        NewQueryRegistry(),
+		cancelGrace:    defaultCancelGrace,
 	}
 }
 
+// Queries returns the registry of in-flight sqlpp processes
+func (e *Executor) Queries() *QueryRegistry {
+	return e.queries
+}
+
+// SetCancelGracePeriod sets how long a cancelled or timed-out sqlpp process
+// group has to exit after SIGINT before it is killed
+func (e *Executor) SetCancelGracePeriod(grace time.Duration) {
+	e.cancelGrace = grace
+}
+
+// SetOutputLimit bounds how much sqlpp output is held in memory per call.
+// Output beyond maxBytes is spilled to store, or dropped if store is nil.
+func (e *Executor) SetOutputLimit(maxBytes int64, store *ResultStore) {
+	e.maxOutputBytes = maxBytes
+	e.results = store
+}
+
+// Use appends interceptors to the chain around every sqlpp run. The first
+// interceptor registered is the outermost. Use must be called before the
+// executor serves any calls.
+func (e *Executor) Use(interceptors ...Interceptor) {
+	e.interceptors = append(e.interceptors, interceptors...)
+}
+
 // ExecuteSchemaCommand executes a schema-related command (@schema-*)
-func (e *Executor) ExecuteSchemaCommand(schemaType, connection, filter, output string) (*types.SqlppResult, error) {
-	// Build the schema command
-	schemaCommand := fmt.Sprintf("@schema-%s", schemaType)
+func (e *Executor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	return e.invoke(ctx, stdinRequest(OpSchema, schemaCommand(schemaType, filter), connection, output), e.run)
+}
+
+// schemaCommand builds the sqlpp schema command (@schema-*) for the given type and filter
+func schemaCommand(schemaType, filter string) string {
+	command := fmt.Sprintf("@schema-%s", schemaType)
 	if filter != "" {
-		schemaCommand = fmt.Sprintf("%s %s", schemaCommand, filter)
+		command = fmt.Sprintf("%s %s", command, filter)
 	}
-
-	return e.executeStdinCommandWithOptions(schemaCommand, connection, output)
+	return command
 }
 
 // ExecuteSQLCommand executes a SQL command
-func (e *Executor) ExecuteSQLCommand(connection, command, output string) (*types.SqlppResult, error) {
-	return e.executeStdinCommandWithOptions(command, connection, output)
+func (e *Executor) ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error) {
+	return e.invoke(ctx, stdinRequest(OpSQL, command, connection, output), e.run)
 }
 
 // ListConnections lists available database connections
-func (e *Executor) ListConnections() (*types.SqlppResult, error) {
+func (e *Executor) ListConnections(ctx context.Context) (*types.SqlppResult, error) {
 	args := []string{"--list-connections"}
-	return e.executeCommand(args)
+	return e.executeCommand(ctx, args)
 }
 
 // ListDrivers lists available database drivers
-func (e *Executor) ListDrivers() (*types.SqlppResult, error) {
-	return e.executeStdinCommand("@drivers")
+func (e *Executor) ListDrivers(ctx context.Context) (*types.SqlppResult, error) {
+	return e.invoke(ctx, stdinRequest(OpListDrivers, "@drivers", "", ""), e.run)
 }
 
-// executeCommand executes a sqlpp command with the given arguments
-func (e *Executor) executeCommand(args []string) (*types.SqlppResult, error) {
-	e.logger.WithFields(logrus.Fields{
-		"executable": e.executablePath,
-		"args":       args,
-		"timeout":    e.timeout,
-	}).Debug("Executing sqlpp command")
-
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
-	defer cancel()
-
-	// Create command
-	cmd := exec.CommandContext(ctx, e.executablePath, args...)
-
-	// Capture stdout and stderr
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	// Execute command
-	err := cmd.Run()
-
-	result := &types.SqlppResult{
-		Success: err == nil,
-		Output:  strings.TrimSpace(stdout.String()),
-	}
-
-	if err != nil {
-		stderrStr := strings.TrimSpace(stderr.String())
-		if stderrStr != "" {
-			result.Error = stderrStr
-		} else {
-			result.Error = err.Error()
-		}
-
-		e.logger.WithFields(logrus.Fields{
-			"error":  err,
-			"stderr": stderrStr,
-			"args":   args,
-		}).Error("sqlpp command failed")
-	} else {
-		e.logger.WithFields(logrus.Fields{
-			"args":        args,
-			"output_size": len(result.Output),
-		}).Debug("sqlpp command succeeded")
-
-		// Log truncated output at TRACE level for detailed debugging
-		if e.logger.Level <= logrus.TraceLevel {
-			e.logger.WithFields(logrus.Fields{
-				"args":           args,
-				"output_preview": truncateForLogging(result.Output),
-			}).Trace("sqlpp command output preview")
-		}
-	}
-
-	return result, nil
+// executeCommand executes a sqlpp command with the given arguments and no input
+func (e *Executor) executeCommand(ctx context.Context, args []string) (*types.SqlppResult, error) {
+	return e.invoke(ctx, &Request{Operation: OpListConnections, Args: args}, e.run)
 }
 
-// executeStdinCommandWithOptions executes a sqlpp command by sending input via stdin with connection and output options
-func (e *Executor) executeStdinCommandWithOptions(input, connection, output string) (*types.SqlppResult, error) {
-	args := []string{"--stdin"}
-
-	if connection != "" {
-		args = append(args, "--connection", connection)
+// stdinRequest builds a request that sends input to sqlpp on stdin
+func stdinRequest(op Operation, input, connection, output string) *Request {
+	return &Request{
+		Operation:  op,
+		Args:       append([]string{"--stdin"}, connectionArgs(connection, output)...),
+		Input:      input,
+		Stdin:      true,
+		Connection: connection,
+		Output:     output,
 	}
+}
 
-	if output != "" {
-		args = append(args, "--output", output)
-	}
+// invoke runs req through the interceptor chain around core
+func (e *Executor) invoke(ctx context.Context, req *Request, core Runner) (*types.SqlppResult, error) {
+	return chain(e.interceptors, core)(ctx, req)
+}
 
-	e.logger.WithFields(logrus.Fields{
-		"executable": e.executablePath,
-		"args":       args,
-		"input":      input,
-		"timeout":    e.timeout,
-	}).Debug("Executing sqlpp command with stdin and options")
-
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+// run is the core runner: it starts one sqlpp process for req, feeds its
+// input, captures output and shapes the result
+func (e *Executor) run(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+	// Derive the execution context from the caller's so cancellation propagates
+	ctx, cancel := e.withTimeout(ctx, req.Connection)
 	defer cancel()
-
-	// Create command with args
-	cmd := exec.CommandContext(ctx, e.executablePath, args...)
-
-	// Set up stdin pipe
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	ctx, cancelQuery := context.WithCancelCause(ctx)
+	defer cancelQuery(nil)
+
+	// Run sqlpp in its own process group and stop the whole group, first
+	// with SIGINT, when the call times out or is cancelled
+	cmd := e.command(ctx, req.Connection, req.Args)
+	setProcessGroup(cmd)
+	stopper := &groupStopper{cmd: cmd, grace: e.cancelGrace}
+	cmd.Cancel = stopper.stop
+	cmd.WaitDelay = e.cancelGrace + processWaitDelay
+
+	// Feed input on stdin; exec copies it in the background so a large input
+	// cannot deadlock against output that is being streamed back
+	if req.Stdin {
+		cmd.Stdin = strings.NewReader(req.Input)
 	}
 
-	// Capture stdout and stderr
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
+	// Capture stdout and stderr. When the caller asked for progress, stdout is
+	// read line by line through a pipe so updates flow while sqlpp runs.
+	stdout := e.newOutputBuffer(ctx)
+	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
+	report := progressFrom(ctx)
+	var stdoutPipe io.ReadCloser
+	if report != nil {
+		pipe, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		}
+		stdoutPipe = pipe
+	} else {
+		cmd.Stdout = stdout
+	}
+
 	// Start the command
+	start := time.Now()
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start command: %w", err)
 	}
+	untrack := e.queries.track(ctx, req, cmd.Process.Pid, false, cancelQuery)
+	defer untrack()
 
-	// Write input to stdin and close it
-	if _, err := stdin.Write([]byte(input)); err != nil {
-		stdin.Close()
-		return nil, fmt.Errorf("failed to write to stdin: %w", err)
+	if stdoutPipe != nil {
+		if err := newProgressTracker(report).stream(stdoutPipe, stdout); err != nil {
+			e.logger.WithError(err).Warn("Error streaming sqlpp output")
+		}
 	}
-	stdin.Close()
 
 	// Wait for command to complete
-	err = cmd.Wait()
+	err := cmd.Wait()
+	stopper.exit()
 
-	result := &types.SqlppResult{
-		Success: err == nil,
-		Output:  strings.TrimSpace(stdout.String()),
-	}
+	result := &types.SqlppResult{Success: err == nil}
+	e.captureOutput(result, stdout)
+	recordExecution(result, cmd.Args[1:], start, err, stderr.String())
 
 	if err != nil {
-		stderrStr := strings.TrimSpace(stderr.String())
-		if stderrStr != "" {
-			result.Error = stderrStr
+		if reason := abortReason(ctx); reason != "" {
+			result.Error = reason
+		} else if result.Stderr != "" {
+			result.Error = result.Stderr
 		} else {
 			result.Error = err.Error()
 		}
-
-		e.logger.WithFields(logrus.Fields{
-			"error":  err,
-			"stderr": stderrStr,
-			"args":   args,
-			"input":  input,
-		}).Error("sqlpp stdin command with options failed")
-	} else {
-		e.logger.WithFields(logrus.Fields{
-			"args":        args,
-			"input":       input,
-			"output_size": len(result.Output),
-		}).Debug("sqlpp stdin command with options succeeded")
-
-		// Log truncated output at TRACE level for detailed debugging
-		if e.logger.Level <= logrus.TraceLevel {
-			e.logger.WithFields(logrus.Fields{
-				"args":           args,
-				"input":          input,
-				"output_preview": truncateForLogging(result.Output),
-			}).Trace("sqlpp stdin command output preview")
-		}
+		result.ErrorCode = string(ClassifyResult(ctx, result))
 	}
 
 	return result, nil
 }
 
-// executeStdinCommand executes a sqlpp command by sending input via stdin
-func (e *Executor) executeStdinCommand(input string) (*types.SqlppResult, error) {
-	e.logger.WithFields(logrus.Fields{
-		"executable": e.executablePath,
-		"input":      input,
-		"timeout":    e.timeout,
-	}).Debug("Executing sqlpp command with stdin")
-
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
-	defer cancel()
-
-	// Create command with --stdin flag
-	cmd := exec.CommandContext(ctx, e.executablePath, "--stdin")
+// newOutputBuffer creates the stdout capture buffer for a call
+func (e *Executor) newOutputBuffer(ctx context.Context) *outputBuffer {
+	return &outputBuffer{
+		limit:   e.maxOutputBytes,
+		store:   e.results,
+		session: SessionIDFrom(ctx),
+	}
+}
 
-	// Set up stdin pipe
-	stdin, err := cmd.StdinPipe()
+// captureOutput fills the output fields of result from a finished buffer
+func (e *Executor) captureOutput(result *types.SqlppResult, stdout *outputBuffer) {
+	output, handle, err := stdout.finish()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+		e.logger.WithError(err).Warn("Failed to store spilled sqlpp output")
 	}
 
-	// Capture stdout and stderr
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	result.Output = strings.TrimSpace(output)
+	result.OutputSize = stdout.size
+	result.Truncated = stdout.truncated()
+	result.ResultHandle = handle
 
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start command: %w", err)
+	if result.Truncated {
+		e.logger.WithFields(logrus.Fields{
+			"output_size":   stdout.size,
+			"memory_limit":  e.maxOutputBytes,
+			"result_handle": handle,
+		}).Info("sqlpp output exceeded in-memory limit")
 	}
+}
 
-	// Write input to stdin and close it
-	if _, err := stdin.Write([]byte(input)); err != nil {
-		stdin.Close()
-		return nil, fmt.Errorf("failed to write to stdin: %w", err)
+// recordExecution fills the execution details of a finished command into result
+func recordExecution(result *types.SqlppResult, args []string, start time.Time, err error, stderr string) {
+	result.Args = args
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.ExitCode = exitCode(err)
+	result.Stderr = strings.TrimSpace(stderr)
+}
+
+// exitCode returns the process exit code for a command error: 0 on success,
+// the status on a normal non-zero exit, and -1 if the process did not exit
+// normally (killed by a signal, or never started)
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
 	}
-	stdin.Close()
+	return -1
+}
 
-	// Wait for command to complete
-	err = cmd.Wait()
+// connectionArgs builds the sqlpp flags selecting a connection and output format
+func connectionArgs(connection, output string) []string {
+	var args []string
 
-	result := &types.SqlppResult{
-		Success: err == nil,
-		Output:  strings.TrimSpace(stdout.String()),
+	if connection != "" {
+		args = append(args, "--connection", connection)
 	}
 
-	if err != nil {
-		stderrStr := strings.TrimSpace(stderr.String())
-		if stderrStr != "" {
-			result.Error = stderrStr
-		} else {
-			result.Error = err.Error()
-		}
-
-		e.logger.WithFields(logrus.Fields{
-			"error":  err,
-			"stderr": stderrStr,
-			"input":  input,
-		}).Error("sqlpp stdin command failed")
-	} else {
-		e.logger.WithFields(logrus.Fields{
-			"input":       input,
-			"output_size": len(result.Output),
-		}).Debug("sqlpp stdin command succeeded")
-
-		// Log truncated output at TRACE level for detailed debugging
-		if e.logger.Level <= logrus.TraceLevel {
-			e.logger.WithFields(logrus.Fields{
-				"input":          input,
-				"output_preview": truncateForLogging(result.Output),
-			}).Trace("sqlpp stdin command output preview")
-		}
+	if output != "" {
+		args = append(args, "--output", output)
 	}
 
-	return result, nil
+	return args
 }
 
-// ValidateExecutable checks if the sqlpp executable is available and working
-func (e *Executor) ValidateExecutable() error {
+// ValidateExecutable checks that the sqlpp executable is available and
+// working, detects its version and supported features, and enforces the
+// minimum version if one is set
+func (e *Executor) ValidateExecutable(ctx context.Context) error {
 	e.logger.WithField("executable", e.executablePath).Debug("Validating sqlpp executable")
 
-	// Try to run sqlpp with --version or --help to check if it's available
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	caps, err := e.detectCapabilities(ctx)
+	if err != nil {
+		return err
+	}
 
-	cmd := exec.CommandContext(ctx, e.executablePath, "--help")
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	if err := e.checkMinVersion(caps); err != nil {
+		return err
+	}
+	e.capabilities = caps
 
-	err := cmd.Run()
-	if err != nil {
-		stderrStr := strings.TrimSpace(stderr.String())
-		if stderrStr != "" {
-			return fmt.Errorf("sqlpp executable validation failed: %s", stderrStr)
-		}
-		return fmt.Errorf("sqlpp executable not found or not working: %w", err)
+	if caps.Version == nil {
+		e.logger.Warn("Could not determine sqlpp version")
 	}
 
-	e.logger.Info("sqlpp executable validated successfully")
+	e.logger.WithFields(logrus.Fields{
+		"version":  caps.VersionString(),
+		"flags":    len(caps.Flags),
+		"commands": len(caps.Commands),
+	}).Info("sqlpp executable validated successfully")
 	return nil
 }
@@ -1,9 +1,13 @@
 package sqlpp
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -37,7 +41,7 @@ echo '{"tables": ["table1", "table2"]}'
 	logger.SetLevel(logrus.DebugLevel)
 	executor := NewExecutor(mockSqlpp, 30, logger)
 
-	result, err := executor.ExecuteSchemaCommand("tables", "test-conn", "test*", "json")
+	result, err := executor.ExecuteSchemaCommand(context.Background(), "tables", "test-conn", "test*", "json")
 	require.NoError(t, err)
 	require.NotNil(t, result)
 
@@ -64,7 +68,7 @@ echo '{"rows": [{"id": 1, "name": "test"}]}'
 	logger := logrus.New()
 	executor := NewExecutor(mockSqlpp, 30, logger)
 
-	result, err := executor.ExecuteSQLCommand("test-conn", "SELECT * FROM users", "json")
+	result, err := executor.ExecuteSQLCommand(context.Background(), "test-conn", "SELECT * FROM users", "json")
 	require.NoError(t, err)
 	require.NotNil(t, result)
 
@@ -92,7 +96,7 @@ echo '["conn1", "conn2", "conn3"]'
 	logger := logrus.New()
 	executor := NewExecutor(mockSqlpp, 30, logger)
 
-	result, err := executor.ListConnections()
+	result, err := executor.ListConnections(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result)
 
@@ -118,7 +122,7 @@ echo '["mysql", "postgresql", "sqlite"]'
 	logger := logrus.New()
 	executor := NewExecutor(mockSqlpp, 30, logger)
 
-	result, err := executor.ListDrivers()
+	result, err := executor.ListDrivers(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result)
 
@@ -143,12 +147,146 @@ exit 1
 	logger := logrus.New()
 	executor := NewExecutor(mockSqlpp, 30, logger)
 
-	result, err := executor.ListConnections()
+	result, err := executor.ListConnections(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result)
 
 	assert.False(t, result.Success)
 	assert.Contains(t, result.Error, "Connection failed")
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, "Error: Connection failed", result.Stderr)
+	assert.Equal(t, []string{"--list-connections"}, result.Args)
+	assert.Equal(t, string(CodeUnknown), result.ErrorCode)
+}
+
+func TestExecuteSQLCommand_Metadata(t *testing.T) {
+	// Create a mock sqlpp executable that succeeds with a warning
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+cat > /dev/null
+echo "Warning: implicit conversion" >&2
+echo '[{"id":1}]'
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	executor := NewExecutor(mockSqlpp, 30, logger)
+
+	result, err := executor.ExecuteSQLCommand(context.Background(), "main", "SELECT 1", "json")
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	assert.True(t, result.Success)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "Warning: implicit conversion", result.Stderr)
+	assert.Equal(t, []string{"--stdin", "--connection", "main", "--output", "json"}, result.Args)
+	assert.GreaterOrEqual(t, result.DurationMs, int64(0))
+	assert.Empty(t, result.Error)
+}
+
+func TestExecuteSQLCommand_ContextCancelled(t *testing.T) {
+	// Create a mock sqlpp executable that hangs until killed
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+exec sleep 30
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	executor := NewExecutor(mockSqlpp, 30, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	result, err := executor.ExecuteSQLCommand(ctx, "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "cancelled")
+	assert.Equal(t, string(CodeCancelled), result.ErrorCode)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestExecuteSQLCommand_WithProgress(t *testing.T) {
+	// Create a mock sqlpp executable that emits rows over time
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+cat > /dev/null
+echo "row 1"
+sleep 0.6
+echo "row 2"
+echo "row 3"
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	executor := NewExecutor(mockSqlpp, 30, logger)
+
+	var mu sync.Mutex
+	var reports []Progress
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, p)
+	})
+
+	result, err := executor.ExecuteSQLCommand(ctx, "test-conn", "SELECT * FROM users", "table")
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	assert.True(t, result.Success)
+	assert.Equal(t, "row 1\nrow 2\nrow 3", result.Output)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.GreaterOrEqual(t, len(reports), 2)
+	final := reports[len(reports)-1]
+	assert.True(t, final.Done)
+	assert.Equal(t, 3, final.Rows)
+	assert.Equal(t, int64(len("row 1\nrow 2\nrow 3\n")), final.Bytes)
+}
+
+// chunkRecorder records the size of each write
+type chunkRecorder struct {
+	sizes []int
+}
+
+func (c *chunkRecorder) Write(p []byte) (int, error) {
+	c.sizes = append(c.sizes, len(p))
+	return len(p), nil
+}
+
+func TestCopyChunks_LongLine(t *testing.T) {
+	line := strings.Repeat("x", 3*streamChunkSize+10)
+	dst := &chunkRecorder{}
+	var total, lines int
+
+	err := copyChunks(strings.NewReader("row 1\n"+line), dst, func(n, l int) {
+		total += n
+		lines += l
+	})
+	require.NoError(t, err)
+
+	// The line is passed on in pieces, and counted once it ends
+	assert.Equal(t, len(line)+6, total)
+	assert.Equal(t, 2, lines)
+	for _, size := range dst.sizes {
+		assert.LessOrEqual(t, size, streamChunkSize)
+	}
 }
 
 func TestValidateExecutable_Success(t *testing.T) {
@@ -167,7 +305,7 @@ exit 0
 	logger := logrus.New()
 	executor := NewExecutor(mockSqlpp, 30, logger)
 
-	err = executor.ValidateExecutable()
+	err = executor.ValidateExecutable(context.Background())
 	assert.NoError(t, err)
 }
 
@@ -175,7 +313,7 @@ func TestValidateExecutable_Failure(t *testing.T) {
 	logger := logrus.New()
 	executor := NewExecutor("/nonexistent/sqlpp", 30, logger)
 
-	err := executor.ValidateExecutable()
+	err := executor.ValidateExecutable(context.Background())
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "sqlpp executable not found")
 }
@@ -195,7 +333,7 @@ echo "No arguments provided"
 	logger := logrus.New()
 	executor := NewExecutor(mockSqlpp, 30, logger)
 
-	result, err := executor.executeCommand([]string{})
+	result, err := executor.executeCommand(context.Background(), []string{})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 
@@ -0,0 +1,516 @@
+package sqlpp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrExplainUnsupported is returned for dialects whose query plans cannot be
+// requested in a single statement
+var ErrExplainUnsupported = errors.New("query plans are not supported")
+
+// PlanNode is one operation of a query plan, normalised across dialects
+type PlanNode struct {
+	Operation     string      `json:"operation"`                // e.g. Seq Scan, SEARCH, Nested loop
+	Relation      string      `json:"relation,omitempty"`       // table or view the operation reads
+	Index         string      `json:"index,omitempty"`          // index the operation uses
+	EstimatedRows float64     `json:"estimated_rows,omitempty"` // rows the planner expects
+	EstimatedCost float64     `json:"estimated_cost,omitempty"` // planner cost, in the database's own units
+	Detail        string      `json:"detail,omitempty"`         // conditions, sort keys and other notes
+	Children      []*PlanNode `json:"children,omitempty"`
+}
+
+// QueryPlan is the plan the database chose for a query
+type QueryPlan struct {
+	Dialect   Dialect     `json:"dialect"`
+	Statement string      `json:"statement"` // statement sent to the database
+	Nodes     []*PlanNode `json:"nodes"`     // top-level operations
+}
+
+// ExplainStatement wraps query, which must be a single statement, in the
+// syntax that asks dialect for its plan as JSON or XML. SQL Server plans are
+// requested with SET SHOWPLAN_XML, which must be alone in its batch, so its
+// statement is split with GO separators.
+func ExplainStatement(dialect Dialect, query string) (string, error) {
+	statement, err := singleStatement(query)
+	if err != nil {
+		return "", err
+	}
+
+	switch dialect {
+	case DialectSQLite:
+		return "EXPLAIN QUERY PLAN " + statement, nil
+	case DialectPostgres:
+		return "EXPLAIN (FORMAT JSON) " + statement, nil
+	case DialectMySQL:
+		return "EXPLAIN FORMAT=JSON " + statement, nil
+	case DialectSQLServer:
+		return "SET SHOWPLAN_XML ON\nGO\n" + statement + "\nGO\nSET SHOWPLAN_XML OFF\nGO\n", nil
+	default:
+		return "", fmt.Errorf("%w for %s", ErrExplainUnsupported, dialect)
+	}
+}
+
+// singleStatement returns query without comments around it or a trailing
+// semicolon, checking that it is one statement
+func singleStatement(query string) (string, error) {
+	tokens := lexSQL(query)
+	statements := splitStatements(query, tokens)
+	switch {
+	case len(statements) == 0:
+		return "", fmt.Errorf("query is empty")
+	case len(statements) > 1:
+		return "", fmt.Errorf("query has %d statements; explain one at a time", len(statements))
+	}
+
+	statement := statements[0]
+	for _, tok := range statement {
+		if tok.kind == tokenDirective {
+			return "", fmt.Errorf("queries with sqlpp directives such as %s cannot be explained", strings.Fields(tok.text)[0])
+		}
+	}
+	if statement[0].upper() == "EXPLAIN" {
+		return "", fmt.Errorf("query is already an EXPLAIN statement")
+	}
+	return query[statement[0].start:statement[len(statement)-1].end], nil
+}
+
+// ParsePlan reads the plan from the JSON output of a statement built by
+// ExplainStatement
+func ParsePlan(dialect Dialect, output string) ([]*PlanNode, error) {
+	switch dialect {
+	case DialectSQLite:
+		rows, err := resultRows(output)
+		if err != nil {
+			return nil, err
+		}
+		return sqlitePlan(rows)
+	case DialectPostgres:
+		doc, err := planDocument(output)
+		if err != nil {
+			return nil, err
+		}
+		return postgresPlan(doc)
+	case DialectMySQL:
+		doc, err := planDocument(output)
+		if err != nil {
+			return nil, err
+		}
+		block, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected a JSON object, got %s", jsonType(doc))
+		}
+		return mysqlChildren(block), nil
+	case DialectSQLServer:
+		doc, err := showPlanXML(output)
+		if err != nil {
+			return nil, err
+		}
+		return sqlServerPlan(doc)
+	default:
+		return nil, fmt.Errorf("%w for %s", ErrExplainUnsupported, dialect)
+	}
+}
+
+// resultRows decodes the rows of sqlpp JSON output, which may hold several
+// result sets one after another
+func resultRows(output string) ([]map[string]interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(output))
+	var rows []map[string]interface{}
+	for {
+		var value interface{}
+		err := dec.Decode(&value)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("output is not JSON: %w", err)
+		}
+
+		switch v := value.(type) {
+		case []interface{}:
+			for _, item := range v {
+				if row, ok := item.(map[string]interface{}); ok {
+					rows = append(rows, row)
+				}
+			}
+		case map[string]interface{}:
+			rows = append(rows, v)
+		}
+	}
+	return rows, nil
+}
+
+// planDocument returns the JSON plan held in the single column of the first
+// row, as Postgres and MySQL return it. The column may hold the plan as text
+// or already decoded.
+func planDocument(output string) (interface{}, error) {
+	rows, err := resultRows(output)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no plan in output")
+	}
+	if len(rows[0]) != 1 {
+		return nil, fmt.Errorf("expected one plan column, got %d", len(rows[0]))
+	}
+
+	for _, value := range rows[0] {
+		text, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+		var doc interface{}
+		if err := json.Unmarshal([]byte(text), &doc); err != nil {
+			return nil, fmt.Errorf("plan is not JSON: %w", err)
+		}
+		return doc, nil
+	}
+	return nil, nil
+}
+
+// sqlitePlan builds the tree from EXPLAIN QUERY PLAN rows, which link to
+// their parent by id
+func sqlitePlan(rows []map[string]interface{}) ([]*PlanNode, error) {
+	var roots []*PlanNode
+	nodes := make(map[float64]*PlanNode, len(rows))
+	for _, row := range rows {
+		detail, ok := row["detail"].(string)
+		if !ok {
+			return nil, fmt.Errorf("plan row has no detail column")
+		}
+		node := sqliteNode(detail)
+
+		// Top-level rows have parent 0
+		if parent, ok := nodes[numberValue(row["parent"])]; ok && numberValue(row["parent"]) != 0 {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+		nodes[numberValue(row["id"])] = node
+	}
+	return roots, nil
+}
+
+// sqliteNode reads the table and index from a detail such as
+// "SEARCH users USING INDEX users_email (email=?)", keeping the text after
+// the table as the node's detail
+func sqliteNode(detail string) *PlanNode {
+	words := strings.Fields(detail)
+	if len(words) < 2 || (words[0] != "SCAN" && words[0] != "SEARCH") {
+		return &PlanNode{Operation: detail}
+	}
+
+	node := &PlanNode{Operation: words[0]}
+	rest := words[1:]
+	// Versions before 3.36 write SCAN TABLE users
+	if rest[0] == "TABLE" && len(rest) > 1 {
+		rest = rest[1:]
+	}
+	node.Relation = rest[0]
+	node.Detail = strings.Join(rest[1:], " ")
+
+	for i, word := range rest {
+		switch {
+		case word == "INDEX" && i+1 < len(rest):
+			node.Index = rest[i+1]
+		case word == "PRIMARY" && i+1 < len(rest) && rest[i+1] == "KEY":
+			node.Index = "PRIMARY KEY"
+		}
+	}
+	return node
+}
+
+// postgresDetails are the Postgres plan fields summarised in Detail, in order
+var postgresDetails = []string{"Join Type", "Index Cond", "Hash Cond", "Merge Cond", "Join Filter", "Filter", "Sort Key", "Group Key"}
+
+// postgresPlan reads EXPLAIN (FORMAT JSON) output: an array holding one
+// object whose Plan is the root node
+func postgresPlan(doc interface{}) ([]*PlanNode, error) {
+	items, ok := doc.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON array, got %s", jsonType(doc))
+	}
+
+	var roots []*PlanNode
+	for _, item := range items {
+		entry, _ := item.(map[string]interface{})
+		plan, ok := entry["Plan"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("plan has no Plan object")
+		}
+		roots = append(roots, postgresNode(plan))
+	}
+	return roots, nil
+}
+
+func postgresNode(plan map[string]interface{}) *PlanNode {
+	node := &PlanNode{
+		Operation:     stringValue(plan["Node Type"]),
+		Relation:      stringValue(plan["Relation Name"]),
+		Index:         stringValue(plan["Index Name"]),
+		EstimatedRows: numberValue(plan["Plan Rows"]),
+		EstimatedCost: numberValue(plan["Total Cost"]),
+	}
+	if node.Relation == "" {
+		node.Relation = stringValue(plan["CTE Name"])
+	}
+
+	var details []string
+	for _, key := range postgresDetails {
+		if value := stringValue(plan[key]); value != "" {
+			details = append(details, key+": "+value)
+		}
+	}
+	node.Detail = strings.Join(details, "; ")
+
+	children, _ := plan["Plans"].([]interface{})
+	for _, child := range children {
+		if child, ok := child.(map[string]interface{}); ok {
+			node.Children = append(node.Children, postgresNode(child))
+		}
+	}
+	return node
+}
+
+// mysqlOperations names the MySQL plan objects that wrap other operations
+var mysqlOperations = map[string]string{
+	"query_block":                "Query block",
+	"ordering_operation":         "Sort",
+	"grouping_operation":         "Group",
+	"duplicates_removal":         "Distinct",
+	"windowing":                  "Window",
+	"union_result":               "Union",
+	"nested_loop":                "Nested loop",
+	"materialized_from_subquery": "Materialize",
+}
+
+// mysqlChildKeys are the fields of MySQL plan objects that hold operations,
+// in the order they are read. query_specifications and attached_subqueries
+// are lists whose items hold operations themselves.
+var mysqlChildKeys = []string{
+	"query_block", "union_result", "windowing", "ordering_operation", "grouping_operation", "duplicates_removal",
+	"nested_loop", "table", "materialized_from_subquery", "query_specifications", "attached_subqueries",
+}
+
+// mysqlAccessTypes describes MySQL table access types
+var mysqlAccessTypes = map[string]string{
+	"ALL":         "Table scan",
+	"index":       "Full index scan",
+	"range":       "Index range scan",
+	"ref":         "Index lookup",
+	"ref_or_null": "Index lookup",
+	"eq_ref":      "Unique index lookup",
+	"const":       "Constant row",
+	"system":      "Constant row",
+	"fulltext":    "Fulltext index",
+	"index_merge": "Index merge",
+}
+
+// mysqlChildren returns the operations held in a MySQL plan object
+func mysqlChildren(obj map[string]interface{}) []*PlanNode {
+	var nodes []*PlanNode
+	for _, key := range mysqlChildKeys {
+		switch value := obj[key].(type) {
+		case map[string]interface{}:
+			nodes = append(nodes, mysqlNode(key, value))
+		case []interface{}:
+			var items []*PlanNode
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					items = append(items, mysqlChildren(item)...)
+				}
+			}
+			if name, ok := mysqlOperations[key]; ok {
+				nodes = append(nodes, &PlanNode{Operation: name, Children: items})
+			} else {
+				nodes = append(nodes, items...)
+			}
+		}
+	}
+	return nodes
+}
+
+func mysqlNode(key string, obj map[string]interface{}) *PlanNode {
+	costs, _ := obj["cost_info"].(map[string]interface{})
+	if key == "table" {
+		access := stringValue(obj["access_type"])
+		node := &PlanNode{
+			Operation:     access,
+			Relation:      stringValue(obj["table_name"]),
+			Index:         stringValue(obj["key"]),
+			EstimatedRows: numberValue(obj["rows_examined_per_scan"]),
+			EstimatedCost: numberValue(costs["prefix_cost"]),
+			Detail:        stringValue(obj["attached_condition"]),
+			Children:      mysqlChildren(obj),
+		}
+		if name, ok := mysqlAccessTypes[access]; ok {
+			node.Operation = name
+		}
+		if node.Operation == "" {
+			node.Operation = "Table"
+		}
+		return node
+	}
+
+	node := &PlanNode{
+		Operation:     mysqlOperations[key],
+		EstimatedCost: numberValue(costs["query_cost"]),
+		Children:      mysqlChildren(obj),
+	}
+	if node.EstimatedCost == 0 {
+		node.EstimatedCost = numberValue(costs["sort_cost"])
+	}
+
+	var details []string
+	if message := stringValue(obj["message"]); message != "" {
+		details = append(details, message)
+	}
+	if obj["using_filesort"] == true {
+		details = append(details, "using filesort")
+	}
+	if obj["using_temporary_table"] == true {
+		details = append(details, "using temporary table")
+	}
+	node.Detail = strings.Join(details, "; ")
+	return node
+}
+
+// showPlanXML finds the ShowPlanXML document in sqlpp output, either in a
+// column of the JSON result or, when the output is not JSON, in the text
+func showPlanXML(output string) (string, error) {
+	if rows, err := resultRows(output); err == nil {
+		for _, row := range rows {
+			for _, value := range row {
+				if text, ok := value.(string); ok && strings.Contains(text, "<ShowPlanXML") {
+					return text, nil
+				}
+			}
+		}
+		return "", fmt.Errorf("no ShowPlanXML document in output")
+	}
+
+	start := strings.Index(output, "<ShowPlanXML")
+	end := strings.LastIndex(output, "</ShowPlanXML>")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("no ShowPlanXML document in output")
+	}
+	return output[start : end+len("</ShowPlanXML>")], nil
+}
+
+// sqlServerPlan builds the tree from the nested RelOp elements of a
+// ShowPlanXML document
+func sqlServerPlan(doc string) ([]*PlanNode, error) {
+	dec := xml.NewDecoder(strings.NewReader(doc))
+	var roots, stack []*PlanNode
+	predicate := 0 // depth inside Predicate elements of the current operation
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("plan is not valid XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "RelOp":
+				node := &PlanNode{
+					Operation:     xmlAttr(t, "PhysicalOp"),
+					EstimatedRows: numberValue(xmlAttr(t, "EstimateRows")),
+					EstimatedCost: numberValue(xmlAttr(t, "EstimatedTotalSubtreeCost")),
+				}
+				if len(stack) > 0 {
+					parent := stack[len(stack)-1]
+					parent.Children = append(parent.Children, node)
+				} else {
+					roots = append(roots, node)
+				}
+				stack = append(stack, node)
+				predicate = 0
+			case "Object":
+				if len(stack) > 0 && stack[len(stack)-1].Relation == "" {
+					node := stack[len(stack)-1]
+					node.Relation = unbracket(xmlAttr(t, "Table"))
+					if schema := unbracket(xmlAttr(t, "Schema")); schema != "" && node.Relation != "" {
+						node.Relation = schema + "." + node.Relation
+					}
+					node.Index = unbracket(xmlAttr(t, "Index"))
+				}
+			case "Predicate":
+				predicate++
+			case "ScalarOperator":
+				if predicate > 0 && len(stack) > 0 && stack[len(stack)-1].Detail == "" {
+					stack[len(stack)-1].Detail = xmlAttr(t, "ScalarString")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "RelOp":
+				stack = stack[:len(stack)-1]
+			case "Predicate":
+				predicate--
+			}
+		}
+	}
+
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("plan has no operations")
+	}
+	return roots, nil
+}
+
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// unbracket removes the [] quoting SQL Server puts around names
+func unbracket(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, "["), "]")
+}
+
+// stringValue renders a field as text; lists are joined with commas
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = stringValue(item)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// numberValue reads a field that may be a JSON number or a number in a
+// string, as MySQL writes costs. Anything else reads as zero.
+func numberValue(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		n, _ := strconv.ParseFloat(v, 64)
+		return n
+	default:
+		return 0
+	}
+}
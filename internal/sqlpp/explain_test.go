@@ -0,0 +1,143 @@
+package sqlpp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainStatement(t *testing.T) {
+	query := "-- active users\nSELECT * FROM users WHERE active = 1;\n"
+	tests := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{DialectSQLite, "EXPLAIN QUERY PLAN SELECT * FROM users WHERE active = 1"},
+		{DialectPostgres, "EXPLAIN (FORMAT JSON) SELECT * FROM users WHERE active = 1"},
+		{DialectMySQL, "EXPLAIN FORMAT=JSON SELECT * FROM users WHERE active = 1"},
+		{DialectSQLServer, "SET SHOWPLAN_XML ON\nGO\nSELECT * FROM users WHERE active = 1\nGO\nSET SHOWPLAN_XML OFF\nGO\n"},
+	}
+	for _, tt := range tests {
+		statement, err := ExplainStatement(tt.dialect, query)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, statement, tt.dialect)
+	}
+
+	_, err := ExplainStatement(DialectOracle, query)
+	assert.ErrorIs(t, err, ErrExplainUnsupported)
+
+	_, err = ExplainStatement(DialectPostgres, "SELECT 1; SELECT 2")
+	assert.ErrorContains(t, err, "query has 2 statements")
+
+	_, err = ExplainStatement(DialectPostgres, "-- nothing")
+	assert.ErrorContains(t, err, "query is empty")
+
+	_, err = ExplainStatement(DialectPostgres, "EXPLAIN SELECT 1")
+	assert.ErrorContains(t, err, "already an EXPLAIN statement")
+}
+
+func TestParsePlan_SQLite(t *testing.T) {
+	output := `[
+		{"id": 3, "parent": 0, "notused": 0, "detail": "SCAN TABLE orders"},
+		{"id": 7, "parent": 0, "notused": 0, "detail": "SEARCH users USING INDEX users_pk (id=?)"},
+		{"id": 9, "parent": 0, "notused": 0, "detail": "CORRELATED SCALAR SUBQUERY 1"},
+		{"id": 12, "parent": 9, "notused": 0, "detail": "SEARCH items USING INTEGER PRIMARY KEY (rowid=?)"}
+	]`
+	nodes, err := ParsePlan(DialectSQLite, output)
+	require.NoError(t, err)
+	require.Len(t, nodes, 3)
+
+	assert.Equal(t, &PlanNode{Operation: "SCAN", Relation: "orders"}, nodes[0])
+	assert.Equal(t, &PlanNode{Operation: "SEARCH", Relation: "users", Index: "users_pk", Detail: "USING INDEX users_pk (id=?)"}, nodes[1])
+	assert.Equal(t, "CORRELATED SCALAR SUBQUERY 1", nodes[2].Operation)
+	require.Len(t, nodes[2].Children, 1)
+	assert.Equal(t, "items", nodes[2].Children[0].Relation)
+	assert.Equal(t, "PRIMARY KEY", nodes[2].Children[0].Index)
+}
+
+func TestParsePlan_Postgres(t *testing.T) {
+	// The plan column arrives as text
+	output := `[{"QUERY PLAN": "[{\"Plan\": {\"Node Type\": \"Hash Join\", \"Join Type\": \"Inner\", \"Total Cost\": 35.5, \"Plan Rows\": 120, \"Hash Cond\": \"(o.user_id = u.id)\", \"Plans\": [{\"Node Type\": \"Seq Scan\", \"Relation Name\": \"orders\", \"Total Cost\": 20, \"Plan Rows\": 1000, \"Filter\": \"(total > 10)\"}, {\"Node Type\": \"Index Scan\", \"Relation Name\": \"users\", \"Index Name\": \"users_pkey\", \"Total Cost\": 8.3, \"Plan Rows\": 1}]}}]"}]`
+	nodes, err := ParsePlan(DialectPostgres, output)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+
+	root := nodes[0]
+	assert.Equal(t, "Hash Join", root.Operation)
+	assert.Equal(t, 35.5, root.EstimatedCost)
+	assert.Equal(t, float64(120), root.EstimatedRows)
+	assert.Equal(t, "Join Type: Inner; Hash Cond: (o.user_id = u.id)", root.Detail)
+	require.Len(t, root.Children, 2)
+	assert.Equal(t, &PlanNode{Operation: "Seq Scan", Relation: "orders", EstimatedRows: 1000, EstimatedCost: 20, Detail: "Filter: (total > 10)"}, root.Children[0])
+	assert.Equal(t, "users_pkey", root.Children[1].Index)
+
+	// The plan column arrives decoded
+	nodes, err = ParsePlan(DialectPostgres, `[{"QUERY PLAN": [{"Plan": {"Node Type": "Result"}}]}]`)
+	require.NoError(t, err)
+	assert.Equal(t, []*PlanNode{{Operation: "Result"}}, nodes)
+
+	_, err = ParsePlan(DialectPostgres, "ERROR: syntax error")
+	assert.ErrorContains(t, err, "output is not JSON")
+}
+
+func TestParsePlan_MySQL(t *testing.T) {
+	output := `[{"EXPLAIN": "{\"query_block\": {\"select_id\": 1, \"cost_info\": {\"query_cost\": \"12.40\"}, \"ordering_operation\": {\"using_filesort\": true, \"nested_loop\": [{\"table\": {\"table_name\": \"o\", \"access_type\": \"ALL\", \"rows_examined_per_scan\": 100, \"cost_info\": {\"prefix_cost\": \"10.25\"}, \"attached_condition\": \"(o.total > 10)\"}}, {\"table\": {\"table_name\": \"u\", \"access_type\": \"eq_ref\", \"key\": \"PRIMARY\", \"rows_examined_per_scan\": 1}}]}}}"}]`
+	nodes, err := ParsePlan(DialectMySQL, output)
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+
+	block := nodes[0]
+	assert.Equal(t, "Query block", block.Operation)
+	assert.Equal(t, 12.4, block.EstimatedCost)
+	require.Len(t, block.Children, 1)
+
+	sort := block.Children[0]
+	assert.Equal(t, "Sort", sort.Operation)
+	assert.Equal(t, "using filesort", sort.Detail)
+	require.Len(t, sort.Children, 1)
+
+	loop := sort.Children[0]
+	assert.Equal(t, "Nested loop", loop.Operation)
+	require.Len(t, loop.Children, 2)
+	assert.Equal(t, &PlanNode{Operation: "Table scan", Relation: "o", EstimatedRows: 100, EstimatedCost: 10.25, Detail: "(o.total > 10)"}, loop.Children[0])
+	assert.Equal(t, &PlanNode{Operation: "Unique index lookup", Relation: "u", Index: "PRIMARY", EstimatedRows: 1}, loop.Children[1])
+}
+
+func TestParsePlan_SQLServer(t *testing.T) {
+	plan := `<ShowPlanXML xmlns="http://schemas.microsoft.com/sqlserver/2004/07/showplan" Version="1.5"><BatchSequence><Batch><Statements><StmtSimple StatementText="SELECT ..."><QueryPlan>` +
+		`<RelOp NodeId="0" PhysicalOp="Nested Loops" LogicalOp="Inner Join" EstimateRows="5" EstimatedTotalSubtreeCost="0.0066"><NestedLoops>` +
+		`<RelOp NodeId="1" PhysicalOp="Clustered Index Scan" LogicalOp="Clustered Index Scan" EstimateRows="5" EstimatedTotalSubtreeCost="0.0033"><IndexScan><Object Database="[shop]" Schema="[dbo]" Table="[orders]" Index="[PK_orders]"/>` +
+		`<Predicate><ScalarOperator ScalarString="[shop].[dbo].[orders].[total]&gt;(10)"/></Predicate></IndexScan></RelOp>` +
+		`<RelOp NodeId="2" PhysicalOp="Index Seek" LogicalOp="Index Seek" EstimateRows="1" EstimatedTotalSubtreeCost="0.0032"><IndexScan><Object Database="[shop]" Schema="[dbo]" Table="[users]" Index="[PK_users]"/></IndexScan></RelOp>` +
+		`</NestedLoops></RelOp></QueryPlan></StmtSimple></Statements></Batch></BatchSequence></ShowPlanXML>`
+
+	for name, output := range map[string]string{
+		"json": `[{"Microsoft SQL Server 2005 XML Showplan": ` + jsonString(plan) + `}]`,
+		"text": "Microsoft SQL Server 2005 XML Showplan\n" + plan + "\n(1 row affected)\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			nodes, err := ParsePlan(DialectSQLServer, output)
+			require.NoError(t, err)
+			require.Len(t, nodes, 1)
+
+			root := nodes[0]
+			assert.Equal(t, "Nested Loops", root.Operation)
+			assert.Empty(t, root.Relation)
+			assert.Equal(t, 0.0066, root.EstimatedCost)
+			require.Len(t, root.Children, 2)
+			assert.Equal(t, &PlanNode{Operation: "Clustered Index Scan", Relation: "dbo.orders", Index: "PK_orders", EstimatedRows: 5, EstimatedCost: 0.0033, Detail: "[shop].[dbo].[orders].[total]>(10)"}, root.Children[0])
+			assert.Equal(t, "dbo.users", root.Children[1].Relation)
+			assert.Empty(t, root.Children[1].Detail)
+		})
+	}
+
+	_, err := ParsePlan(DialectSQLServer, "[]")
+	assert.ErrorContains(t, err, "no ShowPlanXML document")
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
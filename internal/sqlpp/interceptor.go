@@ -0,0 +1,91 @@
+package sqlpp
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// Operation identifies what a sqlpp request does
+type Operation string
+
+const (
+	OpSchema          Operation = "schema"
+	OpSQL             Operation = "sql"
+	OpListConnections Operation = "list_connections"
+	OpListDrivers     Operation = "list_drivers"
+)
+
+// Request describes a single sqlpp run. Interceptors may inspect or modify
+// it before passing it on.
+type Request struct {
+	Operation  Operation
+	Args       []string // command-line arguments passed to sqlpp
+	Input      string   // text sent on stdin when Stdin is set
+	Stdin      bool
+	Connection string // connection name, empty for server-wide operations
+	Output     string // requested output format, empty for the sqlpp default
+}
+
+// Runner runs a sqlpp request. A failed command is reported through the
+// result; the error is reserved for failures to run sqlpp at all.
+type Runner func(ctx context.Context, req *Request) (*types.SqlppResult, error)
+
+// Interceptor wraps a Runner to add behavior around sqlpp runs, such as
+// logging, metrics, redaction, policy checks or caching. An interceptor may
+// return without calling next to short-circuit the run.
+type Interceptor func(next Runner) Runner
+
+// chain wraps core in interceptors, the first being outermost
+func chain(interceptors []Interceptor, core Runner) Runner {
+	runner := core
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		runner = interceptors[i](runner)
+	}
+	return runner
+}
+
+// LoggingInterceptor logs each sqlpp run and its outcome
+func LoggingInterceptor(logger *logrus.Logger) Interceptor {
+	return func(next Runner) Runner {
+		return func(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+			fields := logrus.Fields{
+				"operation":  req.Operation,
+				"args":       req.Args,
+				"connection": req.Connection,
+			}
+			if req.Stdin {
+				fields["input"] = req.Input
+			}
+
+			logger.WithFields(fields).Debug("Executing sqlpp command")
+
+			result, err := next(ctx, req)
+
+			switch {
+			case err != nil:
+				logger.WithFields(fields).WithError(err).Error("sqlpp command could not be run")
+			case !result.Success:
+				logger.WithFields(fields).WithFields(logrus.Fields{
+					"error":      result.Error,
+					"error_code": result.ErrorCode,
+					"exit_code":  result.ExitCode,
+					"stderr":     result.Stderr,
+				}).Error("sqlpp command failed")
+			default:
+				logger.WithFields(fields).WithFields(logrus.Fields{
+					"output_size": result.OutputSize,
+					"duration_ms": result.DurationMs,
+				}).Debug("sqlpp command succeeded")
+
+				// Log truncated output at TRACE level for detailed debugging
+				if logger.Level <= logrus.TraceLevel {
+					logger.WithFields(fields).WithField("output_preview", truncateForLogging(result.Output)).Trace("sqlpp command output preview")
+				}
+			}
+
+			return result, err
+		}
+	}
+}
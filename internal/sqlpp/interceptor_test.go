@@ -0,0 +1,108 @@
+package sqlpp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEchoExecutor creates an executor whose sqlpp echoes its stdin
+func newEchoExecutor(t *testing.T) *Executor {
+	mockSqlpp := filepath.Join(t.TempDir(), "mock-sqlpp")
+	mockScript := `#!/bin/bash
+cat
+`
+	require.NoError(t, os.WriteFile(mockSqlpp, []byte(mockScript), 0755))
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return NewExecutor(mockSqlpp, 30, logger)
+}
+
+func TestExecutor_InterceptorOrder(t *testing.T) {
+	executor := newEchoExecutor(t)
+
+	var calls []string
+	record := func(name string) Interceptor {
+		return func(next Runner) Runner {
+			return func(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+				calls = append(calls, name+":before")
+				result, err := next(ctx, req)
+				calls = append(calls, name+":after")
+				return result, err
+			}
+		}
+	}
+	executor.Use(record("outer"), record("inner"))
+
+	result, err := executor.ExecuteSQLCommand(context.Background(), "main", "SELECT 1", "json")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+
+	assert.Equal(t, []string{"outer:before", "inner:before", "inner:after", "outer:after"}, calls)
+}
+
+func TestExecutor_InterceptorSeesRequest(t *testing.T) {
+	executor := newEchoExecutor(t)
+
+	var seen Request
+	executor.Use(func(next Runner) Runner {
+		return func(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+			seen = *req
+			// Rewrite the input before it reaches sqlpp
+			req.Input = "SELECT 2"
+			return next(ctx, req)
+		}
+	})
+
+	result, err := executor.ExecuteSQLCommand(context.Background(), "main", "SELECT 1", "json")
+	require.NoError(t, err)
+
+	assert.Equal(t, OpSQL, seen.Operation)
+	assert.Equal(t, "main", seen.Connection)
+	assert.Equal(t, "json", seen.Output)
+	assert.Equal(t, "SELECT 1", seen.Input)
+	assert.Equal(t, []string{"--stdin", "--connection", "main", "--output", "json"}, seen.Args)
+	assert.Equal(t, "SELECT 2", result.Output)
+}
+
+func TestExecutor_InterceptorShortCircuit(t *testing.T) {
+	executor := newEchoExecutor(t)
+
+	executor.Use(func(next Runner) Runner {
+		return func(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+			if req.Operation == OpListDrivers {
+				return &types.SqlppResult{Success: true, Output: "cached"}, nil
+			}
+			return next(ctx, req)
+		}
+	})
+
+	result, err := executor.ListDrivers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "cached", result.Output)
+}
+
+func TestLoggingInterceptor(t *testing.T) {
+	executor := newEchoExecutor(t)
+
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+	logger.SetLevel(logrus.DebugLevel)
+	executor.Use(LoggingInterceptor(logger))
+
+	_, err := executor.ExecuteSQLCommand(context.Background(), "main", "SELECT 1", "")
+	require.NoError(t, err)
+
+	assert.Contains(t, logs.String(), "Executing sqlpp command")
+	assert.Contains(t, logs.String(), "sqlpp command succeeded")
+	assert.Contains(t, logs.String(), "operation=sql")
+}
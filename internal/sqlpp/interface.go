@@ -1,12 +1,18 @@
 package sqlpp
 
-import "github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+import (
+	"context"
 
-// ExecutorInterface defines the interface for sqlpp command execution
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// ExecutorInterface defines the interface for sqlpp command execution.
+// Every call takes the caller's context; cancelling it terminates the
+// underlying sqlpp process.
 type ExecutorInterface interface {
-	ExecuteSchemaCommand(schemaType, connection, filter, output string) (*types.SqlppResult, error)
-	ExecuteSQLCommand(connection, command, output string) (*types.SqlppResult, error)
-	ListConnections() (*types.SqlppResult, error)
-	ListDrivers() (*types.SqlppResult, error)
-	ValidateExecutable() error
+	ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error)
+	ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error)
+	ListConnections(ctx context.Context) (*types.SqlppResult, error)
+	ListDrivers(ctx context.Context) (*types.SqlppResult, error)
+	ValidateExecutable(ctx context.Context) error
 }
@@ -0,0 +1,243 @@
+package sqlpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+var (
+	// ErrQueueFull is returned when a call arrives while the wait queue is full
+	ErrQueueFull = errors.New("server busy: sqlpp execution queue is full")
+
+	// ErrQueueTimeout is returned when a call waits too long for an execution slot
+	ErrQueueTimeout = errors.New("server busy: timed out waiting for an sqlpp execution slot")
+)
+
+// LimitOptions configures a LimitedExecutor
+type LimitOptions struct {
+	MaxConcurrent   int                         // concurrent sqlpp calls across all connections (0 = unlimited)
+	ConnectionLimit func(connection string) int // concurrent sqlpp calls for a connection (0 = unlimited)
+	MaxQueue        int                         // calls allowed to wait for a slot (0 = unlimited)
+	QueueTimeout    time.Duration               // how long a call may wait for a slot (0 = no limit)
+}
+
+// LimitedExecutor bounds how many sqlpp calls run at once, globally and per
+// connection. Calls over the limit wait in arrival order; whenever a slot frees
+// up, the earliest waiters that fit within both limits are started.
+type LimitedExecutor struct {
+	next   ExecutorInterface
+	opts   LimitOptions
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	running int
+	perConn map[string]int
+	queue   []*slotWaiter
+}
+
+// slotWaiter is a queued call waiting for an execution slot
+type slotWaiter struct {
+	connection string
+	ready      chan struct{}
+}
+
+// NewLimitedExecutor wraps next with concurrency limits
+func NewLimitedExecutor(next ExecutorInterface, opts LimitOptions, logger *logrus.Logger) *LimitedExecutor {
+	return &LimitedExecutor{
+		next:    next,
+		opts:    opts,
+		logger:  logger,
+		perConn: make(map[string]int),
+	}
+}
+
+// ExecuteSchemaCommand executes a schema command once a slot for connection is free
+func (l *LimitedExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	release, err := l.acquire(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return l.next.ExecuteSchemaCommand(ctx, schemaType, connection, filter, output)
+}
+
+// ExecuteSQLCommand executes a SQL command once a slot for connection is free
+func (l *LimitedExecutor) ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error) {
+	release, err := l.acquire(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return l.next.ExecuteSQLCommand(ctx, connection, command, output)
+}
+
+// ListConnections lists connections once a global slot is free
+func (l *LimitedExecutor) ListConnections(ctx context.Context) (*types.SqlppResult, error) {
+	release, err := l.acquire(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return l.next.ListConnections(ctx)
+}
+
+// ListDrivers lists drivers once a global slot is free
+func (l *LimitedExecutor) ListDrivers(ctx context.Context) (*types.SqlppResult, error) {
+	release, err := l.acquire(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return l.next.ListDrivers(ctx)
+}
+
+// Acquire waits for an execution slot for connection on behalf of work that
+// runs sqlpp outside this executor, such as an open transaction's process,
+// and returns the function that frees it
+func (l *LimitedExecutor) Acquire(ctx context.Context, connection string) (func(), error) {
+	return l.acquire(ctx, connection)
+}
+
+// ValidateExecutable validates the executable without taking a slot
+func (l *LimitedExecutor) ValidateExecutable(ctx context.Context) error {
+	return l.next.ValidateExecutable(ctx)
+}
+
+// Close closes the wrapped executor if it holds resources
+func (l *LimitedExecutor) Close() error {
+	if closer, ok := l.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// acquire waits for an execution slot for connection (empty for calls that
+// are not tied to a connection) and returns the function that releases it
+func (l *LimitedExecutor) acquire(ctx context.Context, connection string) (func(), error) {
+	release := func() { l.release(connection) }
+
+	l.mu.Lock()
+	if l.fits(connection) {
+		l.take(connection)
+		l.mu.Unlock()
+		return release, nil
+	}
+
+	depth := len(l.queue)
+	if l.opts.MaxQueue > 0 && depth >= l.opts.MaxQueue {
+		l.mu.Unlock()
+		l.logger.WithFields(logrus.Fields{
+			"connection":  connection,
+			"queue_depth": depth,
+		}).Warn("sqlpp execution queue full, rejecting call")
+		return nil, fmt.Errorf("%w (%d calls waiting)", ErrQueueFull, depth)
+	}
+
+	w := &slotWaiter{connection: connection, ready: make(chan struct{})}
+	l.queue = append(l.queue, w)
+	l.mu.Unlock()
+
+	l.logger.WithFields(logrus.Fields{
+		"connection":  connection,
+		"queue_depth": depth + 1,
+	}).Debug("Waiting for sqlpp execution slot")
+
+	var timeout <-chan time.Time
+	if l.opts.QueueTimeout > 0 {
+		timer := time.NewTimer(l.opts.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	start := time.Now()
+	select {
+	case <-w.ready:
+		l.logger.WithFields(logrus.Fields{
+			"connection": connection,
+			"wait_time":  time.Since(start),
+		}).Debug("Acquired sqlpp execution slot")
+		return release, nil
+	case <-timeout:
+		if l.abandon(w) {
+			return release, nil
+		}
+		l.logger.WithFields(logrus.Fields{
+			"connection": connection,
+			"wait_time":  time.Since(start),
+		}).Warn("Timed out waiting for sqlpp execution slot")
+		return nil, fmt.Errorf("%w after %s", ErrQueueTimeout, l.opts.QueueTimeout)
+	case <-ctx.Done():
+		if l.abandon(w) {
+			return release, nil
+		}
+		return nil, fmt.Errorf("cancelled while waiting for an sqlpp execution slot: %w", ctx.Err())
+	}
+}
+
+// abandon removes w from the queue. It reports true if w was granted a slot
+// in the meantime, in which case the caller owns that slot.
+func (l *LimitedExecutor) abandon(w *slotWaiter) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, queued := range l.queue {
+		if queued == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return false
+		}
+	}
+	return true
+}
+
+// release frees the slot held for connection and starts eligible waiters
+func (l *LimitedExecutor) release(connection string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.running--
+	if connection != "" {
+		l.perConn[connection]--
+	}
+
+	remaining := l.queue[:0]
+	for _, w := range l.queue {
+		if l.fits(w.connection) {
+			l.take(w.connection)
+			close(w.ready)
+			continue
+		}
+		remaining = append(remaining, w)
+	}
+	l.queue = remaining
+}
+
+// fits reports whether a call for connection can start now. Callers hold l.mu.
+func (l *LimitedExecutor) fits(connection string) bool {
+	if l.opts.MaxConcurrent > 0 && l.running >= l.opts.MaxConcurrent {
+		return false
+	}
+	if connection == "" || l.opts.ConnectionLimit == nil {
+		return true
+	}
+	limit := l.opts.ConnectionLimit(connection)
+	return limit <= 0 || l.perConn[connection] < limit
+}
+
+// take records a running call for connection. Callers hold l.mu.
+func (l *LimitedExecutor) take(connection string) {
+	l.running++
+	if connection != "" {
+		l.perConn[connection]++
+	}
+}
@@ -0,0 +1,178 @@
+package sqlpp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingExecutor records each call as it starts and holds it until released
+type blockingExecutor struct {
+	started chan string
+	release chan struct{}
+}
+
+func newBlockingExecutor() *blockingExecutor {
+	return &blockingExecutor{
+		started: make(chan string, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingExecutor) run(label string) (*types.SqlppResult, error) {
+	b.started <- label
+	<-b.release
+	return &types.SqlppResult{Success: true, Output: label}, nil
+}
+
+func (b *blockingExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	return b.run(connection + ":" + schemaType)
+}
+
+func (b *blockingExecutor) ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error) {
+	return b.run(connection + ":" + command)
+}
+
+func (b *blockingExecutor) ListConnections(ctx context.Context) (*types.SqlppResult, error) {
+	return b.run("connections")
+}
+
+func (b *blockingExecutor) ListDrivers(ctx context.Context) (*types.SqlppResult, error) {
+	return b.run("drivers")
+}
+
+func (b *blockingExecutor) ValidateExecutable(ctx context.Context) error {
+	return nil
+}
+
+var _ ExecutorInterface = (*blockingExecutor)(nil)
+
+func newTestLimiter(next ExecutorInterface, opts LimitOptions) *LimitedExecutor {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return NewLimitedExecutor(next, opts, logger)
+}
+
+// runAsync starts a SQL command in the background and returns its error channel
+func runAsync(l *LimitedExecutor, connection, command string) chan error {
+	errs := make(chan error, 1)
+	go func() {
+		_, err := l.ExecuteSQLCommand(context.Background(), connection, command, "")
+		errs <- err
+	}()
+	return errs
+}
+
+func waitStarted(t *testing.T, b *blockingExecutor) string {
+	select {
+	case label := <-b.started:
+		return label
+	case <-time.After(2 * time.Second):
+		t.Fatal("call did not start")
+		return ""
+	}
+}
+
+func TestLimitedExecutor_QueueFull(t *testing.T) {
+	backend := newBlockingExecutor()
+	limiter := newTestLimiter(backend, LimitOptions{MaxConcurrent: 1, MaxQueue: 1})
+
+	first := runAsync(limiter, "main", "SELECT 1")
+	waitStarted(t, backend)
+
+	second := runAsync(limiter, "main", "SELECT 2")
+	require.Eventually(t, func() bool {
+		limiter.mu.Lock()
+		defer limiter.mu.Unlock()
+		return len(limiter.queue) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	_, err := limiter.ExecuteSQLCommand(context.Background(), "main", "SELECT 3", "")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	close(backend.release)
+	assert.NoError(t, <-first)
+	assert.NoError(t, <-second)
+}
+
+func TestLimitedExecutor_QueueTimeout(t *testing.T) {
+	backend := newBlockingExecutor()
+	limiter := newTestLimiter(backend, LimitOptions{MaxConcurrent: 1, QueueTimeout: 50 * time.Millisecond})
+
+	first := runAsync(limiter, "main", "SELECT 1")
+	waitStarted(t, backend)
+
+	_, err := limiter.ExecuteSQLCommand(context.Background(), "main", "SELECT 2", "")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrQueueTimeout)
+
+	close(backend.release)
+	assert.NoError(t, <-first)
+
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	assert.Empty(t, limiter.queue)
+	assert.Zero(t, limiter.running)
+}
+
+func TestLimitedExecutor_PerConnectionLimit(t *testing.T) {
+	backend := newBlockingExecutor()
+	limiter := newTestLimiter(backend, LimitOptions{
+		ConnectionLimit: func(connection string) int {
+			if connection == "main" {
+				return 1
+			}
+			return 0
+		},
+	})
+
+	first := runAsync(limiter, "main", "SELECT 1")
+	assert.Equal(t, "main:SELECT 1", waitStarted(t, backend))
+
+	// A second call on the limited connection waits, another connection does not
+	second := runAsync(limiter, "main", "SELECT 2")
+	other := runAsync(limiter, "reporting", "SELECT 3")
+	assert.Equal(t, "reporting:SELECT 3", waitStarted(t, backend))
+
+	close(backend.release)
+	assert.Equal(t, "main:SELECT 2", waitStarted(t, backend))
+	assert.NoError(t, <-first)
+	assert.NoError(t, <-second)
+	assert.NoError(t, <-other)
+}
+
+func TestLimitedExecutor_FIFOOrder(t *testing.T) {
+	backend := newBlockingExecutor()
+	limiter := newTestLimiter(backend, LimitOptions{MaxConcurrent: 1})
+
+	var errs []chan error
+	errs = append(errs, runAsync(limiter, "main", "first"))
+	waitStarted(t, backend)
+
+	for i, command := range []string{"second", "third"} {
+		errs = append(errs, runAsync(limiter, "main", command))
+		depth := i + 1
+		require.Eventually(t, func() bool {
+			limiter.mu.Lock()
+			defer limiter.mu.Unlock()
+			return len(limiter.queue) == depth
+		}, 2*time.Second, 10*time.Millisecond)
+	}
+
+	// Release calls one at a time and check they start in arrival order
+	backend.release <- struct{}{}
+	assert.Equal(t, "main:second", waitStarted(t, backend))
+	backend.release <- struct{}{}
+	assert.Equal(t, "main:third", waitStarted(t, backend))
+	backend.release <- struct{}{}
+
+	for _, e := range errs {
+		assert.NoError(t, <-e)
+	}
+}
@@ -0,0 +1,379 @@
+package sqlpp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidParameters is returned when statement parameters are malformed or
+// do not match the statement's placeholders
+var ErrInvalidParameters = errors.New("invalid parameters")
+
+// Dialect is the SQL dialect literals are written in
+type Dialect string
+
+// Supported dialects
+const (
+	DialectPostgres  Dialect = "postgres"
+	DialectMySQL     Dialect = "mysql"
+	DialectSQLite    Dialect = "sqlite"
+	DialectSQLServer Dialect = "sqlserver"
+	DialectOracle    Dialect = "oracle"
+)
+
+// dialectKeywords maps substrings of driver names and descriptions to
+// dialects, checked in order
+var dialectKeywords = []struct {
+	keyword string
+	dialect Dialect
+}{
+	{"postgres", DialectPostgres},
+	{"pgx", DialectPostgres},
+	{"cockroach", DialectPostgres},
+	{"mysql", DialectMySQL},
+	{"mariadb", DialectMySQL},
+	{"sqlite", DialectSQLite},
+	{"sqlserver", DialectSQLServer},
+	{"sql server", DialectSQLServer},
+	{"mssql", DialectSQLServer},
+	{"oracle", DialectOracle},
+	{"godror", DialectOracle},
+	{"oci8", DialectOracle},
+}
+
+// DialectFor returns the dialect of a database driver, recognised by name or
+// failing that by its description
+func DialectFor(driver, description string) (Dialect, bool) {
+	for _, text := range []string{driver, description} {
+		text = strings.ToLower(text)
+		if text == "pq" {
+			return DialectPostgres, true
+		}
+		for _, k := range dialectKeywords {
+			if strings.Contains(text, k.keyword) {
+				return k.dialect, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ParamType is the SQL type a parameter is bound as
+type ParamType string
+
+// Parameter types
+const (
+	ParamString    ParamType = "string"
+	ParamInteger   ParamType = "integer"
+	ParamNumber    ParamType = "number"
+	ParamBoolean   ParamType = "boolean"
+	ParamNull      ParamType = "null"
+	ParamDate      ParamType = "date"
+	ParamTimestamp ParamType = "timestamp"
+	ParamBinary    ParamType = "binary" // value is base64 encoded
+)
+
+// ParamTypes lists the parameter types in the order they are documented
+var ParamTypes = []ParamType{ParamString, ParamInteger, ParamNumber, ParamBoolean, ParamNull, ParamDate, ParamTimestamp, ParamBinary}
+
+// Parameter is a value bound into a statement. Value holds a decoded JSON
+// value: string, float64, bool or nil.
+type Parameter struct {
+	Type  ParamType
+	Value interface{}
+}
+
+// Parameters are bound either by position to ? placeholders or by name to
+// :name placeholders
+type Parameters struct {
+	Positional []Parameter
+	Named      map[string]Parameter
+}
+
+var (
+	numberLiteral   = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+	integerLiteral  = regexp.MustCompile(`^-?[0-9]+$`)
+	timestampLayout = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999"}
+)
+
+// BindParameters replaces the placeholders in sql with params written as
+// dialect literals. Placeholders inside strings, quoted identifiers and
+// comments are left alone, as are :: casts. Positional parameters must match
+// the ? placeholders one to one, and named parameters the :name placeholders.
+func BindParameters(sql string, dialect Dialect, params Parameters) (string, error) {
+	if len(params.Positional) > 0 && len(params.Named) > 0 {
+		return "", fmt.Errorf("%w: use either positional or named parameters, not both", ErrInvalidParameters)
+	}
+	named := len(params.Named) > 0
+
+	var b strings.Builder
+	prev := 0
+	position := 0
+	used := make(map[string]bool)
+
+	tokens := lexSQL(sql)
+	for i, tok := range tokens {
+		var param Parameter
+		var label string
+		end := tok.end
+
+		switch {
+		case !named && tok.kind == tokenSymbol && tok.text == "?":
+			if position >= len(params.Positional) {
+				return "", fmt.Errorf("%w: statement has more ? placeholders than the %d parameters given", ErrInvalidParameters, len(params.Positional))
+			}
+			param = params.Positional[position]
+			label = strconv.Itoa(position + 1)
+			position++
+		case named && isNamedPlaceholder(tokens, i):
+			name := tokens[i+1].text
+			var ok bool
+			param, ok = params.Named[name]
+			if !ok {
+				return "", fmt.Errorf("%w: no value for placeholder :%s", ErrInvalidParameters, name)
+			}
+			label = name
+			used[name] = true
+			end = tokens[i+1].end
+		default:
+			continue
+		}
+
+		literal, err := Literal(dialect, param)
+		if err != nil {
+			return "", fmt.Errorf("%w: parameter %s: %v", ErrInvalidParameters, label, err)
+		}
+		b.WriteString(sql[prev:tok.start])
+		b.WriteString(literal)
+		prev = end
+	}
+	b.WriteString(sql[prev:])
+
+	if !named && position < len(params.Positional) {
+		return "", fmt.Errorf("%w: %d parameters given but the statement has %d ? placeholders", ErrInvalidParameters, len(params.Positional), position)
+	}
+	var unused []string
+	for name := range params.Named {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return "", fmt.Errorf("%w: no placeholder for parameters %s", ErrInvalidParameters, strings.Join(unused, ", "))
+	}
+
+	return b.String(), nil
+}
+
+// isNamedPlaceholder reports whether tokens[i] starts a :name placeholder: a
+// colon directly followed by a name, and not the second colon of a :: cast
+func isNamedPlaceholder(tokens []sqlToken, i int) bool {
+	tok := tokens[i]
+	if tok.kind != tokenSymbol || tok.text != ":" || i+1 >= len(tokens) {
+		return false
+	}
+	if i > 0 && tokens[i-1].text == ":" && tokens[i-1].end == tok.start {
+		return false
+	}
+	next := tokens[i+1]
+	return next.kind == tokenWord && next.start == tok.end && variableName.MatchString(next.text)
+}
+
+// Literal writes param as a SQL literal in dialect, checking that its value
+// fits its type
+func Literal(dialect Dialect, param Parameter) (string, error) {
+	switch param.Type {
+	case ParamNull:
+		if param.Value != nil {
+			return "", fmt.Errorf("null parameter has a value")
+		}
+		return "NULL", nil
+	case ParamString:
+		s, ok := param.Value.(string)
+		if !ok {
+			return "", fmt.Errorf("expected a string, got %s", jsonType(param.Value))
+		}
+		return quoteString(dialect, s)
+	case ParamInteger:
+		return integerLiteralFor(param.Value)
+	case ParamNumber:
+		return numberLiteralFor(param.Value)
+	case ParamBoolean:
+		v, ok := param.Value.(bool)
+		if !ok {
+			return "", fmt.Errorf("expected a boolean, got %s", jsonType(param.Value))
+		}
+		return booleanLiteral(dialect, v), nil
+	case ParamDate:
+		s, ok := param.Value.(string)
+		if !ok {
+			return "", fmt.Errorf("expected a date string, got %s", jsonType(param.Value))
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return "", fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", s)
+		}
+		return typedLiteral(dialect, "DATE", s), nil
+	case ParamTimestamp:
+		s, ok := param.Value.(string)
+		if !ok {
+			return "", fmt.Errorf("expected a timestamp string, got %s", jsonType(param.Value))
+		}
+		ts, err := parseTimestamp(s)
+		if err != nil {
+			return "", err
+		}
+		return typedLiteral(dialect, "TIMESTAMP", ts), nil
+	case ParamBinary:
+		s, ok := param.Value.(string)
+		if !ok {
+			return "", fmt.Errorf("expected a base64 string, got %s", jsonType(param.Value))
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid base64 value: %v", err)
+		}
+		return binaryLiteral(dialect, data), nil
+	default:
+		return "", fmt.Errorf("unknown type %q", param.Type)
+	}
+}
+
+// quoteString quotes s as a string literal. Line breaks are rejected: sqlpp
+// reads its input line by line, so a bound value could otherwise start a GO
+// separator, a # directive or the worker delimiter on a line of its own.
+func quoteString(dialect Dialect, s string) (string, error) {
+	if strings.ContainsRune(s, 0) {
+		return "", fmt.Errorf("strings must not contain NUL characters")
+	}
+	if strings.ContainsAny(s, "\r\n") {
+		return "", fmt.Errorf("strings must not contain line breaks")
+	}
+
+	// Backslash is an escape character in MySQL strings by default
+	if dialect == DialectMySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	escaped := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	if dialect == DialectSQLServer {
+		return "N" + escaped, nil
+	}
+	return escaped, nil
+}
+
+func integerLiteralFor(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return "", fmt.Errorf("expected a whole number, got %v", v)
+		}
+		return strconv.FormatInt(int64(v), 10), nil
+	case string:
+		// Strings carry integers too large for a JSON number
+		if !integerLiteral.MatchString(v) {
+			return "", fmt.Errorf("invalid integer %q", v)
+		}
+		return v, nil
+	default:
+		return "", fmt.Errorf("expected an integer, got %s", jsonType(value))
+	}
+}
+
+func numberLiteralFor(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("number must be finite")
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case string:
+		// Strings keep decimals exact
+		if !numberLiteral.MatchString(v) {
+			return "", fmt.Errorf("invalid number %q", v)
+		}
+		return v, nil
+	default:
+		return "", fmt.Errorf("expected a number, got %s", jsonType(value))
+	}
+}
+
+func booleanLiteral(dialect Dialect, v bool) string {
+	switch dialect {
+	case DialectPostgres, DialectMySQL:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+}
+
+// typedLiteral writes a date or timestamp literal. Dialects without typed
+// literals take the plain string and convert it implicitly.
+func typedLiteral(dialect Dialect, keyword, value string) string {
+	switch dialect {
+	case DialectPostgres, DialectMySQL, DialectOracle:
+		return keyword + " '" + value + "'"
+	default:
+		return "'" + value + "'"
+	}
+}
+
+func binaryLiteral(dialect Dialect, data []byte) string {
+	h := strings.ToUpper(hex.EncodeToString(data))
+	switch dialect {
+	case DialectPostgres:
+		return `'\x` + h + `'::bytea`
+	case DialectSQLServer:
+		return "0x" + h
+	case DialectOracle:
+		return "HEXTORAW('" + h + "')"
+	default:
+		return "X'" + h + "'"
+	}
+}
+
+// parseTimestamp checks s and normalises it to "YYYY-MM-DD HH:MM:SS[.fff]",
+// converting timestamps with a UTC offset to UTC
+func parseTimestamp(s string) (string, error) {
+	for _, layout := range timestampLayout {
+		ts, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		return ts.UTC().Format("2006-01-02 15:04:05.999999999"), nil
+	}
+	return "", fmt.Errorf("invalid timestamp %q (expected RFC 3339 or YYYY-MM-DD HH:MM:SS)", s)
+}
+
+// jsonType names the JSON type of a decoded value for error messages
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
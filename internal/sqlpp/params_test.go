@@ -0,0 +1,117 @@
+package sqlpp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindParameters_Positional(t *testing.T) {
+	sql := "SELECT * FROM users WHERE name = ? AND note <> '?' -- is this ?\nAND id = ? AND tags::text LIKE ?"
+	bound, err := BindParameters(sql, DialectPostgres, Parameters{Positional: []Parameter{
+		{Type: ParamString, Value: "O'Brien"},
+		{Type: ParamInteger, Value: float64(42)},
+		{Type: ParamNull},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE name = 'O''Brien' AND note <> '?' -- is this ?\nAND id = 42 AND tags::text LIKE NULL", bound)
+
+	_, err = BindParameters("SELECT ?, ?", DialectPostgres, Parameters{Positional: []Parameter{{Type: ParamNull}}})
+	assert.ErrorIs(t, err, ErrInvalidParameters)
+	assert.ErrorContains(t, err, "more ? placeholders than the 1 parameters given")
+
+	_, err = BindParameters("SELECT ?", DialectPostgres, Parameters{Positional: []Parameter{{Type: ParamNull}, {Type: ParamNull}}})
+	assert.ErrorContains(t, err, "2 parameters given but the statement has 1 ? placeholders")
+}
+
+func TestBindParameters_Named(t *testing.T) {
+	sql := "UPDATE t SET a = :a, b = :b::int WHERE c = ':a' AND d = :a"
+	bound, err := BindParameters(sql, DialectMySQL, Parameters{Named: map[string]Parameter{
+		"a": {Type: ParamString, Value: `C:\temp`},
+		"b": {Type: ParamBoolean, Value: true},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, `UPDATE t SET a = 'C:\\temp', b = TRUE::int WHERE c = ':a' AND d = 'C:\\temp'`, bound)
+
+	_, err = BindParameters("SELECT :a, :missing", DialectMySQL, Parameters{Named: map[string]Parameter{"a": {Type: ParamNull}}})
+	assert.ErrorContains(t, err, "no value for placeholder :missing")
+
+	_, err = BindParameters("SELECT :a", DialectMySQL, Parameters{Named: map[string]Parameter{"a": {Type: ParamNull}, "b": {Type: ParamNull}, "c": {Type: ParamNull}}})
+	assert.ErrorContains(t, err, "no placeholder for parameters b, c")
+
+	_, err = BindParameters("SELECT :a", DialectMySQL, Parameters{Named: map[string]Parameter{"a": {Type: ParamInteger, Value: 1.5}}})
+	assert.ErrorContains(t, err, "parameter a: expected a whole number, got 1.5")
+
+	_, err = BindParameters("SELECT :a", DialectMySQL, Parameters{Named: map[string]Parameter{"a": {Type: ParamString, Value: "x'\nGO\nDROP TABLE t\nGO\n"}}})
+	assert.ErrorContains(t, err, "parameter a: strings must not contain line breaks")
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		param    Parameter
+		expected string
+	}{
+		{DialectSQLServer, Parameter{Type: ParamString, Value: "café's"}, "N'café''s'"},
+		{DialectPostgres, Parameter{Type: ParamString, Value: `a\b`}, `'a\b'`},
+		{DialectSQLite, Parameter{Type: ParamBoolean, Value: false}, "0"},
+		{DialectPostgres, Parameter{Type: ParamNumber, Value: 2.5}, "2.5"},
+		{DialectOracle, Parameter{Type: ParamNumber, Value: "12345678901234567890.01"}, "12345678901234567890.01"},
+		{DialectPostgres, Parameter{Type: ParamInteger, Value: "9223372036854775807"}, "9223372036854775807"},
+		{DialectPostgres, Parameter{Type: ParamDate, Value: "2024-02-29"}, "DATE '2024-02-29'"},
+		{DialectSQLite, Parameter{Type: ParamDate, Value: "2024-02-29"}, "'2024-02-29'"},
+		{DialectMySQL, Parameter{Type: ParamTimestamp, Value: "2024-02-29T10:30:00+02:00"}, "TIMESTAMP '2024-02-29 08:30:00'"},
+		{DialectSQLServer, Parameter{Type: ParamTimestamp, Value: "2024-02-29 10:30:00.5"}, "'2024-02-29 10:30:00.5'"},
+		{DialectPostgres, Parameter{Type: ParamBinary, Value: "3q2+7w=="}, `'\xDEADBEEF'::bytea`},
+		{DialectSQLite, Parameter{Type: ParamBinary, Value: "3q2+7w=="}, "X'DEADBEEF'"},
+		{DialectSQLServer, Parameter{Type: ParamBinary, Value: "3q2+7w=="}, "0xDEADBEEF"},
+	}
+
+	for _, tt := range tests {
+		literal, err := Literal(tt.dialect, tt.param)
+		require.NoError(t, err, tt.expected)
+		assert.Equal(t, tt.expected, literal)
+	}
+
+	invalid := []Parameter{
+		{Type: ParamString, Value: float64(1)},
+		{Type: ParamString, Value: "a\x00b"},
+		{Type: ParamString, Value: "a\nGO\nDROP TABLE users"},
+		{Type: ParamString, Value: "a\rb"},
+		{Type: ParamInteger, Value: "1; DROP TABLE users"},
+		{Type: ParamNumber, Value: "1e"},
+		{Type: ParamDate, Value: "2024-02-30"},
+		{Type: ParamTimestamp, Value: "yesterday"},
+		{Type: ParamBinary, Value: "not base64!"},
+		{Type: ParamNull, Value: "x"},
+		{Type: "uuid", Value: "x"},
+	}
+	for _, param := range invalid {
+		_, err := Literal(DialectPostgres, param)
+		assert.Error(t, err, "%v", param)
+	}
+}
+
+func TestDialectFor(t *testing.T) {
+	tests := []struct {
+		driver, description string
+		expected            Dialect
+	}{
+		{"postgres", "", DialectPostgres},
+		{"pq", "", DialectPostgres},
+		{"mysql", "", DialectMySQL},
+		{"sqlite3", "", DialectSQLite},
+		{"mssql", "", DialectSQLServer},
+		{"godror", "", DialectOracle},
+		{"custom", "Microsoft SQL Server driver", DialectSQLServer},
+	}
+	for _, tt := range tests {
+		dialect, ok := DialectFor(tt.driver, tt.description)
+		assert.True(t, ok, tt.driver)
+		assert.Equal(t, tt.expected, dialect, tt.driver)
+	}
+
+	_, ok := DialectFor("snowflake", "")
+	assert.False(t, ok)
+}
@@ -0,0 +1,552 @@
+package sqlpp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// healthCheckTimeout bounds how long an idle worker may take to answer a
+// probe, including the wait for an execution slot
+const healthCheckTimeout = 3 * time.Second
+
+// errWorkerUnavailable is returned when a statement could not be handed to a
+// worker at all, so it is safe to run it elsewhere
+var errWorkerUnavailable = errors.New("sqlpp worker unavailable")
+
+// PoolOptions configures a PooledExecutor
+type PoolOptions struct {
+	MaxWorkers          int           // live workers per session, connection and output format
+	MaxUses             int           // statements a worker runs before it is recycled
+	HealthCheckInterval time.Duration // how often idle workers are probed
+	HealthCheckQuery    string        // statement used to probe idle workers
+	Delimiter           string        // batch delimiter line understood by sqlpp
+}
+
+// workerKey identifies the workers that can serve a call. Workers belong to
+// one MCP session, so state a statement leaves behind, such as USE or a
+// temporary table, never reaches another session.
+type workerKey struct {
+	session    string
+	connection string
+	output     string
+}
+
+// PooledExecutor keeps long-lived sqlpp processes per connection so repeated
+// calls skip process startup and connection setup. Workers are driven with a
+// delimiter protocol: each statement is followed by a delimiter line, and sqlpp
+// answers with the statement output followed by "<delimiter> <status>". Only
+// sqlpp builds whose --help lists --delimiter speak it, so callers check
+// Capabilities before using a pool.
+//
+// Calls the pool cannot serve (progress streaming, exhausted capacity, broken
+// workers) fall through to the wrapped Executor. Calls reach the pool through
+// the concurrency limiter, so a checked-out worker runs on its caller's slot;
+// health probes, which have no caller, take a slot of their own.
+type PooledExecutor struct {
+	*Executor
+	opts  PoolOptions
+	slots SlotFunc // nil = probes are not limited
+
+	mu     sync.Mutex
+	idle   map[workerKey][]*worker
+	live   map[workerKey]int
+	ended  map[string]bool // ended sessions that still have busy workers
+	closed bool
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// NewPooledExecutor creates a pooled executor on top of executor and starts
+// the idle worker health check
+func NewPooledExecutor(executor *Executor, opts PoolOptions) *PooledExecutor {
+	p := &PooledExecutor{
+		Executor: executor,
+		opts:     opts,
+		idle:     make(map[workerKey][]*worker),
+		live:     make(map[workerKey]int),
+		ended:    make(map[string]bool),
+		stop:     make(chan struct{}),
+	}
+
+	if opts.HealthCheckInterval > 0 {
+		p.stopped.Add(1)
+		go p.healthCheckLoop()
+	}
+
+	return p
+}
+
+// SetSlots makes health probes wait for an execution slot from slots, so
+// they count against the same concurrency limits as calls
+func (p *PooledExecutor) SetSlots(slots SlotFunc) {
+	p.slots = slots
+}
+
+// ExecuteSchemaCommand executes a schema-related command (@schema-*) on a pooled worker
+func (p *PooledExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	return p.invoke(ctx, stdinRequest(OpSchema, schemaCommand(schemaType, filter), connection, output), p.runPooled)
+}
+
+// ExecuteSQLCommand executes a SQL command on a pooled worker
+func (p *PooledExecutor) ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error) {
+	return p.invoke(ctx, stdinRequest(OpSQL, command, connection, output), p.runPooled)
+}
+
+// Close stops the health check and terminates all idle workers. Workers that
+// are busy are terminated when their current statement completes.
+func (p *PooledExecutor) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	var workers []*worker
+	for key, idle := range p.idle {
+		workers = append(workers, idle...)
+		p.live[key] -= len(idle)
+		delete(p.idle, key)
+	}
+	p.mu.Unlock()
+
+	close(p.stop)
+	p.stopped.Wait()
+
+	for _, w := range workers {
+		w.close()
+	}
+
+	p.logger.WithField("workers", len(workers)).Debug("sqlpp worker pool closed")
+	return nil
+}
+
+// EndSession stops the idle workers of session, e.g. when the client
+// disconnects. Workers it is still using are stopped once they finish.
+func (p *PooledExecutor) EndSession(session string) {
+	p.mu.Lock()
+	var workers []*worker
+	for key, idle := range p.idle {
+		if key.session == session {
+			workers = append(workers, idle...)
+			p.live[key] -= len(idle)
+			delete(p.idle, key)
+		}
+	}
+	if p.sessionLive(session) > 0 {
+		p.ended[session] = true
+	}
+	p.mu.Unlock()
+
+	for _, w := range workers {
+		w.close()
+	}
+}
+
+// runPooled is the core runner for pooled calls: it runs the request on a
+// worker, falling back to a one-off process when no worker can take it
+func (p *PooledExecutor) runPooled(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+	// Streaming needs direct access to the process output
+	if progressFrom(ctx) != nil {
+		return p.run(ctx, req)
+	}
+
+	key := workerKey{session: SessionIDFrom(ctx), connection: req.Connection, output: req.Output}
+	w := p.acquire(key)
+	if w == nil {
+		return p.run(ctx, req)
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"connection": key.connection,
+		"output":     key.output,
+		"pid":        w.pid(),
+		"uses":       w.uses,
+	}).Debug("Running sqlpp command on pooled worker")
+
+	result, err := p.runOnWorker(ctx, req, w, p.opts.Delimiter)
+	p.release(w, err == nil && result.Success)
+
+	if errors.Is(err, errWorkerUnavailable) {
+		p.logger.WithError(err).Warn("sqlpp worker unavailable, running command in a new process")
+		return p.run(ctx, req)
+	}
+	return result, err
+}
+
+// runOnWorker runs req on w with the call's timeout, tracking it as a
+// running query and capturing its output like a one-off process
+func (e *Executor) runOnWorker(ctx context.Context, req *Request, w *worker, delimiter string) (*types.SqlppResult, error) {
+	ctx, cancel := e.withTimeout(ctx, req.Connection)
+	defer cancel()
+	ctx, cancelQuery := context.WithCancelCause(ctx)
+	defer cancelQuery(nil)
+
+	untrack := e.queries.track(ctx, req, w.pid(), true, cancelQuery)
+	defer untrack()
+
+	w.uses++
+	stdout := e.newOutputBuffer(ctx)
+	result, err := w.run(ctx, req.Input, delimiter, stdout)
+	if err != nil {
+		stdout.discard()
+		return nil, err
+	}
+	e.captureOutput(result, stdout)
+
+	return result, nil
+}
+
+// acquire returns an idle worker for key, starting one if the pool has
+// capacity. It returns nil when the caller should not use the pool.
+func (p *PooledExecutor) acquire(key workerKey) *worker {
+	p.mu.Lock()
+	if p.closed || p.ended[key.session] {
+		p.mu.Unlock()
+		return nil
+	}
+	if idle := p.idle[key]; len(idle) > 0 {
+		w := idle[len(idle)-1]
+		p.idle[key] = idle[:len(idle)-1]
+		p.mu.Unlock()
+		return w
+	}
+	if p.live[key] >= p.opts.MaxWorkers {
+		p.mu.Unlock()
+		return nil
+	}
+	p.live[key]++
+	p.mu.Unlock()
+
+	w, err := p.startWorker(key, p.opts.Delimiter)
+	if err != nil {
+		p.mu.Lock()
+		p.live[key]--
+		p.mu.Unlock()
+
+		p.logger.WithError(err).WithField("connection", key.connection).Warn("Failed to start sqlpp worker")
+		return nil
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"connection": key.connection,
+		"output":     key.output,
+		"pid":        w.pid(),
+	}).Debug("Started sqlpp worker")
+
+	return w
+}
+
+// release returns a worker to the idle set, or recycles it when it failed,
+// reached its use limit, or its session or the pool is closing
+func (p *PooledExecutor) release(w *worker, healthy bool) {
+	p.mu.Lock()
+	recycle := !healthy || p.closed || p.ended[w.key.session] || w.uses >= p.opts.MaxUses
+	if recycle {
+		p.live[w.key]--
+		if p.ended[w.key.session] && p.sessionLive(w.key.session) == 0 {
+			delete(p.ended, w.key.session)
+		}
+	} else {
+		p.idle[w.key] = append(p.idle[w.key], w)
+	}
+	p.mu.Unlock()
+
+	if recycle {
+		w.close()
+		p.logger.WithFields(logrus.Fields{
+			"connection": w.key.connection,
+			"pid":        w.pid(),
+			"uses":       w.uses,
+			"healthy":    healthy,
+		}).Debug("Recycled sqlpp worker")
+	}
+}
+
+// sessionLive returns how many workers session has; p.mu must be held
+func (p *PooledExecutor) sessionLive(session string) int {
+	n := 0
+	for key, live := range p.live {
+		if key.session == session {
+			n += live
+		}
+	}
+	return n
+}
+
+// startWorker starts a long-lived sqlpp process for key that reads
+// statements separated by delimiter lines
+func (e *Executor) startWorker(key workerKey, delimiter string) (*worker, error) {
+	args := append([]string{"--stdin", "--delimiter", delimiter}, connectionArgs(key.connection, key.output)...)
+
+	cmd := e.command(context.Background(), key.connection, args)
+	cmd.WaitDelay = processWaitDelay
+	setProcessGroup(cmd)
+
+	w := &worker{key: key, cmd: cmd, grace: e.cancelGrace}
+	cmd.Stderr = &w.stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start worker: %w", err)
+	}
+
+	w.stdin = stdin
+	w.stdout = bufio.NewReader(stdout)
+	return w, nil
+}
+
+// healthCheckLoop periodically probes idle workers until the pool is closed
+func (p *PooledExecutor) healthCheckLoop() {
+	defer p.stopped.Done()
+
+	ticker := time.NewTicker(p.opts.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkIdleWorkers()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// checkIdleWorkers probes every idle worker at once, recycling those that
+// fail or do not answer in time
+func (p *PooledExecutor) checkIdleWorkers() {
+	// Take the idle workers out of the pool so no call picks them up mid-probe
+	p.mu.Lock()
+	var workers []*worker
+	for key, idle := range p.idle {
+		workers = append(workers, idle...)
+		delete(p.idle, key)
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.release(w, p.probe(w))
+		}()
+	}
+	wg.Wait()
+}
+
+// probe runs the health check query on w. A worker whose probe cannot get an
+// execution slot in time is left alone until the next check.
+func (p *PooledExecutor) probe(w *worker) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	if p.slots != nil {
+		release, err := p.slots(ctx, w.key.connection)
+		if err != nil {
+			return true
+		}
+		defer release()
+	}
+
+	result, err := w.run(ctx, p.opts.HealthCheckQuery, p.opts.Delimiter, io.Discard)
+	healthy := err == nil && result.Success
+	if !healthy {
+		p.logger.WithFields(logrus.Fields{
+			"connection": w.key.connection,
+			"pid":        w.pid(),
+		}).Warn("sqlpp worker failed health check")
+	}
+	return healthy
+}
+
+// worker is a long-lived sqlpp process serving one connection and output format
+type worker struct {
+	key    workerKey
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr lockedBuffer
+	uses   int
+	grace  time.Duration
+
+	closeOnce sync.Once
+}
+
+func (w *worker) pid() int {
+	if w.cmd.Process == nil {
+		return 0
+	}
+	return w.cmd.Process.Pid
+}
+
+// run sends one statement to the worker and copies its output up to the
+// delimiter into stdout. The returned result carries the status only.
+func (w *worker) run(ctx context.Context, input, delimiter string, stdout io.Writer) (*types.SqlppResult, error) {
+	start := time.Now()
+	args := w.cmd.Args[1:]
+
+	// Only this statement's diagnostics belong in its result
+	w.stderr.Reset()
+
+	if _, err := io.WriteString(w.stdin, input+"\n"+delimiter+"\n"); err != nil {
+		return nil, fmt.Errorf("%w: %v", errWorkerUnavailable, err)
+	}
+
+	type batch struct {
+		status int
+		err    error
+	}
+	done := make(chan batch, 1)
+	go func() {
+		status, err := w.readBatch(delimiter, stdout)
+		done <- batch{status: status, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// The statement cannot be interrupted in place, so the worker goes:
+		// SIGINT to its process group first, SIGKILL after the grace period
+		_ = interruptGroup(w.cmd.Process)
+		select {
+		case <-done:
+		case <-time.After(w.grace):
+			_ = killGroup(w.cmd.Process)
+			<-done
+		}
+		w.close()
+		result := &types.SqlppResult{
+			Success:    false,
+			Error:      abortReason(ctx),
+			ExitCode:   -1,
+			DurationMs: time.Since(start).Milliseconds(),
+			Args:       args,
+		}
+		result.ErrorCode = string(ClassifyResult(ctx, result))
+		return result, nil
+	case b := <-done:
+		if b.err != nil {
+			w.close()
+			return nil, fmt.Errorf("sqlpp worker failed: %w", b.err)
+		}
+
+		result := &types.SqlppResult{
+			Success:    b.status == 0,
+			ExitCode:   b.status,
+			DurationMs: time.Since(start).Milliseconds(),
+			Args:       args,
+		}
+		if result.Success {
+			// Warnings written before the delimiter; the worker stays up, so
+			// stderr that is still in flight shows up in no result
+			result.Stderr = strings.TrimSpace(w.stderr.String())
+		} else {
+			// Failed workers are recycled; closing first flushes their stderr
+			w.close()
+			result.Stderr = strings.TrimSpace(w.stderr.String())
+			result.Error = result.Stderr
+			if result.Error == "" {
+				result.Error = fmt.Sprintf("sqlpp batch failed with status %d", b.status)
+			}
+			result.ErrorCode = string(ClassifyResult(ctx, result))
+		}
+		return result, nil
+	}
+}
+
+// readBatch copies worker output up to the delimiter line into dst and
+// returns the status sqlpp reported after the delimiter
+func (w *worker) readBatch(delimiter string, dst io.Writer) (int, error) {
+	for {
+		line, err := w.stdout.ReadString('\n')
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		if trimmed == delimiter || strings.HasPrefix(trimmed, delimiter+" ") {
+			status := 0
+			if rest := strings.TrimSpace(strings.TrimPrefix(trimmed, delimiter)); rest != "" {
+				parsed, perr := strconv.Atoi(rest)
+				if perr != nil {
+					return 0, fmt.Errorf("invalid batch status %q", rest)
+				}
+				status = parsed
+			}
+			return status, nil
+		}
+
+		if _, werr := io.WriteString(dst, line); werr != nil {
+			return 0, werr
+		}
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// close ends the worker, giving it a chance to exit cleanly on end of input
+func (w *worker) close() {
+	w.closeOnce.Do(func() {
+		w.stdin.Close()
+
+		done := make(chan struct{})
+		go func() {
+			_ = w.cmd.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(processWaitDelay):
+			_ = killGroup(w.cmd.Process)
+			<-done
+		}
+	})
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes and reads
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// Reset discards everything written so far
+func (b *lockedBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
@@ -0,0 +1,221 @@
+package sqlpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDelimiter = "--@@end@@"
+
+// writePoolMock creates a mock sqlpp that speaks the worker delimiter protocol.
+// Each batch echoes the worker pid and input; input containing FAIL fails,
+// input containing WARN writes a warning to stderr, and input containing SLOW
+// takes a second.
+func writePoolMock(t *testing.T) string {
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+delim=""
+while [[ $# -gt 0 ]]; do
+	if [[ "$1" == "--delimiter" ]]; then delim="$2"; shift; fi
+	shift
+done
+if [[ -z "$delim" ]]; then
+	input=$(cat)
+	echo "oneshot: $input"
+	exit 0
+fi
+batch=""
+while IFS= read -r line; do
+	if [[ "$line" == "$delim" ]]; then
+		if [[ "$batch" == *SLOW* ]]; then
+			sleep 1
+		fi
+		if [[ "$batch" == *WARN* ]]; then
+			echo "Warning: $batch" >&2
+		fi
+		if [[ "$batch" == *FAIL* ]]; then
+			echo "Error: statement failed" >&2
+			echo "$delim 1"
+		else
+			echo "pid $$: $batch"
+			echo "$delim 0"
+		fi
+		batch=""
+	else
+		batch="$batch$line"
+	fi
+done
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+	return mockSqlpp
+}
+
+func newTestPool(t *testing.T, opts PoolOptions) *PooledExecutor {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	opts.Delimiter = testDelimiter
+	pool := NewPooledExecutor(NewExecutor(writePoolMock(t), 30, logger), opts)
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func TestPooledExecutor_ReusesWorker(t *testing.T) {
+	pool := newTestPool(t, PoolOptions{MaxWorkers: 1, MaxUses: 10})
+
+	first, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+	require.True(t, first.Success)
+
+	second, err := pool.ExecuteSchemaCommand(context.Background(), "tables", "test-conn", "", "json")
+	require.NoError(t, err)
+	require.True(t, second.Success)
+
+	assert.Contains(t, first.Output, "SELECT 1")
+	assert.Contains(t, second.Output, "@schema-tables")
+	assert.Equal(t, pidOf(first.Output), pidOf(second.Output))
+}
+
+func TestPooledExecutor_RecyclesAfterMaxUses(t *testing.T) {
+	pool := newTestPool(t, PoolOptions{MaxWorkers: 1, MaxUses: 2})
+
+	var pids []string
+	for i := 0; i < 3; i++ {
+		result, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "SELECT 1", "json")
+		require.NoError(t, err)
+		require.True(t, result.Success)
+		pids = append(pids, pidOf(result.Output))
+	}
+
+	assert.Equal(t, pids[0], pids[1])
+	assert.NotEqual(t, pids[1], pids[2])
+}
+
+func TestPooledExecutor_RecyclesOnError(t *testing.T) {
+	pool := newTestPool(t, PoolOptions{MaxWorkers: 1, MaxUses: 10})
+
+	first, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+
+	failed, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "FAIL", "json")
+	require.NoError(t, err)
+	assert.False(t, failed.Success)
+	assert.Contains(t, failed.Error, "statement failed")
+
+	next, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+	assert.NotEqual(t, pidOf(first.Output), pidOf(next.Output))
+}
+
+func TestPooledExecutor_StderrPerStatement(t *testing.T) {
+	pool := newTestPool(t, PoolOptions{MaxWorkers: 1, MaxUses: 10})
+
+	warned, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "SELECT 1 -- WARN", "json")
+	require.NoError(t, err)
+	assert.True(t, warned.Success)
+	assert.Eventually(t, func() bool {
+		return strings.Contains(pool.idle[workerKey{connection: "test-conn", output: "json"}][0].stderr.String(), "Warning")
+	},
+		time.Second, 10*time.Millisecond)
+
+	failed, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "FAIL", "json")
+	require.NoError(t, err)
+	assert.Equal(t, "Error: statement failed", failed.Stderr, "earlier statements' stderr is not carried over")
+}
+
+func TestPooledExecutor_HealthCheckRecyclesFailingWorkers(t *testing.T) {
+	pool := newTestPool(t, PoolOptions{MaxWorkers: 1, MaxUses: 10, HealthCheckQuery: "FAIL"})
+
+	first, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+
+	pool.checkIdleWorkers()
+
+	next, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+	assert.NotEqual(t, pidOf(first.Output), pidOf(next.Output))
+}
+
+func TestPooledExecutor_HealthCheckProbesConcurrently(t *testing.T) {
+	pool := newTestPool(t, PoolOptions{MaxWorkers: 1, MaxUses: 10, HealthCheckQuery: "SELECT 1 -- SLOW"})
+
+	for _, session := range []string{"session-1", "session-2", "session-3"} {
+		_, err := pool.ExecuteSQLCommand(WithSessionID(context.Background(), session), "test-conn", "SELECT 1", "json")
+		require.NoError(t, err)
+	}
+
+	start := time.Now()
+	pool.checkIdleWorkers()
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Len(t, pool.idle, 3)
+}
+
+func TestPooledExecutor_HealthCheckWaitsForSlot(t *testing.T) {
+	pool := newTestPool(t, PoolOptions{MaxWorkers: 1, MaxUses: 10, HealthCheckQuery: "FAIL"})
+	limiter := NewLimitedExecutor(nil, LimitOptions{MaxConcurrent: 1, QueueTimeout: 50 * time.Millisecond}, pool.logger)
+	pool.SetSlots(limiter.Acquire)
+	ctx := context.Background()
+
+	first, err := pool.ExecuteSQLCommand(ctx, "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+
+	// A probe that cannot get a slot leaves the worker alone
+	release, err := limiter.Acquire(ctx, "test-conn")
+	require.NoError(t, err)
+	pool.checkIdleWorkers()
+	release()
+
+	next, err := pool.ExecuteSQLCommand(ctx, "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+	assert.Equal(t, pidOf(first.Output), pidOf(next.Output))
+}
+
+func TestPooledExecutor_WorkersPerSession(t *testing.T) {
+	pool := newTestPool(t, PoolOptions{MaxWorkers: 1, MaxUses: 10})
+	ctx1 := WithSessionID(context.Background(), "session-1")
+	ctx2 := WithSessionID(context.Background(), "session-2")
+
+	first, err := pool.ExecuteSQLCommand(ctx1, "test-conn", "USE app", "json")
+	require.NoError(t, err)
+	other, err := pool.ExecuteSQLCommand(ctx2, "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+	again, err := pool.ExecuteSQLCommand(ctx1, "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, pidOf(first.Output), pidOf(other.Output), "sessions do not share workers")
+	assert.Equal(t, pidOf(first.Output), pidOf(again.Output))
+
+	// Ending a session stops its workers only
+	pool.EndSession("session-1")
+	assert.Empty(t, pool.idle[workerKey{session: "session-1", connection: "test-conn", output: "json"}])
+	assert.Len(t, pool.idle[workerKey{session: "session-2", connection: "test-conn", output: "json"}], 1)
+	assert.Empty(t, pool.ended, "sessions without busy workers are not remembered")
+}
+
+func TestPooledExecutor_FallsBackWhenClosed(t *testing.T) {
+	pool := newTestPool(t, PoolOptions{MaxWorkers: 1, MaxUses: 10})
+	require.NoError(t, pool.Close())
+
+	result, err := pool.ExecuteSQLCommand(context.Background(), "test-conn", "SELECT 1", "json")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Contains(t, result.Output, "oneshot: SELECT 1")
+}
+
+// pidOf extracts the worker pid echoed by the pool mock
+func pidOf(output string) string {
+	pid, _, _ := strings.Cut(strings.TrimPrefix(output, "pid "), ":")
+	return pid
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package sqlpp
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts cmd in a new process group
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// interruptGroup stops the process led by p. Windows has no SIGINT for
+// background processes, so this terminates it immediately.
+func interruptGroup(p *os.Process) error {
+	return p.Kill()
+}
+
+// killGroup terminates the process led by p
+func killGroup(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,113 @@
+package sqlpp
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// configFlag is the sqlpp flag that selects its configuration file
+const configFlag = "--config"
+
+// ProcessOptions controls the environment a sqlpp process starts in
+type ProcessOptions struct {
+	// ConfigFile is passed to sqlpp with --config; empty leaves sqlpp to
+	// find its configuration itself
+	ConfigFile string
+	// WorkingDir is the process working directory; empty means the server's
+	WorkingDir string
+	// EnvAllow lists the server environment variables sqlpp may see. A
+	// trailing * matches by prefix, e.g. PG*. Empty passes everything.
+	EnvAllow []string
+	// EnvSet holds KEY=VALUE pairs set after the allowlist is applied
+	EnvSet []string
+}
+
+// SetProcessOptions configures the config file, working directory and
+// environment of sqlpp processes per connection. Calls without a
+// connection, such as list_connections, use the options for "".
+func (e *Executor) SetProcessOptions(forConnection func(connection string) ProcessOptions) {
+	e.processOptions = forConnection
+}
+
+// processOptionsFor returns the process options for connection
+func (e *Executor) processOptionsFor(connection string) ProcessOptions {
+	if e.processOptions == nil {
+		return ProcessOptions{}
+	}
+	return e.processOptions(connection)
+}
+
+// command builds a sqlpp command for connection with its process options applied
+func (e *Executor) command(ctx context.Context, connection string, args []string) *exec.Cmd {
+	opts := e.processOptionsFor(connection)
+	if opts.ConfigFile != "" {
+		args = append([]string{configFlag, opts.ConfigFile}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, e.executablePath, args...)
+	opts.apply(cmd)
+	return cmd
+}
+
+// apply sets the working directory and environment of cmd
+func (o ProcessOptions) apply(cmd *exec.Cmd) {
+	cmd.Dir = o.WorkingDir
+	cmd.Env = o.environ(os.Environ())
+}
+
+// environ builds the process environment from base, the server's own. It
+// returns nil, meaning inherit, when no allowlist or overrides are set.
+func (o ProcessOptions) environ(base []string) []string {
+	if len(o.EnvAllow) == 0 && len(o.EnvSet) == 0 {
+		return nil
+	}
+
+	var env []string
+	for _, kv := range base {
+		name, _, _ := strings.Cut(kv, "=")
+		if o.allowed(name) {
+			env = append(env, kv)
+		}
+	}
+
+	// Overrides replace any inherited value
+	for _, kv := range o.EnvSet {
+		name, _, _ := strings.Cut(kv, "=")
+		env = removeEnv(env, name)
+		env = append(env, kv)
+	}
+	if env == nil {
+		env = []string{}
+	}
+	return env
+}
+
+// allowed reports whether the variable name passes the allowlist
+func (o ProcessOptions) allowed(name string) bool {
+	if len(o.EnvAllow) == 0 {
+		return true
+	}
+	for _, pattern := range o.EnvAllow {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// removeEnv drops the variable name from env
+func removeEnv(env []string, name string) []string {
+	kept := env[:0]
+	for _, kv := range env {
+		if n, _, _ := strings.Cut(kv, "="); n != name {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
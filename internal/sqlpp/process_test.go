@@ -0,0 +1,92 @@
+package sqlpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessOptions_Environ(t *testing.T) {
+	base := []string{"PATH=/usr/bin", "HOME=/root", "PGHOST=prod", "PGUSER=admin", "AWS_SECRET_ACCESS_KEY=secret"}
+
+	// No settings inherit the server environment
+	assert.Nil(t, ProcessOptions{}.environ(base))
+
+	// Overrides alone keep everything else
+	env := ProcessOptions{EnvSet: []string{"PGHOST=replica"}}.environ(base)
+	assert.Equal(t, []string{"PATH=/usr/bin", "HOME=/root", "PGUSER=admin", "AWS_SECRET_ACCESS_KEY=secret", "PGHOST=replica"}, env)
+
+	// The allowlist drops everything it does not match
+	env = ProcessOptions{
+		EnvAllow: []string{"PATH", "PG*"},
+		EnvSet:   []string{"PGAPPNAME=mcp_sqlpp"},
+	}.environ(base)
+	assert.Equal(t, []string{"PATH=/usr/bin", "PGHOST=prod", "PGUSER=admin", "PGAPPNAME=mcp_sqlpp"}, env)
+
+	// An allowlist that matches nothing yields an empty, not inherited, environment
+	env = ProcessOptions{EnvAllow: []string{"NOTHING"}}.environ(base)
+	assert.NotNil(t, env)
+	assert.Empty(t, env)
+}
+
+func TestExecuteSQLCommand_ProcessOptions(t *testing.T) {
+	// Create a mock sqlpp executable that reports how it was started
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+	workDir := t.TempDir()
+
+	mockScript := `#!/bin/bash
+echo "args: $*"
+echo "pwd: $(pwd)"
+echo "allowed: ${MCP_TEST_ALLOWED:-unset}"
+echo "blocked: ${MCP_TEST_BLOCKED:-unset}"
+echo "set: ${MCP_TEST_SET:-unset}"
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	t.Setenv("MCP_TEST_ALLOWED", "yes")
+	t.Setenv("MCP_TEST_BLOCKED", "yes")
+
+	logger := logrus.New()
+	executor := NewExecutor(mockSqlpp, 30, logger)
+	executor.SetProcessOptions(func(connection string) ProcessOptions {
+		if connection != "warehouse" {
+			return ProcessOptions{}
+		}
+		return ProcessOptions{
+			ConfigFile: "/etc/sqlpp/warehouse.yaml",
+			WorkingDir: workDir,
+			EnvAllow:   []string{"MCP_TEST_ALLOWED"},
+			EnvSet:     []string{"MCP_TEST_SET=value"},
+		}
+	})
+
+	result, err := executor.ExecuteSQLCommand(context.Background(), "warehouse", "SELECT 1", "")
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	resolvedWorkDir, err := filepath.EvalSymlinks(workDir)
+	require.NoError(t, err)
+
+	lines := strings.Split(result.Output, "\n")
+	assert.Equal(t, "args: --config /etc/sqlpp/warehouse.yaml --stdin --connection warehouse", lines[0])
+	assert.Contains(t, []string{"pwd: " + workDir, "pwd: " + resolvedWorkDir}, lines[1])
+	assert.Equal(t, "allowed: yes", lines[2])
+	assert.Equal(t, "blocked: unset", lines[3])
+	assert.Equal(t, "set: value", lines[4])
+	assert.Equal(t, []string{"--config", "/etc/sqlpp/warehouse.yaml", "--stdin", "--connection", "warehouse"}, result.Args)
+
+	// Other connections inherit the server environment
+	result, err = executor.ExecuteSQLCommand(context.Background(), "main", "SELECT 1", "")
+	require.NoError(t, err)
+	assert.Contains(t, result.Output, "args: --stdin --connection main")
+	assert.Contains(t, result.Output, "blocked: yes")
+}
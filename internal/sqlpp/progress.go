@@ -0,0 +1,132 @@
+package sqlpp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressInterval is how often progress is reported while sqlpp is running
+const progressInterval = 500 * time.Millisecond
+
+// streamChunkSize is how much output is read and passed on at a time, so a
+// very long line is never held in memory whole
+const streamChunkSize = 32 * 1024
+
+// Progress describes how far a running sqlpp command has got
+type Progress struct {
+	Rows    int           // output lines received so far (rows for tabular output)
+	Bytes   int64         // output bytes received so far
+	Elapsed time.Duration // time since the command started
+	Done    bool          // true for the final report once output is complete
+}
+
+// ProgressFunc receives progress updates while sqlpp output is streamed
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context that requests streamed execution, reporting
+// progress to fn as sqlpp output arrives
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressFrom returns the progress callback carried by ctx, if any
+func progressFrom(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// progressTracker counts streamed output and reports it at a fixed interval
+type progressTracker struct {
+	mu     sync.Mutex
+	rows   int
+	bytes  int64
+	start  time.Time
+	report ProgressFunc
+}
+
+func newProgressTracker(report ProgressFunc) *progressTracker {
+	return &progressTracker{
+		start:  time.Now(),
+		report: report,
+	}
+}
+
+func (t *progressTracker) add(n, lines int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows += lines
+	t.bytes += int64(n)
+}
+
+func (t *progressTracker) snapshot(done bool) Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Progress{
+		Rows:    t.rows,
+		Bytes:   t.bytes,
+		Elapsed: time.Since(t.start),
+		Done:    done,
+	}
+}
+
+// stream copies r to dst, reporting progress periodically and
+// once more when r is exhausted
+func (t *progressTracker) stream(r io.Reader, dst io.Writer) error {
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.report(t.snapshot(false))
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	err := copyChunks(r, dst, t.add)
+
+	close(stop)
+	wg.Wait()
+	t.report(t.snapshot(true))
+
+	return err
+}
+
+// copyChunks copies r to dst in fixed-size chunks, calling onChunk with the
+// size of each chunk written and the number of lines it completes. A final
+// line without a trailing newline counts as a line.
+func copyChunks(r io.Reader, dst io.Writer, onChunk func(n, lines int)) error {
+	buf := make([]byte, streamChunkSize)
+	partial := false
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			if _, werr := dst.Write(chunk); werr != nil {
+				return werr
+			}
+			onChunk(n, bytes.Count(chunk, []byte{'\n'}))
+			partial = chunk[n-1] != '\n'
+		}
+		if err == io.EOF {
+			if partial {
+				onChunk(0, 1)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package sqlpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQueryNotFound is returned when cancelling a query that is not running
+var ErrQueryNotFound = errors.New("query not found or already finished")
+
+// errQueryCancelled is the context cause for queries stopped by Cancel
+var errQueryCancelled = errors.New("cancelled by cancel_query")
+
+// maxFingerprintLength bounds the statement fingerprint shown for a query
+const maxFingerprintLength = 200
+
+// RunningQuery describes an in-flight sqlpp process
+type RunningQuery struct {
+	ID          string    `json:"id"`
+	Connection  string    `json:"connection,omitempty"`
+	Operation   Operation `json:"operation"`
+	Fingerprint string    `json:"fingerprint"`
+	StartedAt   time.Time `json:"started_at"`
+	ElapsedMs   int64     `json:"elapsed_ms"`
+	Session     string    `json:"session,omitempty"`
+	PID         int       `json:"pid"`
+	Pooled      bool      `json:"pooled"`
+}
+
+// QueryRegistry tracks in-flight sqlpp processes so they can be listed and
+// cancelled
+type QueryRegistry struct {
+	mu      sync.Mutex
+	next    int
+	running map[string]*runningQuery
+}
+
+type runningQuery struct {
+	info   RunningQuery
+	cancel context.CancelCauseFunc
+}
+
+// NewQueryRegistry creates an empty registry
+func NewQueryRegistry() *QueryRegistry {
+	return &QueryRegistry{running: make(map[string]*runningQuery)}
+}
+
+// List returns the queries session is running, oldest first. Sessions only
+// see their own queries, which carry their SQL.
+func (r *QueryRegistry) List(session string) []RunningQuery {
+	r.mu.Lock()
+	queries := make([]RunningQuery, 0, len(r.running))
+	for _, q := range r.running {
+		if q.info.Session != session {
+			continue
+		}
+		info := q.info
+		info.ElapsedMs = time.Since(info.StartedAt).Milliseconds()
+		queries = append(queries, info)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].StartedAt.Before(queries[j].StartedAt)
+	})
+	return queries
+}
+
+// Cancel stops the query with the given ID that session is running. The
+// process is interrupted first and killed if it has not exited after the
+// executor's grace period. Other sessions' queries are not found.
+func (r *QueryRegistry) Cancel(session, id string) error {
+	r.mu.Lock()
+	q, ok := r.running[id]
+	r.mu.Unlock()
+	if !ok || q.info.Session != session {
+		return ErrQueryNotFound
+	}
+
+	q.cancel(errQueryCancelled)
+	return nil
+}
+
+// add registers a started query and returns its ID
+func (r *QueryRegistry) add(info RunningQuery, cancel context.CancelCauseFunc) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next++
+	info.ID = fmt.Sprintf("q-%d", r.next)
+	r.running[info.ID] = &runningQuery{info: info, cancel: cancel}
+	return info.ID
+}
+
+// remove drops a finished query
+func (r *QueryRegistry) remove(id string) {
+	r.mu.Lock()
+	delete(r.running, id)
+	r.mu.Unlock()
+}
+
+// track registers a query started for req and returns a function that
+// removes it again
+func (r *QueryRegistry) track(ctx context.Context, req *Request, pid int, pooled bool, cancel context.CancelCauseFunc) func() {
+	id := r.add(RunningQuery{
+		Connection:  req.Connection,
+		Operation:   req.Operation,
+		Fingerprint: requestFingerprint(req),
+		StartedAt:   time.Now(),
+		Session:     SessionIDFrom(ctx),
+		PID:         pid,
+		Pooled:      pooled,
+	}, cancel)
+	return func() { r.remove(id) }
+}
+
+// requestFingerprint summarises what a request runs
+func requestFingerprint(req *Request) string {
+	if req.Stdin {
+		return Fingerprint(req.Input)
+	}
+	return strings.Join(req.Args, " ")
+}
+
+// Fingerprint normalises a statement for display: literals become ?,
+// comments are dropped, whitespace is collapsed, and long text is cut short
+func Fingerprint(sql string) string {
+	var b strings.Builder
+	prev := 0
+
+	for _, tok := range lexSQL(sql) {
+		if gap := sql[prev:tok.start]; gap != "" && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		prev = tok.end
+
+		switch {
+		case tok.kind == tokenString:
+			b.WriteByte('?')
+		case tok.kind == tokenWord && tok.text[0] >= '0' && tok.text[0] <= '9':
+			b.WriteByte('?')
+		default:
+			b.WriteString(strings.Join(strings.Fields(tok.text), " "))
+		}
+
+		if b.Len() > maxFingerprintLength {
+			break
+		}
+	}
+
+	fingerprint := b.String()
+	if len(fingerprint) > maxFingerprintLength {
+		fingerprint = strings.ToValidUTF8(fingerprint[:maxFingerprintLength], "") + "..."
+	}
+	return fingerprint
+}
+
+// groupStopper stops a command's process group gracefully: SIGINT first,
+// then SIGKILL if the group is still running after the grace period
+type groupStopper struct {
+	cmd   *exec.Cmd
+	grace time.Duration
+
+	mu     sync.Mutex
+	timer  *time.Timer
+	exited bool
+}
+
+// stop interrupts the process group; it is used as the command's Cancel
+// function so timeouts and cancellation both stop sqlpp gracefully
+func (s *groupStopper) stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.exited || s.timer != nil {
+		return nil
+	}
+
+	s.timer = time.AfterFunc(s.grace, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.exited {
+			_ = killGroup(s.cmd.Process)
+		}
+	})
+	_ = interruptGroup(s.cmd.Process)
+	return nil
+}
+
+// exit records that the command has been waited for, so its process group
+// ID is no longer signalled
+func (s *groupStopper) exit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.exited = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
@@ -0,0 +1,141 @@
+package sqlpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected string
+	}{
+		{"SELECT * FROM users WHERE id = 42", "SELECT * FROM users WHERE id = ?"},
+		{"select name\n  from users -- active only\n where name = 'O''Brien'", "select name from users where name = ?"},
+		{"SELECT count(*) FROM t", "SELECT count(*) FROM t"},
+		{"@schema-tables user%", "@schema-tables user%"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Fingerprint(tt.sql))
+	}
+
+	long := Fingerprint("SELECT " + repeatColumns(100) + " FROM t")
+	assert.LessOrEqual(t, len(long), maxFingerprintLength+3)
+	assert.True(t, len(long) > maxFingerprintLength)
+}
+
+func repeatColumns(n int) string {
+	s := "c0"
+	for i := 1; i < n; i++ {
+		s += ", c0"
+	}
+	return s
+}
+
+func TestQueryRegistry_CancelUnknown(t *testing.T) {
+	registry := NewQueryRegistry()
+	assert.ErrorIs(t, registry.Cancel("", "q-1"), ErrQueryNotFound)
+	assert.Empty(t, registry.List(""))
+}
+
+// runInBackground starts a SQL command and returns a channel with its result
+func runInBackground(executor *Executor, ctx context.Context, command string) chan *types.SqlppResult {
+	results := make(chan *types.SqlppResult, 1)
+	go func() {
+		result, _ := executor.ExecuteSQLCommand(ctx, "main", command, "")
+		results <- result
+	}()
+	return results
+}
+
+// waitRunning waits until session runs exactly one query and returns it
+func waitRunning(t *testing.T, executor *Executor, session string) RunningQuery {
+	var queries []RunningQuery
+	require.Eventually(t, func() bool {
+		queries = executor.Queries().List(session)
+		return len(queries) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	return queries[0]
+}
+
+func TestExecutor_CancelRunningQuery(t *testing.T) {
+	// Create a mock sqlpp executable that exits on SIGINT
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+trap 'echo "interrupted" >&2; exit 130' INT
+while true; do sleep 0.1; done
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	executor := NewExecutor(mockSqlpp, 30, logger)
+
+	ctx := WithSessionID(context.Background(), "session-1")
+	results := runInBackground(executor, ctx, "SELECT * FROM big WHERE id = 7")
+
+	query := waitRunning(t, executor, "session-1")
+	assert.Equal(t, "main", query.Connection)
+	assert.Equal(t, OpSQL, query.Operation)
+	assert.Equal(t, "SELECT * FROM big WHERE id = ?", query.Fingerprint)
+	assert.Equal(t, "session-1", query.Session)
+	assert.NotZero(t, query.PID)
+
+	// Other sessions neither see nor cancel it
+	assert.Empty(t, executor.Queries().List("session-2"))
+	assert.ErrorIs(t, executor.Queries().Cancel("session-2", query.ID), ErrQueryNotFound)
+
+	start := time.Now()
+	require.NoError(t, executor.Queries().Cancel("session-1", query.ID))
+
+	result := <-results
+	require.NotNil(t, result)
+	assert.False(t, result.Success)
+	assert.Equal(t, "sqlpp command cancelled by cancel_query", result.Error)
+	assert.Equal(t, string(CodeCancelled), result.ErrorCode)
+	assert.Less(t, time.Since(start), 3*time.Second)
+	assert.Empty(t, executor.Queries().List("session-1"))
+}
+
+func TestExecutor_CancelKillsAfterGracePeriod(t *testing.T) {
+	// Create a mock sqlpp executable that ignores SIGINT
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+trap '' INT
+while true; do sleep 0.1; done
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	executor := NewExecutor(mockSqlpp, 30, logger)
+	executor.SetCancelGracePeriod(200 * time.Millisecond)
+
+	results := runInBackground(executor, context.Background(), "SELECT 1")
+	query := waitRunning(t, executor, "")
+
+	start := time.Now()
+	require.NoError(t, executor.Queries().Cancel("", query.ID))
+
+	result := <-results
+	require.NotNil(t, result)
+	assert.False(t, result.Success)
+	assert.Equal(t, -1, result.ExitCode)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
@@ -0,0 +1,164 @@
+package sqlpp
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// RetryPolicy controls how failed calls are retried
+type RetryPolicy struct {
+	MaxAttempts    int           // Attempts including the first; 1 or less disables retries
+	InitialBackoff time.Duration // Delay before the first retry
+	MaxBackoff     time.Duration // Upper bound on the delay between attempts
+}
+
+// backoff returns the delay before the given retry (1 for the first retry):
+// exponential growth from InitialBackoff, capped at MaxBackoff, with the
+// upper half randomised so concurrent callers do not retry in lockstep
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// RetryingExecutor retries calls that failed with a transient error, such as
+// a refused connection, deadlock or serialization failure. Only calls that are
+// safe to repeat are retried: schema commands, listings and read-only SQL.
+type RetryingExecutor struct {
+	next   ExecutorInterface
+	policy func(connection string) RetryPolicy
+	logger *logrus.Logger
+
+	// sleep waits between attempts; replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryingExecutor wraps next with retries using the policy returned for
+// each call's connection
+func NewRetryingExecutor(next ExecutorInterface, policy func(connection string) RetryPolicy, logger *logrus.Logger) *RetryingExecutor {
+	return &RetryingExecutor{
+		next:   next,
+		policy: policy,
+		logger: logger,
+		sleep:  sleepContext,
+	}
+}
+
+// ExecuteSchemaCommand implements ExecutorInterface
+func (r *RetryingExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	return r.retry(ctx, connection, func() (*types.SqlppResult, error) {
+		return r.next.ExecuteSchemaCommand(ctx, schemaType, connection, filter, output)
+	})
+}
+
+// ExecuteSQLCommand implements ExecutorInterface. Statements that may modify
+// data run once.
+func (r *RetryingExecutor) ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error) {
+	call := func() (*types.SqlppResult, error) {
+		return r.next.ExecuteSQLCommand(ctx, connection, command, output)
+	}
+	if !IsReadOnlySQL(command) {
+		result, err := call()
+		if result != nil {
+			result.Attempts = 1
+		}
+		return result, err
+	}
+	return r.retry(ctx, connection, call)
+}
+
+// ListConnections implements ExecutorInterface
+func (r *RetryingExecutor) ListConnections(ctx context.Context) (*types.SqlppResult, error) {
+	return r.retry(ctx, "", func() (*types.SqlppResult, error) {
+		return r.next.ListConnections(ctx)
+	})
+}
+
+// ListDrivers implements ExecutorInterface
+func (r *RetryingExecutor) ListDrivers(ctx context.Context) (*types.SqlppResult, error) {
+	return r.retry(ctx, "", func() (*types.SqlppResult, error) {
+		return r.next.ListDrivers(ctx)
+	})
+}
+
+// ValidateExecutable implements ExecutorInterface
+func (r *RetryingExecutor) ValidateExecutable(ctx context.Context) error {
+	return r.next.ValidateExecutable(ctx)
+}
+
+// Close releases the resources of the wrapped executor
+func (r *RetryingExecutor) Close() error {
+	if closer, ok := r.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// retry runs call until it succeeds, fails with a non-transient error, or the
+// connection's attempt limit is reached
+func (r *RetryingExecutor) retry(ctx context.Context, connection string, call func() (*types.SqlppResult, error)) (*types.SqlppResult, error) {
+	policy := r.policy(connection)
+
+	for attempt := 1; ; attempt++ {
+		result, err := call()
+		if err != nil {
+			return nil, err
+		}
+		result.Attempts = attempt
+
+		code := ErrorCode(result.ErrorCode)
+		if result.Success || !code.Transient() || attempt >= policy.MaxAttempts {
+			if attempt > 1 {
+				r.logger.WithFields(logrus.Fields{
+					"connection": connection,
+					"attempts":   attempt,
+					"success":    result.Success,
+					"error_code": result.ErrorCode,
+				}).Info("sqlpp call finished after retries")
+			}
+			return result, nil
+		}
+
+		delay := policy.backoff(attempt)
+		r.logger.WithFields(logrus.Fields{
+			"connection":   connection,
+			"attempt":      attempt,
+			"max_attempts": policy.MaxAttempts,
+			"error_code":   result.ErrorCode,
+			"backoff":      delay,
+		}).Warn("Transient sqlpp failure, retrying")
+
+		if err := r.sleep(ctx, delay); err != nil {
+			// The caller gave up while waiting; report the last failure
+			return result, nil
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,137 @@
+package sqlpp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedExecutor returns queued results in order and counts calls
+type scriptedExecutor struct {
+	blockingExecutor
+	results []*types.SqlppResult
+	calls   int
+}
+
+func (s *scriptedExecutor) next() (*types.SqlppResult, error) {
+	result := s.results[min(s.calls, len(s.results)-1)]
+	s.calls++
+	copied := *result
+	return &copied, nil
+}
+
+func (s *scriptedExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+	return s.next()
+}
+
+func (s *scriptedExecutor) ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error) {
+	return s.next()
+}
+
+func newTestRetrier(next ExecutorInterface, maxAttempts int) (*RetryingExecutor, *[]time.Duration) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	r := NewRetryingExecutor(next, func(connection string) RetryPolicy {
+		return RetryPolicy{MaxAttempts: maxAttempts, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	}, logger)
+
+	var delays []time.Duration
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return r, &delays
+}
+
+var (
+	deadlockResult = &types.SqlppResult{Success: false, ExitCode: 1, Error: "deadlock detected", ErrorCode: string(CodeDeadlock)}
+	syntaxResult   = &types.SqlppResult{Success: false, ExitCode: 1, Error: "syntax error", ErrorCode: string(CodeSyntaxError)}
+	okResult       = &types.SqlppResult{Success: true, Output: "ok"}
+)
+
+func TestRetryingExecutor_RetriesTransientFailure(t *testing.T) {
+	backend := &scriptedExecutor{results: []*types.SqlppResult{deadlockResult, deadlockResult, okResult}}
+	retrier, delays := newTestRetrier(backend, 3)
+
+	result, err := retrier.ExecuteSQLCommand(context.Background(), "main", "SELECT 1", "")
+	require.NoError(t, err)
+
+	assert.True(t, result.Success)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, 3, backend.calls)
+	assert.Len(t, *delays, 2)
+}
+
+func TestRetryingExecutor_StopsAtMaxAttempts(t *testing.T) {
+	backend := &scriptedExecutor{results: []*types.SqlppResult{deadlockResult}}
+	retrier, _ := newTestRetrier(backend, 2)
+
+	result, err := retrier.ExecuteSchemaCommand(context.Background(), "tables", "main", "", "")
+	require.NoError(t, err)
+
+	assert.False(t, result.Success)
+	assert.Equal(t, 2, result.Attempts)
+	assert.Equal(t, 2, backend.calls)
+}
+
+func TestRetryingExecutor_NoRetryForPermanentFailure(t *testing.T) {
+	backend := &scriptedExecutor{results: []*types.SqlppResult{syntaxResult, okResult}}
+	retrier, _ := newTestRetrier(backend, 3)
+
+	result, err := retrier.ExecuteSQLCommand(context.Background(), "main", "SELEC 1", "")
+	require.NoError(t, err)
+
+	assert.False(t, result.Success)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, 1, backend.calls)
+}
+
+func TestRetryingExecutor_NoRetryForWrites(t *testing.T) {
+	backend := &scriptedExecutor{results: []*types.SqlppResult{deadlockResult, okResult}}
+	retrier, _ := newTestRetrier(backend, 3)
+
+	result, err := retrier.ExecuteSQLCommand(context.Background(), "main", "UPDATE users SET active = 1", "")
+	require.NoError(t, err)
+
+	assert.False(t, result.Success)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, 1, backend.calls)
+}
+
+func TestRetryingExecutor_StopsWhenCancelled(t *testing.T) {
+	backend := &scriptedExecutor{results: []*types.SqlppResult{deadlockResult, okResult}}
+	retrier, _ := newTestRetrier(backend, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := retrier.ExecuteSQLCommand(ctx, "main", "SELECT 1", "")
+	require.NoError(t, err)
+
+	assert.False(t, result.Success)
+	assert.Equal(t, 1, backend.calls)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	for i := 0; i < 20; i++ {
+		first := policy.backoff(1)
+		assert.GreaterOrEqual(t, first, 50*time.Millisecond)
+		assert.LessOrEqual(t, first, 100*time.Millisecond)
+
+		second := policy.backoff(2)
+		assert.GreaterOrEqual(t, second, 100*time.Millisecond)
+		assert.LessOrEqual(t, second, 200*time.Millisecond)
+
+		capped := policy.backoff(10)
+		assert.GreaterOrEqual(t, capped, 150*time.Millisecond)
+		assert.LessOrEqual(t, capped, 300*time.Millisecond)
+	}
+}
@@ -0,0 +1,206 @@
+package sqlpp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SampleMode selects which rows a sample returns
+type SampleMode string
+
+const (
+	// SampleFirst returns the first rows the database reads, in no
+	// particular order
+	SampleFirst SampleMode = "first"
+	// SampleRandom returns randomly chosen rows
+	SampleRandom SampleMode = "random"
+)
+
+const (
+	// tableSampleMinRows is the estimated table size from which random
+	// samples read a fraction of the table's pages instead of sorting every
+	// row. Smaller tables are cheap to sort, and page sampling would often
+	// return too few of their rows.
+	tableSampleMinRows = 10000
+
+	// tableSampleFactor is how many more rows than asked for a page sample
+	// aims to read, so that it rarely comes up short
+	tableSampleFactor = 10
+)
+
+// SampleRequest describes rows to sample from a table
+type SampleRequest struct {
+	Schema  string   // empty for the connection's current schema
+	Table   string   // required
+	Columns []string // empty for all columns
+	Limit   int      // rows to return, at least 1
+	Mode    SampleMode
+}
+
+// QuoteIdentifier quotes name as an identifier in dialect, so that it is
+// used as written whatever its case or characters
+func QuoteIdentifier(dialect Dialect, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("identifier must not be empty")
+	}
+	if strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("identifier must not contain NUL characters")
+	}
+
+	switch dialect {
+	case DialectMySQL:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`", nil
+	case DialectSQLServer:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]", nil
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`, nil
+	}
+}
+
+// RowEstimateQuery returns a catalog query for the planner's estimate of the
+// number of rows in table, as a single row_estimate column. It returns ""
+// for dialects whose random samples do not depend on the table size.
+func RowEstimateQuery(dialect Dialect, schema, table string) (string, error) {
+	tbl, err := quoteString(dialect, table)
+	if err != nil {
+		return "", fmt.Errorf("invalid table name: %v", err)
+	}
+
+	switch dialect {
+	case DialectPostgres:
+		sch, err := schemaExpression(dialect, schema)
+		if err != nil {
+			return "", err
+		}
+		return `SELECT c.reltuples AS row_estimate
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = ` + sch + ` AND c.relname = ` + tbl, nil
+	case DialectSQLServer:
+		sch, err := schemaExpression(dialect, schema)
+		if err != nil {
+			return "", err
+		}
+		return `SELECT SUM(p.rows) AS row_estimate
+FROM sys.partitions p
+WHERE p.object_id = OBJECT_ID(QUOTENAME(` + sch + `) + '.' + QUOTENAME(` + tbl + `)) AND p.index_id IN (0, 1)`, nil
+	case DialectOracle:
+		sch, err := schemaExpression(dialect, schema)
+		if err != nil {
+			return "", err
+		}
+		return `SELECT num_rows AS row_estimate
+FROM all_tables
+WHERE owner = ` + sch + ` AND table_name = ` + tbl, nil
+	default:
+		return "", nil
+	}
+}
+
+// ParseRowEstimate reads the output of a RowEstimateQuery. A missing or
+// negative estimate, as for tables that were never analysed, is returned
+// as 0.
+func ParseRowEstimate(output string) (float64, error) {
+	rows, err := resultRows(output)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return math.Max(numberValue(rows[0]["row_estimate"]), 0), nil
+}
+
+// SampleQuery returns a SELECT for the rows req asks for. estimatedRows is
+// the table size from RowEstimateQuery, or 0 if it is unknown; large tables
+// are sampled by page where the dialect supports TABLESAMPLE, so random
+// samples do not sort the whole table. Names are quoted as identifiers.
+func SampleQuery(dialect Dialect, req SampleRequest, estimatedRows float64) (string, error) {
+	if req.Limit < 1 {
+		return "", fmt.Errorf("limit must be at least 1")
+	}
+	if req.Mode != SampleFirst && req.Mode != SampleRandom {
+		return "", fmt.Errorf("unknown sample mode %q (expected %s or %s)", req.Mode, SampleFirst, SampleRandom)
+	}
+
+	from, err := QuoteIdentifier(dialect, req.Table)
+	if err != nil {
+		return "", fmt.Errorf("invalid table name: %v", err)
+	}
+	if req.Schema != "" {
+		schema, err := QuoteIdentifier(dialect, req.Schema)
+		if err != nil {
+			return "", fmt.Errorf("invalid schema name: %v", err)
+		}
+		from = schema + "." + from
+	}
+
+	columns := "*"
+	if len(req.Columns) > 0 {
+		quoted := make([]string, len(req.Columns))
+		for i, column := range req.Columns {
+			if quoted[i], err = QuoteIdentifier(dialect, column); err != nil {
+				return "", fmt.Errorf("invalid column name: %v", err)
+			}
+		}
+		columns = strings.Join(quoted, ", ")
+	}
+
+	limit := strconv.Itoa(req.Limit)
+	random := req.Mode == SampleRandom
+	percent := samplePercent(req.Limit, estimatedRows)
+
+	switch dialect {
+	case DialectPostgres:
+		if !random {
+			return "SELECT " + columns + " FROM " + from + " LIMIT " + limit, nil
+		}
+		if percent != "" {
+			from += " TABLESAMPLE SYSTEM (" + percent + ")"
+		}
+		return "SELECT " + columns + " FROM " + from + " ORDER BY random() LIMIT " + limit, nil
+	case DialectSQLite:
+		if !random {
+			return "SELECT " + columns + " FROM " + from + " LIMIT " + limit, nil
+		}
+		return "SELECT " + columns + " FROM " + from + " ORDER BY RANDOM() LIMIT " + limit, nil
+	case DialectMySQL:
+		if !random {
+			return "SELECT " + columns + " FROM " + from + " LIMIT " + limit, nil
+		}
+		return "SELECT " + columns + " FROM " + from + " ORDER BY RAND() LIMIT " + limit, nil
+	case DialectSQLServer:
+		if !random {
+			return "SELECT TOP (" + limit + ") " + columns + " FROM " + from, nil
+		}
+		if percent != "" {
+			from += " TABLESAMPLE SYSTEM (" + percent + " PERCENT)"
+		}
+		return "SELECT TOP (" + limit + ") " + columns + " FROM " + from + " ORDER BY NEWID()", nil
+	case DialectOracle:
+		if !random {
+			return "SELECT " + columns + " FROM " + from + " FETCH FIRST " + limit + " ROWS ONLY", nil
+		}
+		if percent != "" {
+			from += " SAMPLE BLOCK (" + percent + ")"
+		}
+		return "SELECT " + columns + " FROM " + from + " ORDER BY DBMS_RANDOM.VALUE FETCH FIRST " + limit + " ROWS ONLY", nil
+	default:
+		return "", fmt.Errorf("sampling is not supported for %s", dialect)
+	}
+}
+
+// samplePercent returns the percentage of a table's pages to read for a
+// random sample of limit rows, or "" if the whole table should be read
+func samplePercent(limit int, estimatedRows float64) string {
+	if estimatedRows < tableSampleMinRows {
+		return ""
+	}
+	percent := 100 * float64(limit*tableSampleFactor) / estimatedRows
+	if percent >= 100 {
+		return ""
+	}
+	// Round up to the smallest percentage Oracle takes, 0.000001
+	return strconv.FormatFloat(math.Ceil(percent*1e6)/1e6, 'f', -1, 64)
+}
@@ -0,0 +1,106 @@
+package sqlpp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		name     string
+		expected string
+	}{
+		{DialectPostgres, `Order "Lines"`, `"Order ""Lines"""`},
+		{DialectSQLite, "users", `"users"`},
+		{DialectOracle, "USERS", `"USERS"`},
+		{DialectMySQL, "my`table", "`my``table`"},
+		{DialectSQLServer, "a]b", "[a]]b]"},
+	}
+	for _, tt := range tests {
+		quoted, err := QuoteIdentifier(tt.dialect, tt.name)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, quoted, tt.dialect)
+	}
+
+	_, err := QuoteIdentifier(DialectPostgres, "")
+	assert.Error(t, err)
+	_, err = QuoteIdentifier(DialectPostgres, "a\x00b")
+	assert.Error(t, err)
+}
+
+func TestSampleQuery(t *testing.T) {
+	first := SampleRequest{Table: "orders", Columns: []string{"id", "total"}, Limit: 5, Mode: SampleFirst}
+	random := SampleRequest{Schema: "sales", Table: "orders", Limit: 10, Mode: SampleRandom}
+
+	tests := []struct {
+		dialect  Dialect
+		req      SampleRequest
+		rows     float64
+		expected string
+	}{
+		{DialectPostgres, first, 0, `SELECT "id", "total" FROM "orders" LIMIT 5`},
+		{DialectSQLite, first, 0, `SELECT "id", "total" FROM "orders" LIMIT 5`},
+		{DialectMySQL, first, 0, "SELECT `id`, `total` FROM `orders` LIMIT 5"},
+		{DialectSQLServer, first, 0, "SELECT TOP (5) [id], [total] FROM [orders]"},
+		{DialectOracle, first, 0, `SELECT "id", "total" FROM "orders" FETCH FIRST 5 ROWS ONLY`},
+
+		// Small or unanalysed tables are sorted whole
+		{DialectPostgres, random, 0, `SELECT * FROM "sales"."orders" ORDER BY random() LIMIT 10`},
+		{DialectPostgres, random, 5000, `SELECT * FROM "sales"."orders" ORDER BY random() LIMIT 10`},
+		{DialectSQLServer, random, 0, "SELECT TOP (10) * FROM [sales].[orders] ORDER BY NEWID()"},
+
+		// Large tables are sampled by page, aiming for ten times the rows
+		{DialectPostgres, random, 1e6, `SELECT * FROM "sales"."orders" TABLESAMPLE SYSTEM (0.01) ORDER BY random() LIMIT 10`},
+		{DialectSQLServer, random, 1e6, "SELECT TOP (10) * FROM [sales].[orders] TABLESAMPLE SYSTEM (0.01 PERCENT) ORDER BY NEWID()"},
+		{DialectOracle, random, 1e6, `SELECT * FROM "sales"."orders" SAMPLE BLOCK (0.01) ORDER BY DBMS_RANDOM.VALUE FETCH FIRST 10 ROWS ONLY`},
+		{DialectPostgres, random, 3e6, `SELECT * FROM "sales"."orders" TABLESAMPLE SYSTEM (0.003334) ORDER BY random() LIMIT 10`},
+		{DialectPostgres, random, 1e15, `SELECT * FROM "sales"."orders" TABLESAMPLE SYSTEM (0.000001) ORDER BY random() LIMIT 10`},
+
+		// Without TABLESAMPLE the whole table is sorted
+		{DialectSQLite, random, 1e6, `SELECT * FROM "sales"."orders" ORDER BY RANDOM() LIMIT 10`},
+		{DialectMySQL, random, 1e6, "SELECT * FROM `sales`.`orders` ORDER BY RAND() LIMIT 10"},
+	}
+	for _, tt := range tests {
+		query, err := SampleQuery(tt.dialect, tt.req, tt.rows)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, query, tt.dialect)
+	}
+
+	_, err := SampleQuery(DialectPostgres, SampleRequest{Table: "orders", Limit: 0, Mode: SampleFirst}, 0)
+	assert.ErrorContains(t, err, "limit must be at least 1")
+
+	_, err = SampleQuery(DialectPostgres, SampleRequest{Table: "orders", Limit: 1, Mode: "last"}, 0)
+	assert.ErrorContains(t, err, `unknown sample mode "last"`)
+
+	_, err = SampleQuery(DialectPostgres, SampleRequest{Table: "orders", Columns: []string{""}, Limit: 1, Mode: SampleFirst}, 0)
+	assert.ErrorContains(t, err, "invalid column name")
+
+	_, err = SampleQuery(Dialect("acme"), first, 0)
+	assert.ErrorContains(t, err, "sampling is not supported for acme")
+}
+
+func TestRowEstimate(t *testing.T) {
+	query, err := RowEstimateQuery(DialectPostgres, "", "orders")
+	require.NoError(t, err)
+	assert.Contains(t, query, "n.nspname = current_schema() AND c.relname = 'orders'")
+
+	query, err = RowEstimateQuery(DialectSQLite, "", "orders")
+	require.NoError(t, err)
+	assert.Empty(t, query, "SQLite samples do not need an estimate")
+
+	estimate, err := ParseRowEstimate(`[{"row_estimate": 125000}]`)
+	require.NoError(t, err)
+	assert.Equal(t, 125000.0, estimate)
+
+	// Never analysed
+	estimate, err = ParseRowEstimate(`[{"row_estimate": -1}]`)
+	require.NoError(t, err)
+	assert.Zero(t, estimate)
+
+	estimate, err = ParseRowEstimate(`[]`)
+	require.NoError(t, err)
+	assert.Zero(t, estimate)
+}
@@ -0,0 +1,209 @@
+package sqlpp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrScriptNotFound is returned for a script name no root contains
+	ErrScriptNotFound = errors.New("script not found")
+
+	// ErrScriptOutsideRoot is returned for names that would leave the script
+	// roots, through .. components, absolute paths or symlinks
+	ErrScriptOutsideRoot = errors.New("script path is outside the script roots")
+)
+
+// scriptExtension is the file extension of runnable scripts
+const scriptExtension = ".sql"
+
+// variableName matches the names accepted for script variables
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Script is a runnable .sql file under a script root
+type Script struct {
+	Name string // slash-separated path relative to its root
+	Path string // absolute path with symlinks resolved
+	Size int64
+}
+
+// ScriptLibrary exposes the .sql files under a set of directories. Scripts
+// are addressed by their path relative to a root; roots are searched in
+// order and nothing outside them can be reached.
+type ScriptLibrary struct {
+	roots []string
+}
+
+// NewScriptLibrary creates a library over roots, which must be directories
+func NewScriptLibrary(roots []string) (*ScriptLibrary, error) {
+	lib := &ScriptLibrary{}
+	for _, root := range roots {
+		resolved, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return nil, fmt.Errorf("invalid script root %s: %w", root, err)
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("invalid script root %s: %w", root, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("invalid script root %s: not a directory", root)
+		}
+		lib.roots = append(lib.roots, resolved)
+	}
+	return lib, nil
+}
+
+// Resolve finds the script with the given relative name
+func (l *ScriptLibrary) Resolve(name string) (*Script, error) {
+	rel := filepath.FromSlash(name)
+	if !filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("%w: %s", ErrScriptOutsideRoot, name)
+	}
+	if !strings.EqualFold(filepath.Ext(rel), scriptExtension) {
+		return nil, fmt.Errorf("%w: %s (only %s files can be run)", ErrScriptNotFound, name, scriptExtension)
+	}
+
+	for _, root := range l.roots {
+		path, err := filepath.EvalSymlinks(filepath.Join(root, rel))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve script %s: %w", name, err)
+		}
+
+		// A symlink inside the root may still point outside it
+		if !within(root, path) {
+			return nil, fmt.Errorf("%w: %s", ErrScriptOutsideRoot, name)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve script %s: %w", name, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%w: %s is not a file", ErrScriptNotFound, name)
+		}
+		return &Script{Name: filepath.ToSlash(rel), Path: path, Size: info.Size()}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrScriptNotFound, name)
+}
+
+// Read returns the content of the named script
+func (l *ScriptLibrary) Read(name string) (*Script, string, error) {
+	script, err := l.Resolve(name)
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := os.ReadFile(script.Path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read script %s: %w", name, err)
+	}
+	return script, string(content), nil
+}
+
+// List returns the scripts in all roots, sorted by name. When several roots
+// hold the same name, the first root's script wins as it does in Resolve.
+func (l *ScriptLibrary) List() ([]Script, error) {
+	seen := make(map[string]bool)
+	var scripts []Script
+
+	for _, root := range l.roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.EqualFold(filepath.Ext(path), scriptExtension) {
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(rel)
+			if seen[name] {
+				return nil
+			}
+
+			// Skip symlinks that escape the root rather than listing them
+			script, err := l.Resolve(name)
+			if err != nil {
+				return nil
+			}
+			seen[name] = true
+			scripts = append(scripts, *script)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list scripts in %s: %w", root, err)
+		}
+	}
+
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i].Name < scripts[j].Name })
+	return scripts, nil
+}
+
+// within reports whether path is root or below it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
+
+// ScriptInput builds the sqlpp input that runs a script with variables set
+// as #define directives ahead of its content. Values are written as SQL
+// literals in dialect, so a string value is quoted and cannot add SQL of its
+// own; scripts use ${name} unquoted where the literal belongs.
+func ScriptInput(content string, dialect Dialect, variables map[string]interface{}) (string, error) {
+	literals := make(map[string]string, len(variables))
+	names := make([]string, 0, len(variables))
+	for name, value := range variables {
+		if !variableName.MatchString(name) {
+			return "", fmt.Errorf("invalid variable name %q", name)
+		}
+		literal, err := variableLiteral(dialect, value)
+		if err != nil {
+			return "", fmt.Errorf("variable %s %w", name, err)
+		}
+		literals[name] = literal
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "#define %s %s\n", name, literals[name])
+	}
+	b.WriteString(content)
+	return b.String(), nil
+}
+
+// variableLiteral writes a script variable's JSON value as a SQL literal
+func variableLiteral(dialect Dialect, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		literal, err := quoteString(dialect, v)
+		if err != nil {
+			return "", fmt.Errorf("is invalid: %w", err)
+		}
+		return literal, nil
+	case float64:
+		literal, err := numberLiteralFor(v)
+		if err != nil {
+			return "", fmt.Errorf("is invalid: %w", err)
+		}
+		return literal, nil
+	case bool:
+		return booleanLiteral(dialect, v), nil
+	default:
+		return "", fmt.Errorf("must be a string, number or boolean")
+	}
+}
@@ -0,0 +1,120 @@
+package sqlpp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript creates a file under dir, making parent directories as needed
+func writeScript(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestScriptLibrary_Resolve(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	writeScript(t, root, "reports/daily_sales.sql", "SELECT * FROM sales")
+	writeScript(t, root, "notes.txt", "not a script")
+	writeScript(t, outside, "secret.sql", "SELECT * FROM secrets")
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.sql"), filepath.Join(root, "escape.sql")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "linked")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "reports", "daily_sales.sql"), filepath.Join(root, "alias.sql")))
+
+	lib, err := NewScriptLibrary([]string{root})
+	require.NoError(t, err)
+
+	script, err := lib.Resolve("reports/daily_sales.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "reports/daily_sales.sql", script.Name)
+	assert.Equal(t, int64(len("SELECT * FROM sales")), script.Size)
+
+	// Symlinks that stay inside the root are followed
+	_, err = lib.Resolve("alias.sql")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		expected error
+	}{
+		{"../secret.sql", ErrScriptOutsideRoot},
+		{"reports/../../secret.sql", ErrScriptOutsideRoot},
+		{filepath.Join(outside, "secret.sql"), ErrScriptOutsideRoot},
+		{"escape.sql", ErrScriptOutsideRoot},
+		{"linked/secret.sql", ErrScriptOutsideRoot},
+		{"missing.sql", ErrScriptNotFound},
+		{"notes.txt", ErrScriptNotFound},
+		{"reports", ErrScriptNotFound},
+	}
+
+	for _, tt := range tests {
+		_, err := lib.Resolve(tt.name)
+		assert.ErrorIs(t, err, tt.expected, tt.name)
+	}
+}
+
+func TestScriptLibrary_List(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+	outside := t.TempDir()
+
+	writeScript(t, first, "reports/daily_sales.sql", "SELECT 1")
+	writeScript(t, second, "reports/daily_sales.sql", "SELECT 2")
+	writeScript(t, second, "admin/vacuum.sql", "VACUUM")
+	writeScript(t, second, "README.md", "docs")
+	writeScript(t, outside, "secret.sql", "SELECT * FROM secrets")
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.sql"), filepath.Join(second, "escape.sql")))
+
+	lib, err := NewScriptLibrary([]string{first, second})
+	require.NoError(t, err)
+
+	scripts, err := lib.List()
+	require.NoError(t, err)
+
+	names := make([]string, len(scripts))
+	for i, script := range scripts {
+		names[i] = script.Name
+	}
+	assert.Equal(t, []string{"admin/vacuum.sql", "reports/daily_sales.sql"}, names)
+
+	// The first root wins for duplicate names
+	_, content, err := lib.Read("reports/daily_sales.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", content)
+}
+
+func TestNewScriptLibrary_InvalidRoot(t *testing.T) {
+	_, err := NewScriptLibrary([]string{filepath.Join(t.TempDir(), "missing")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid script root")
+}
+
+func TestScriptInput(t *testing.T) {
+	input, err := ScriptInput("SELECT * FROM sales WHERE region = ${region} AND total > ${min} AND paid = ${paid}", DialectPostgres, map[string]interface{}{
+		"region": "emea' OR '1'='1",
+		"min":    100.5,
+		"paid":   true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "#define min 100.5\n#define paid TRUE\n#define region 'emea'' OR ''1''=''1'\n"+
+		"SELECT * FROM sales WHERE region = ${region} AND total > ${min} AND paid = ${paid}", input)
+
+	input, err = ScriptInput("SELECT 1", DialectSQLServer, map[string]interface{}{"paid": false, "region": "emea"})
+	require.NoError(t, err)
+	assert.Equal(t, "#define paid 0\n#define region N'emea'\nSELECT 1", input)
+
+	_, err = ScriptInput("SELECT 1", DialectPostgres, map[string]interface{}{"bad name": "x"})
+	assert.ErrorContains(t, err, `invalid variable name "bad name"`)
+
+	_, err = ScriptInput("SELECT 1", DialectPostgres, map[string]interface{}{"region": "emea\n#include /etc/passwd"})
+	assert.ErrorContains(t, err, "variable region is invalid: strings must not contain line breaks")
+
+	_, err = ScriptInput("SELECT 1", DialectPostgres, map[string]interface{}{"day": []interface{}{"a"}})
+	assert.ErrorContains(t, err, "variable day must be a string, number or boolean")
+}
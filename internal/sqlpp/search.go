@@ -0,0 +1,345 @@
+package sqlpp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Schema object types found by schema searches and compared by schema diffs
+const (
+	ObjectTable     = "table"
+	ObjectView      = "view"
+	ObjectColumn    = "column"
+	ObjectIndex     = "index" // diffs only
+	ObjectProcedure = "procedure"
+	ObjectFunction  = "function"
+)
+
+// SchemaObject is a named object in a database catalog. Columns carry the
+// name of their table or view in Name.
+type SchemaObject struct {
+	Type    string `json:"type"`
+	Schema  string `json:"schema,omitempty"`
+	Name    string `json:"name"`
+	Column  string `json:"column,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// SchemaMatch is a schema object that matches a search term
+type SchemaMatch struct {
+	SchemaObject
+	Score     float64 `json:"score"`      // 0 to 1, higher is better
+	MatchedOn string  `json:"matched_on"` // "name" or "comment"
+}
+
+const (
+	// commentWeight scales matches on comments below matches on names
+	commentWeight = 0.6
+
+	// qualifiedWeight scales column matches that need the table name, as
+	// for "customer email" against customers.email
+	qualifiedWeight = 0.9
+)
+
+// SearchCatalogQuery returns a catalog query listing the tables, views,
+// columns, procedures and functions of the connection's user schemas with
+// their comments, as the columns object_type, object_schema, object_name,
+// column_name and comment
+func SearchCatalogQuery(dialect Dialect) (string, error) {
+	switch dialect {
+	case DialectPostgres:
+		return `SELECT CASE WHEN c.relkind IN ('v', 'm') THEN 'view' ELSE 'table' END AS object_type, n.nspname AS object_schema, c.relname AS object_name,
+  NULL AS column_name, obj_description(c.oid, 'pg_class') AS comment
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f') AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'
+UNION ALL
+SELECT 'column', n.nspname, c.relname, a.attname, col_description(c.oid, a.attnum)
+FROM pg_attribute a JOIN pg_class c ON c.oid = a.attrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f') AND a.attnum > 0 AND NOT a.attisdropped
+  AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'
+UNION ALL
+SELECT CASE WHEN p.prokind = 'p' THEN 'procedure' ELSE 'function' END, n.nspname, p.proname, NULL, obj_description(p.oid, 'pg_proc')
+FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+WHERE p.prokind IN ('f', 'p') AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_%'`, nil
+	case DialectMySQL:
+		return `SELECT CASE WHEN table_type = 'VIEW' THEN 'view' ELSE 'table' END AS object_type, table_schema AS object_schema, table_name AS object_name,
+  NULL AS column_name, table_comment AS comment
+FROM information_schema.tables WHERE table_schema = DATABASE()
+UNION ALL
+SELECT 'column', table_schema, table_name, column_name, column_comment
+FROM information_schema.columns WHERE table_schema = DATABASE()
+UNION ALL
+SELECT LOWER(routine_type), routine_schema, routine_name, NULL, routine_comment
+FROM information_schema.routines WHERE routine_schema = DATABASE()`, nil
+	case DialectSQLite:
+		return `SELECT m.type AS object_type, 'main' AS object_schema, m.name AS object_name, NULL AS column_name, NULL AS comment
+FROM sqlite_master m WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite\_%' ESCAPE '\'
+UNION ALL
+SELECT 'column', 'main', m.name, p.name, NULL
+FROM sqlite_master m JOIN pragma_table_info(m.name) p
+WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite\_%' ESCAPE '\'`, nil
+	case DialectSQLServer:
+		// Comments are the MS_Description extended properties
+		return `SELECT CASE o.type WHEN 'U' THEN 'table' WHEN 'V' THEN 'view' WHEN 'P' THEN 'procedure' ELSE 'function' END AS object_type,
+  s.name AS object_schema, o.name AS object_name, NULL AS column_name, CAST(ep.value AS nvarchar(4000)) AS comment
+FROM sys.objects o JOIN sys.schemas s ON s.schema_id = o.schema_id
+LEFT JOIN sys.extended_properties ep ON ep.class = 1 AND ep.major_id = o.object_id AND ep.minor_id = 0 AND ep.name = 'MS_Description'
+WHERE o.type IN ('U', 'V', 'P', 'FN', 'IF', 'TF') AND o.is_ms_shipped = 0
+UNION ALL
+SELECT 'column', s.name, o.name, c.name, CAST(ep.value AS nvarchar(4000))
+FROM sys.columns c JOIN sys.objects o ON o.object_id = c.object_id JOIN sys.schemas s ON s.schema_id = o.schema_id
+LEFT JOIN sys.extended_properties ep ON ep.class = 1 AND ep.major_id = c.object_id AND ep.minor_id = c.column_id AND ep.name = 'MS_Description'
+WHERE o.type IN ('U', 'V') AND o.is_ms_shipped = 0`, nil
+	case DialectOracle:
+		return `SELECT LOWER(o.object_type) AS object_type, o.owner AS object_schema, o.object_name AS object_name, NULL AS column_name, tc.comments AS comment
+FROM all_objects o
+LEFT JOIN all_tab_comments tc ON tc.owner = o.owner AND tc.table_name = o.object_name
+WHERE o.owner = SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') AND o.object_type IN ('TABLE', 'VIEW', 'PROCEDURE', 'FUNCTION')
+UNION ALL
+SELECT 'column', c.owner, c.table_name, c.column_name, cc.comments
+FROM all_tab_columns c
+LEFT JOIN all_col_comments cc ON cc.owner = c.owner AND cc.table_name = c.table_name AND cc.column_name = c.column_name
+WHERE c.owner = SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA')`, nil
+	default:
+		return "", fmt.Errorf("schema search is not supported for %s", dialect)
+	}
+}
+
+// ParseSchemaObjects reads the output of a SearchCatalogQuery
+func ParseSchemaObjects(output string) ([]SchemaObject, error) {
+	rows, err := resultRows(output)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]SchemaObject, 0, len(rows))
+	for _, row := range rows {
+		lower := lowerKeys(row)
+		object := SchemaObject{
+			Type:    stringValue(lower["object_type"]),
+			Schema:  stringValue(lower["object_schema"]),
+			Name:    stringValue(lower["object_name"]),
+			Column:  stringValue(lower["column_name"]),
+			Comment: strings.TrimSpace(stringValue(lower["comment"])),
+		}
+		if object.Name != "" {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// RankSchemaObjects returns the objects matching term with a score of at
+// least minScore, best first. Names are matched fuzzily, so word order,
+// case, separators and small typos do not stop a match; comments are
+// matched word by word and rank below names.
+func RankSchemaObjects(term string, objects []SchemaObject, minScore float64) []SchemaMatch {
+	query := newSearchText(term)
+	if query.compact == "" {
+		return nil
+	}
+
+	var matches []SchemaMatch
+	for _, object := range objects {
+		var score float64
+		if object.Type == ObjectColumn {
+			score = max(
+				query.match(newSearchText(object.Column)),
+				qualifiedWeight*query.match(newSearchText(object.Name+" "+object.Column)),
+			)
+		} else {
+			score = query.match(newSearchText(object.Name))
+		}
+		matchedOn := "name"
+		if object.Comment != "" {
+			if commentScore := commentWeight * query.matchWords(newSearchText(object.Comment)); commentScore > score {
+				score, matchedOn = commentScore, "comment"
+			}
+		}
+		if score >= minScore && score > 0 {
+			matches = append(matches, SchemaMatch{SchemaObject: object, Score: roundScore(score), MatchedOn: matchedOn})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		// Tables before their columns, then by name
+		if matches[i].Name != matches[j].Name {
+			return matches[i].Name < matches[j].Name
+		}
+		return matches[i].Column < matches[j].Column
+	})
+	return matches
+}
+
+// searchText is text prepared for matching: its lower-case words, split at
+// separators and camelCase boundaries, and those words run together
+type searchText struct {
+	words   []string
+	compact string
+}
+
+func newSearchText(s string) searchText {
+	var words []string
+	var word []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		// customerEmail and HTTPServer split before the upper-case letter
+		// that starts a word
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+			flush()
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	flush()
+	return searchText{words: words, compact: strings.Join(words, "")}
+}
+
+// match scores how well a name matches the search text, from 1 for the
+// same words down to loose matches of the letters in order
+func (q searchText) match(name searchText) float64 {
+	if name.compact == "" {
+		return 0
+	}
+	switch {
+	case name.compact == q.compact:
+		return 1
+	case strings.HasPrefix(name.compact, q.compact):
+		return 0.9
+	case strings.Contains(name.compact, q.compact):
+		return 0.8
+	}
+
+	score := 0.75 * q.matchWords(name)
+	if withinTypos(q.compact, name.compact) {
+		score = max(score, 0.7)
+	}
+	if isSubsequence(q.compact, name.compact) {
+		// Letters in order, e.g. "cstmr" in "customer", by how much of the
+		// name they cover
+		score = max(score, 0.3+0.3*float64(len(q.compact))/float64(len(name.compact)))
+	}
+	return score
+}
+
+// matchWords scores how well every search word is found among the words of
+// text, allowing prefixes and small typos, from 1 for all found exactly
+func (q searchText) matchWords(text searchText) float64 {
+	if len(q.words) == 0 {
+		return 0
+	}
+	var total float64
+	for _, want := range q.words {
+		best := 0.0
+		for _, have := range text.words {
+			switch {
+			case have == want:
+				best = 1
+			case strings.HasPrefix(have, want):
+				best = max(best, 0.9)
+			case strings.Contains(have, want):
+				best = max(best, 0.8)
+			case withinTypos(want, have):
+				best = max(best, 0.7)
+			case typoPrefix(want, have):
+				best = max(best, 0.65)
+			}
+			if best == 1 {
+				break
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total / float64(len(q.words))
+}
+
+// withinTypos reports whether a and b differ by no more typos than the
+// length of a allows: none below 4 letters, one below 8, then two
+func withinTypos(a, b string) bool {
+	allowed := 0
+	switch {
+	case len(a) >= 8:
+		allowed = 2
+	case len(a) >= 4:
+		allowed = 1
+	}
+	if allowed == 0 {
+		return false
+	}
+	diff := len(a) - len(b)
+	if diff > allowed || -diff > allowed {
+		return false
+	}
+	return editDistance(a, b) <= allowed
+}
+
+// typoPrefix reports whether a starts b but for a few typos, as "custmer"
+// starts "customers"
+func typoPrefix(a, b string) bool {
+	for n := len(a) - 1; n <= len(a)+1; n++ {
+		if n > 0 && n < len(b) && withinTypos(a, b[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// isSubsequence reports whether the letters of a appear in b in order
+func isSubsequence(a, b string) bool {
+	rb := []rune(b)
+	j := 0
+	for _, r := range a {
+		for j < len(rb) && rb[j] != r {
+			j++
+		}
+		if j == len(rb) {
+			return false
+		}
+		j++
+	}
+	return true
+}
+
+// roundScore rounds a score to two decimals for display
+func roundScore(score float64) float64 {
+	return float64(int(score*100+0.5)) / 100
+}
@@ -0,0 +1,83 @@
+package sqlpp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchCatalogQuery(t *testing.T) {
+	for _, dialect := range []Dialect{DialectPostgres, DialectMySQL, DialectSQLite, DialectSQLServer, DialectOracle} {
+		query, err := SearchCatalogQuery(dialect)
+		require.NoError(t, err, dialect)
+		for _, column := range []string{"object_type", "object_schema", "object_name", "column_name", "comment"} {
+			assert.Contains(t, query, "AS "+column, dialect)
+		}
+	}
+
+	_, err := SearchCatalogQuery(Dialect("db2"))
+	assert.ErrorContains(t, err, "schema search is not supported")
+}
+
+func TestParseSchemaObjects(t *testing.T) {
+	objects, err := ParseSchemaObjects(`[{"OBJECT_TYPE": "table", "OBJECT_SCHEMA": "APP", "OBJECT_NAME": "ORDERS", "COLUMN_NAME": null, "COMMENT": " Customer orders "},
+		{"OBJECT_TYPE": "column", "OBJECT_SCHEMA": "APP", "OBJECT_NAME": "ORDERS", "COLUMN_NAME": "ID", "COMMENT": null},
+		{"OBJECT_TYPE": "table", "OBJECT_NAME": null}]`)
+	require.NoError(t, err)
+	assert.Equal(t, []SchemaObject{
+		{Type: ObjectTable, Schema: "APP", Name: "ORDERS", Comment: "Customer orders"},
+		{Type: ObjectColumn, Schema: "APP", Name: "ORDERS", Column: "ID"},
+	}, objects)
+}
+
+func TestRankSchemaObjects(t *testing.T) {
+	objects := []SchemaObject{
+		{Type: ObjectTable, Name: "customer_addresses"},
+		{Type: ObjectTable, Name: "Customer"},
+		{Type: ObjectColumn, Name: "customers", Column: "emailAddress"},
+		{Type: ObjectColumn, Name: "customers", Column: "email"},
+		{Type: ObjectColumn, Name: "users", Column: "mail_opt_in"},
+		{Type: ObjectView, Name: "active_accounts", Comment: "Accounts of customers with an order this year"},
+		{Type: ObjectFunction, Name: "calc_tax"},
+	}
+
+	tests := []struct {
+		term     string
+		expected []string // names of the matches, best first
+	}{
+		{"customer", []string{"Customer", "customer_addresses", "customers.email", "customers.emailAddress", "active_accounts"}},
+		{"email", []string{"customers.email", "customers.emailAddress", "users.mail_opt_in"}},
+		{"customer email", []string{"customers.email", "customers.emailAddress"}},
+		{"email address", []string{"customers.emailAddress"}},
+		{"custmer", []string{"Customer", "customer_addresses", "customers.email", "customers.emailAddress"}},
+		{"ctax", []string{"calc_tax"}},
+		{"invoice", nil},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			var names []string
+			for _, match := range RankSchemaObjects(tt.term, objects, 0.4) {
+				name := match.Name
+				if match.Column != "" {
+					name += "." + match.Column
+				}
+				names = append(names, name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+
+	matches := RankSchemaObjects("order", objects, 0.4)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "comment", matches[0].MatchedOn)
+	assert.Equal(t, 0.6, matches[0].Score)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("order", "order"))
+	assert.Equal(t, 1, editDistance("order", "ordr"))
+	assert.Equal(t, 2, editDistance("customer", "custmoer"))
+	assert.Equal(t, 3, editDistance("", "abc"))
+}
@@ -0,0 +1,371 @@
+package sqlpp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrResultNotFound is returned for unknown or expired result handles
+	ErrResultNotFound = errors.New("result handle not found or expired")
+
+	// ErrResultTooLarge is returned by ReadAll for a result over its limit
+	ErrResultTooLarge = errors.New("stored result is too large to read whole")
+)
+
+// ResultStore keeps sqlpp output that exceeded the in-memory limit in temp
+// files, addressed by opaque handles. Entries are removed when their session
+// ends or once they are older than the TTL.
+type ResultStore struct {
+	dir    string
+	ttl    time.Duration
+	logger *logrus.Logger
+
+	mu      sync.Mutex
+	entries map[string]*storedResult
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// storedResult is a spilled output file and its owner
+type storedResult struct {
+	path    string
+	size    int64
+	session string
+	created time.Time
+}
+
+// ResultPage is a slice of a stored result
+type ResultPage struct {
+	Handle     string `json:"handle"`
+	Offset     int64  `json:"offset"`
+	NextOffset int64  `json:"next_offset"`
+	TotalSize  int64  `json:"total_size"`
+	EOF        bool   `json:"eof"`
+	Data       string `json:"data"`
+}
+
+// NewResultStore creates a store in dir (a new temp directory if empty) and
+// starts removing entries older than ttl
+func NewResultStore(dir string, ttl time.Duration, logger *logrus.Logger) (*ResultStore, error) {
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "mcp_sqlpp-results-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create result directory: %w", err)
+		}
+		dir = tmp
+	} else if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create result directory: %w", err)
+	}
+
+	s := &ResultStore{
+		dir:     dir,
+		ttl:     ttl,
+		logger:  logger,
+		entries: make(map[string]*storedResult),
+		stop:    make(chan struct{}),
+	}
+
+	if ttl > 0 {
+		s.stopped.Add(1)
+		go s.expireLoop()
+	}
+
+	return s, nil
+}
+
+// Read returns up to limit bytes of the stored result starting at offset.
+// Pages that do not reach the end are cut back to the last full line, or to
+// the last full character of a line longer than the page. Results of other
+// sessions are not found.
+func (s *ResultStore) Read(session, handle string, offset, limit int64) (*ResultPage, error) {
+	entry, err := s.entry(session, handle)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset < 0 || offset > entry.size {
+		return nil, fmt.Errorf("offset %d is outside the result (size %d)", offset, entry.size)
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be greater than 0")
+	}
+
+	f, err := os.Open(entry.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stored result: %w", err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, min(limit, entry.size-offset))
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read stored result: %w", err)
+	}
+	data := buf[:n]
+
+	eof := offset+int64(n) >= entry.size
+	if !eof {
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		} else {
+			data = data[:completeRunes(data)]
+		}
+	}
+
+	return &ResultPage{
+		Handle:     handle,
+		Offset:     offset,
+		NextOffset: offset + int64(len(data)),
+		TotalSize:  entry.size,
+		EOF:        eof,
+		Data:       string(data),
+	}, nil
+}
+
+// ReadAll returns the whole stored result, for callers that parse the output
+// rather than page through it. Results larger than limit bytes are not read.
+func (s *ResultStore) ReadAll(session, handle string, limit int64) (string, error) {
+	entry, err := s.entry(session, handle)
+	if err != nil {
+		return "", err
+	}
+	if entry.size > limit {
+		return "", fmt.Errorf("%w: %d bytes, more than %d", ErrResultTooLarge, entry.size, limit)
+	}
+
+	data, err := os.ReadFile(entry.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stored result: %w", err)
+	}
+	return string(data), nil
+}
+
+// entry returns the stored result behind handle if session owns it
+func (s *ResultStore) entry(session, handle string) (*storedResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[handle]
+	if !ok || entry.session != session {
+		return nil, ErrResultNotFound
+	}
+	return entry, nil
+}
+
+// completeRunes returns the length of data without a multi-byte character
+// cut off at its end. A page too small for a single character keeps its
+// bytes, so reading always moves forward.
+func completeRunes(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(data[i]) {
+			continue
+		}
+		if utf8.FullRune(data[i:]) || i == 0 {
+			return len(data)
+		}
+		return i
+	}
+	return len(data)
+}
+
+// ReleaseSession removes every result owned by the given session
+func (s *ResultStore) ReleaseSession(session string) {
+	s.removeWhere(func(entry *storedResult) bool {
+		return entry.session == session
+	})
+}
+
+// Close stops expiry and deletes all stored results
+func (s *ResultStore) Close() error {
+	close(s.stop)
+	s.stopped.Wait()
+
+	s.mu.Lock()
+	s.entries = make(map[string]*storedResult)
+	s.mu.Unlock()
+
+	return os.RemoveAll(s.dir)
+}
+
+// create opens a new temp file for spilled output
+func (s *ResultStore) create() (*os.File, error) {
+	return os.CreateTemp(s.dir, "result-*.out")
+}
+
+// add registers a completed spill file and returns its handle
+func (s *ResultStore) add(path string, size int64, session string) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate result handle: %w", err)
+	}
+	handle := hex.EncodeToString(raw)
+
+	s.mu.Lock()
+	s.entries[handle] = &storedResult{
+		path:    path,
+		size:    size,
+		session: session,
+		created: time.Now(),
+	}
+	s.mu.Unlock()
+
+	s.logger.WithFields(logrus.Fields{
+		"handle":  handle,
+		"size":    size,
+		"session": session,
+	}).Debug("Stored spilled sqlpp output")
+
+	return handle, nil
+}
+
+// expireLoop periodically removes results older than the TTL
+func (s *ResultStore) expireLoop() {
+	defer s.stopped.Done()
+
+	ticker := time.NewTicker(min(s.ttl, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cutoff := time.Now().Add(-s.ttl)
+			s.removeWhere(func(entry *storedResult) bool {
+				return entry.created.Before(cutoff)
+			})
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// removeWhere deletes the entries matching fn along with their files
+func (s *ResultStore) removeWhere(fn func(*storedResult) bool) {
+	s.mu.Lock()
+	var removed []*storedResult
+	for handle, entry := range s.entries {
+		if fn(entry) {
+			removed = append(removed, entry)
+			delete(s.entries, handle)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, entry := range removed {
+		if err := os.Remove(entry.path); err != nil && !os.IsNotExist(err) {
+			s.logger.WithError(err).WithField("path", entry.path).Warn("Failed to remove stored result")
+		}
+	}
+}
+
+// outputBuffer captures process output in memory up to a limit. Past the
+// limit, output continues into a ResultStore file, or is dropped if there is
+// no store; either way memory use stays bounded.
+type outputBuffer struct {
+	limit   int64
+	store   *ResultStore
+	session string
+
+	mem  bytes.Buffer
+	file *os.File
+	size int64
+	err  error
+}
+
+// Write implements io.Writer
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.size += int64(len(p))
+
+	if b.file == nil {
+		if b.limit <= 0 || int64(b.mem.Len()+len(p)) <= b.limit {
+			return b.mem.Write(p)
+		}
+		if b.store != nil && b.err == nil {
+			if err := b.spill(); err != nil {
+				b.err = err
+			}
+		}
+		if b.file == nil {
+			// Nowhere to spill to: keep what fits and drop the rest
+			if room := b.limit - int64(b.mem.Len()); room > 0 {
+				b.mem.Write(p[:room])
+			}
+			return len(p), nil
+		}
+	}
+
+	// Top up the in-memory preview before it is frozen
+	if room := b.limit - int64(b.mem.Len()); room > 0 {
+		b.mem.Write(p[:min(room, int64(len(p)))])
+	}
+
+	if _, err := b.file.Write(p); err != nil {
+		b.err = err
+	}
+	return len(p), nil
+}
+
+// spill moves the in-memory output into a new store file
+func (b *outputBuffer) spill() error {
+	f, err := b.store.create()
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b.mem.Bytes()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	b.file = f
+	return nil
+}
+
+// truncated reports whether the output did not fit in memory
+func (b *outputBuffer) truncated() bool {
+	return b.limit > 0 && b.size > b.limit
+}
+
+// finish completes capture and returns the in-memory output and, if the
+// output was spilled, the handle of the stored copy
+func (b *outputBuffer) finish() (string, string, error) {
+	output := b.mem.String()
+	if b.truncated() {
+		output = strings.ToValidUTF8(output, "")
+	}
+
+	if b.file == nil {
+		return output, "", b.err
+	}
+
+	path := b.file.Name()
+	if err := b.file.Close(); err != nil && b.err == nil {
+		b.err = err
+	}
+	if b.err != nil {
+		os.Remove(path)
+		return output, "", b.err
+	}
+
+	handle, err := b.store.add(path, b.size, b.session)
+	return output, handle, err
+}
+
+// discard drops any spilled output without registering it
+func (b *outputBuffer) discard() {
+	if b.file != nil {
+		b.file.Close()
+		os.Remove(b.file.Name())
+		b.file = nil
+	}
+}
@@ -0,0 +1,174 @@
+package sqlpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *ResultStore {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	store, err := NewResultStore(filepath.Join(t.TempDir(), "results"), time.Hour, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestOutputBuffer_WithinLimit(t *testing.T) {
+	buf := &outputBuffer{limit: 100, store: newTestStore(t)}
+	buf.Write([]byte("hello\n"))
+
+	output, handle, err := buf.finish()
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", output)
+	assert.Empty(t, handle)
+	assert.False(t, buf.truncated())
+}
+
+func TestOutputBuffer_SpillsToStore(t *testing.T) {
+	store := newTestStore(t)
+	buf := &outputBuffer{limit: 10, store: store, session: "session-1"}
+	buf.Write([]byte("line 1\n"))
+	buf.Write([]byte("line 2\n"))
+	buf.Write([]byte("line 3\n"))
+
+	output, handle, err := buf.finish()
+	require.NoError(t, err)
+	assert.True(t, buf.truncated())
+	assert.Equal(t, "line 1\nlin", output)
+	require.NotEmpty(t, handle)
+
+	page, err := store.Read("session-1", handle, 0, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2\nline 3\n", page.Data)
+	assert.True(t, page.EOF)
+	assert.Equal(t, int64(21), page.TotalSize)
+}
+
+func TestOutputBuffer_DropsWithoutStore(t *testing.T) {
+	buf := &outputBuffer{limit: 4}
+	buf.Write([]byte("abcdefgh"))
+
+	output, handle, err := buf.finish()
+	require.NoError(t, err)
+	assert.Equal(t, "abcd", output)
+	assert.Empty(t, handle)
+	assert.True(t, buf.truncated())
+	assert.Equal(t, int64(8), buf.size)
+}
+
+func TestResultStore_ReadPagesByLine(t *testing.T) {
+	store := newTestStore(t)
+	buf := &outputBuffer{limit: 1, store: store}
+	buf.Write([]byte("aaaa\nbbbb\ncccc\n"))
+	_, handle, err := buf.finish()
+	require.NoError(t, err)
+
+	page, err := store.Read("", handle, 0, 8)
+	require.NoError(t, err)
+	assert.Equal(t, "aaaa\n", page.Data)
+	assert.False(t, page.EOF)
+	assert.Equal(t, int64(5), page.NextOffset)
+
+	page, err = store.Read("", handle, page.NextOffset, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "bbbb\ncccc\n", page.Data)
+	assert.True(t, page.EOF)
+
+	_, err = store.Read("", handle, 100, 10)
+	assert.Error(t, err)
+
+	all, err := store.ReadAll("", handle, 15)
+	require.NoError(t, err)
+	assert.Equal(t, "aaaa\nbbbb\ncccc\n", all)
+
+	_, err = store.ReadAll("", handle, 14)
+	assert.ErrorIs(t, err, ErrResultTooLarge)
+}
+
+func TestResultStore_ReadCutsOnCharacters(t *testing.T) {
+	store := newTestStore(t)
+	buf := &outputBuffer{limit: 1, store: store}
+	buf.Write([]byte("Zürich München"))
+	_, handle, err := buf.finish()
+	require.NoError(t, err)
+
+	// "ü" takes two bytes; a page ending between them stops before it
+	page, err := store.Read("", handle, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Z", page.Data)
+
+	page, err = store.Read("", handle, page.NextOffset, 9)
+	require.NoError(t, err)
+	assert.Equal(t, "ürich M", page.Data)
+
+	// A page too small for one character still moves forward
+	page, err = store.Read("", handle, page.NextOffset, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.NextOffset-page.Offset)
+}
+
+func TestResultStore_ReleaseSession(t *testing.T) {
+	store := newTestStore(t)
+	buf := &outputBuffer{limit: 1, store: store, session: "session-1"}
+	buf.Write([]byte("data"))
+	_, handle, err := buf.finish()
+	require.NoError(t, err)
+
+	// Other sessions cannot read it
+	_, err = store.Read("session-2", handle, 0, 10)
+	assert.ErrorIs(t, err, ErrResultNotFound)
+	_, err = store.ReadAll("session-2", handle, 100)
+	assert.ErrorIs(t, err, ErrResultNotFound)
+
+	store.ReleaseSession("session-1")
+
+	_, err = store.Read("session-1", handle, 0, 10)
+	assert.ErrorIs(t, err, ErrResultNotFound)
+
+	entries, err := os.ReadDir(store.dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestExecuteSQLCommand_OutputLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+cat > /dev/null
+for i in $(seq 1 100); do echo "row $i"; done
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	store := newTestStore(t)
+	executor := NewExecutor(mockSqlpp, 30, logger)
+	executor.SetOutputLimit(64, store)
+
+	ctx := WithSessionID(context.Background(), "session-1")
+	result, err := executor.ExecuteSQLCommand(ctx, "test-conn", "SELECT * FROM big", "table")
+	require.NoError(t, err)
+
+	assert.True(t, result.Success)
+	assert.True(t, result.Truncated)
+	assert.LessOrEqual(t, len(result.Output), 64)
+	assert.True(t, strings.HasPrefix(result.Output, "row 1\nrow 2"))
+	require.NotEmpty(t, result.ResultHandle)
+
+	page, err := store.Read("session-1", result.ResultHandle, 0, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, result.OutputSize, page.TotalSize)
+	assert.Contains(t, page.Data, "row 100\n")
+}
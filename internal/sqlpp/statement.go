@@ -0,0 +1,337 @@
+package sqlpp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the kind of a SQL token
+type tokenKind int
+
+const (
+	tokenWord      tokenKind = iota // keyword, identifier or number
+	tokenString                     // single-quoted or dollar-quoted string literal
+	tokenQuoted                     // quoted identifier: "x", `x` or [x]
+	tokenSymbol                     // any other single character
+	tokenDirective                  // sqlpp preprocessor line such as #include
+)
+
+// sqlToken is a token of SQL text. Whitespace and comments are not tokens.
+type sqlToken struct {
+	kind  tokenKind
+	text  string
+	start int
+	end   int
+}
+
+// upper returns the token text in upper case, for keyword comparisons
+func (t sqlToken) upper() string {
+	return strings.ToUpper(t.text)
+}
+
+// lexSQL splits SQL into tokens, skipping whitespace and comments. It is not
+// a full SQL parser: it only understands enough syntax to tell keywords apart
+// from literals, identifiers and comments across the common dialects.
+func lexSQL(sql string) []sqlToken {
+	var tokens []sqlToken
+	lineStart := true
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '\n':
+			lineStart = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '#' && lineStart:
+			end := indexFrom(sql, i, "\n")
+			tokens = append(tokens, sqlToken{kind: tokenDirective, text: strings.TrimSpace(sql[i:end]), start: i, end: end})
+			i = end
+			continue
+		case strings.HasPrefix(sql[i:], "--"):
+			i = indexFrom(sql, i, "\n")
+			continue
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := indexFrom(sql, i+2, "*/")
+			i = min(end+2, len(sql))
+			continue
+		}
+
+		lineStart = false
+		start := i
+
+		switch {
+		case c == '\'':
+			i = scanQuoted(sql, i, '\'')
+			tokens = append(tokens, sqlToken{kind: tokenString, text: sql[start:i], start: start, end: i})
+		case c == '"' || c == '`':
+			i = scanQuoted(sql, i, c)
+			tokens = append(tokens, sqlToken{kind: tokenQuoted, text: sql[start:i], start: start, end: i})
+		case c == '[':
+			i = min(indexFrom(sql, i, "]")+1, len(sql))
+			tokens = append(tokens, sqlToken{kind: tokenQuoted, text: sql[start:i], start: start, end: i})
+		case c == '$' && dollarTag(sql[i:]) != "":
+			tag := dollarTag(sql[i:])
+			end := indexFrom(sql, i+len(tag), tag)
+			i = min(end+len(tag), len(sql))
+			tokens = append(tokens, sqlToken{kind: tokenString, text: sql[start:i], start: start, end: i})
+		case isWordChar(rune(c)) || c >= 0x80:
+			for i < len(sql) && (isWordChar(rune(sql[i])) || sql[i] >= 0x80) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: tokenWord, text: sql[start:i], start: start, end: i})
+		default:
+			i++
+			tokens = append(tokens, sqlToken{kind: tokenSymbol, text: sql[start:i], start: start, end: i})
+		}
+	}
+
+	return tokens
+}
+
+// indexFrom returns the index of substr in s at or after from, or len(s)
+func indexFrom(s string, from int, substr string) int {
+	if idx := strings.Index(s[from:], substr); idx >= 0 {
+		return from + idx
+	}
+	return len(s)
+}
+
+// scanQuoted returns the index just past the quoted section starting at i.
+// A doubled quote character is an escaped quote.
+func scanQuoted(s string, i int, quote byte) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != quote {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(s)
+}
+
+// dollarTag returns the PostgreSQL dollar-quote tag ($$ or $name$) that s
+// starts with, or an empty string
+func dollarTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		if s[j] == '$' {
+			return s[:j+1]
+		}
+		if !isWordChar(rune(s[j])) || unicode.IsDigit(rune(s[j])) && j == 1 {
+			return ""
+		}
+	}
+	return ""
+}
+
+func isWordChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isBatchSeparator reports whether tok is a GO batch separator: the word GO
+// alone on its line, optionally followed by a repeat count
+func isBatchSeparator(sql string, tok sqlToken) bool {
+	if tok.kind != tokenWord || tok.upper() != "GO" {
+		return false
+	}
+
+	lineStart := strings.LastIndexByte(sql[:tok.start], '\n') + 1
+	if strings.TrimSpace(sql[lineStart:tok.start]) != "" {
+		return false
+	}
+
+	rest := sql[tok.end:indexFrom(sql, tok.end, "\n")]
+	if idx := strings.Index(rest, "--"); idx >= 0 {
+		rest = rest[:idx]
+	}
+	rest = strings.TrimSpace(rest)
+	return strings.TrimLeft(rest, "0123456789") == ""
+}
+
+// splitStatements groups tokens into statements, which end at a semicolon or
+// a GO batch separator. Separators are not included and empty statements are
+// dropped.
+func splitStatements(sql string, tokens []sqlToken) [][]sqlToken {
+	var statements [][]sqlToken
+	var current []sqlToken
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		separator := tok.kind == tokenSymbol && tok.text == ";"
+		if isBatchSeparator(sql, tok) {
+			separator = true
+			// Skip the optional repeat count
+			if i+1 < len(tokens) && tokens[i+1].kind == tokenWord && !strings.Contains(sql[tok.end:tokens[i+1].start], "\n") {
+				i++
+			}
+		}
+
+		if separator {
+			if len(current) > 0 {
+				statements = append(statements, current)
+			}
+			current = nil
+			continue
+		}
+		current = append(current, tok)
+	}
+
+	if len(current) > 0 {
+		statements = append(statements, current)
+	}
+	return statements
+}
+
+// readOnlyStarts are the leading keywords of statements that only read data
+var readOnlyStarts = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"EXPLAIN":  true,
+	"VALUES":   true,
+}
+
+// writeKeywords mark a statement as modifying data or state wherever they
+// appear, e.g. a data-modifying CTE or SELECT ... INTO
+var writeKeywords = map[string]bool{
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"UPSERT":   true,
+	"REPLACE":  true,
+	"INTO":     true,
+	"CREATE":   true,
+	"ALTER":    true,
+	"DROP":     true,
+	"TRUNCATE": true,
+	"RENAME":   true,
+	"GRANT":    true,
+	"REVOKE":   true,
+	"EXEC":     true,
+	"EXECUTE":  true,
+	"CALL":     true,
+	"COPY":     true,
+	"LOCK":     true,
+	"ANALYZE":  true,
+}
+
+// writeFunctions are built-in functions that change state even when called
+// from a SELECT, such as advancing a sequence or taking a lock. Other
+// functions, including user-defined ones, are assumed to only read.
+var writeFunctions = map[string]bool{
+	"NEXTVAL":               true, // PostgreSQL, and Oracle's seq.NEXTVAL
+	"SETVAL":                true,
+	"SET_CONFIG":            true,
+	"PG_ADVISORY_LOCK":      true,
+	"PG_ADVISORY_XACT_LOCK": true,
+	"PG_TRY_ADVISORY_LOCK":  true,
+	"PG_ADVISORY_UNLOCK":    true,
+	"PG_NOTIFY":             true,
+	"PG_CANCEL_BACKEND":     true,
+	"PG_TERMINATE_BACKEND":  true,
+	"PG_RELOAD_CONF":        true,
+	"LO_IMPORT":             true,
+	"LO_EXPORT":             true,
+	"LO_UNLINK":             true,
+	"DBLINK_EXEC":           true,
+	"GET_LOCK":              true, // MySQL
+	"RELEASE_LOCK":          true,
+	"RELEASE_ALL_LOCKS":     true,
+}
+
+// IsReadOnlySQL reports whether every statement in command only reads data.
+// It is deliberately conservative: anything it cannot recognise, including
+// sqlpp #include directives, counts as a write, as do calls to the built-in
+// functions in writeFunctions and SQL Server's NEXT VALUE FOR.
+func IsReadOnlySQL(command string) bool {
+	seen := false
+
+	for _, statement := range splitStatements(command, lexSQL(command)) {
+		var words []sqlToken
+		for _, tok := range statement {
+			if tok.kind == tokenDirective {
+				if strings.HasPrefix(strings.ToLower(tok.text), "#include") {
+					return false
+				}
+				continue
+			}
+			words = append(words, tok)
+		}
+		if len(words) == 0 {
+			continue
+		}
+
+		if words[0].kind != tokenWord || !readOnlyStarts[words[0].upper()] {
+			return false
+		}
+		for i, tok := range words {
+			if tok.kind != tokenWord {
+				continue
+			}
+			word := tok.upper()
+			if writeKeywords[word] || writeFunctions[word] {
+				return false
+			}
+			if word == "NEXT" && i+1 < len(words) && words[i+1].upper() == "VALUE" {
+				return false
+			}
+		}
+		seen = true
+	}
+
+	return seen
+}
+
+// ddlStarts are the leading keywords of statements that change the schema
+var ddlStarts = map[string]bool{
+	"CREATE":  true,
+	"ALTER":   true,
+	"DROP":    true,
+	"RENAME":  true,
+	"COMMENT": true,
+}
+
+// IsDDL reports whether any statement in command may change the schema,
+// including SELECT ... INTO, which creates a table. Like IsReadOnlySQL it errs
+// on the side of caution: sqlpp #include directives count as DDL, since the
+// included file is not visible here.
+func IsDDL(command string) bool {
+	for _, statement := range splitStatements(command, lexSQL(command)) {
+		var first string
+		for _, tok := range statement {
+			if tok.kind == tokenDirective {
+				if strings.HasPrefix(strings.ToLower(tok.text), "#include") {
+					return true
+				}
+				continue
+			}
+			if tok.kind != tokenWord {
+				if first == "" {
+					break
+				}
+				continue
+			}
+
+			word := tok.upper()
+			switch {
+			case first == "" && ddlStarts[word]:
+				return true
+			case first == "SELECT" && word == "INTO":
+				return true
+			case first == "":
+				first = word
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package sqlpp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReadOnlySQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected bool
+	}{
+		{"select", "SELECT * FROM users", true},
+		{"lowercase with semicolon", "select id from users;", true},
+		{"cte", "WITH recent AS (SELECT * FROM orders) SELECT * FROM recent", true},
+		{"multiple selects", "SELECT 1; SELECT 2", true},
+		{"go batches", "SELECT 1\nGO\nSELECT 2\nGO 2\n", true},
+		{"keyword in string", "SELECT * FROM logs WHERE message = 'DELETE FROM users'", true},
+		{"keyword in comment", "-- DROP TABLE users\nSELECT 1 /* INSERT */", true},
+		{"keyword as quoted identifier", `SELECT "update" FROM audit`, true},
+		{"define directive", "#define LIMIT 10\nSELECT * FROM users", true},
+		{"show", "SHOW TABLES", true},
+		{"insert", "INSERT INTO users VALUES (1)", false},
+		{"update", "UPDATE users SET name = 'x'", false},
+		{"select into", "SELECT * INTO backup FROM users", false},
+		{"data-modifying cte", "WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone", false},
+		{"select then delete", "SELECT 1; DELETE FROM users", false},
+		{"select then delete in batch", "SELECT 1\nGO\nDELETE FROM users", false},
+		{"unknown statement", "VACUUM", false},
+		{"explain analyze", "EXPLAIN ANALYZE SELECT 1", false},
+		{"include directive", "#include \"setup.sql\"\nSELECT 1", false},
+		{"procedure", "EXEC sp_who", false},
+		{"sequence", "SELECT nextval('orders_id_seq')", false},
+		{"oracle sequence", "SELECT orders_seq.NEXTVAL FROM dual", false},
+		{"sql server sequence", "SELECT NEXT VALUE FOR dbo.orders_seq", false},
+		{"advisory lock", "SELECT pg_advisory_lock(42)", false},
+		{"mysql lock", "SELECT GET_LOCK('job', 10)", false},
+		{"select for update", "SELECT * FROM jobs FOR UPDATE", false},
+		{"offset fetch next", "SELECT * FROM t ORDER BY id OFFSET 10 ROWS FETCH NEXT 10 ROWS ONLY", true},
+		{"read-only function", "SELECT lower(name), now() FROM users", true},
+		{"empty", "  -- nothing\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsReadOnlySQL(tt.command))
+		})
+	}
+}
+
+func TestIsDDL(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected bool
+	}{
+		{"create table", "CREATE TABLE t (id int)", true},
+		{"drop after select", "SELECT 1;\ndrop index ix_t", true},
+		{"alter in batch", "SELECT 1\nGO\nALTER TABLE t ADD c int", true},
+		{"comment on", "COMMENT ON TABLE t IS 'orders'", true},
+		{"select into", "SELECT * INTO backup FROM users", true},
+		{"include directive", "#include \"migrate.sql\"", true},
+		{"define directive", "#define T users\nCREATE VIEW v AS SELECT 1", true},
+		{"select", "SELECT * FROM users", false},
+		{"insert into", "INSERT INTO users SELECT * FROM staging", false},
+		{"keyword in string", "SELECT 'DROP TABLE users'", false},
+		{"keyword as column", "SELECT created FROM t WHERE drop_date IS NULL", false},
+		{"update", "UPDATE t SET create_time = now()", false},
+		{"empty", "-- CREATE TABLE t", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsDDL(tt.command))
+		})
+	}
+}
+
+func TestLexSQL(t *testing.T) {
+	tokens := lexSQL("SELECT 'it''s', \"a b\", [c d], $$x;y$$ -- tail\nFROM t")
+
+	var texts []string
+	for _, tok := range tokens {
+		texts = append(texts, tok.text)
+	}
+
+	assert.Equal(t, []string{"SELECT", "'it''s'", ",", `"a b"`, ",", "[c d]", ",", "$$x;y$$", "FROM", "t"}, texts)
+	assert.Equal(t, tokenString, tokens[1].kind)
+	assert.Equal(t, tokenQuoted, tokens[3].kind)
+	assert.Equal(t, tokenString, tokens[7].kind)
+}
@@ -0,0 +1,315 @@
+package sqlpp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// TableStats holds the size statistics of a table. Fields the database does
+// not keep in its catalog are left unset.
+type TableStats struct {
+	Schema       string `json:"schema,omitempty"`
+	Table        string `json:"table"`
+	RowEstimate  *int64 `json:"row_estimate,omitempty"`  // planner estimate from the last analyze
+	ExactRows    *int64 `json:"exact_rows,omitempty"`    // COUNT(*), when asked for
+	TotalBytes   *int64 `json:"total_bytes,omitempty"`   // table, indexes and overflow storage
+	IndexBytes   *int64 `json:"index_bytes,omitempty"`   // indexes only
+	LastAnalyzed string `json:"last_analyzed,omitempty"` // when statistics were last gathered
+	LastVacuumed string `json:"last_vacuumed,omitempty"` // Postgres only
+}
+
+// ParseTableNames reads the table names listed by @schema-tables. It takes
+// JSON rows with a table_name, name or table column, arrays of names, and
+// objects holding such arrays, as well as table output with one of those
+// columns. Names are returned once each, in listing order.
+func ParseTableNames(output string) ([]string, error) {
+	return ParseObjectNames(output, ObjectTable)
+}
+
+// ParseObjectNames reads the names of the objects of type objectType (table,
+// view, procedure or function) listed by the matching @schema command, as
+// ParseTableNames does for tables
+func ParseObjectNames(output, objectType string) ([]string, error) {
+	keys, ok := objectNameKeys[objectType]
+	if !ok {
+		return nil, fmt.Errorf("unknown object type %q", objectType)
+	}
+
+	var names []string
+	dec := json.NewDecoder(strings.NewReader(output))
+	for {
+		var value interface{}
+		err := dec.Decode(&value)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return objectNamesFromRecords(output, objectType, keys)
+		}
+		names = collectObjectNames(value, keys, names)
+	}
+	return uniqueNames(names), nil
+}
+
+// objectNameKeys are the columns that hold object names in listings, by
+// object type, in order of preference
+var objectNameKeys = map[string][]string{
+	ObjectTable:     {"table_name", "name", "table"},
+	ObjectView:      {"view_name", "table_name", "name", "view"},
+	ObjectProcedure: {"procedure_name", "routine_name", "name", "procedure"},
+	ObjectFunction:  {"function_name", "routine_name", "name", "function"},
+}
+
+func collectObjectNames(value interface{}, keys []string, names []string) []string {
+	switch v := value.(type) {
+	case string:
+		return append(names, v)
+	case []interface{}:
+		for _, item := range v {
+			names = collectObjectNames(item, keys, names)
+		}
+	case map[string]interface{}:
+		lower := lowerKeys(v)
+		for _, key := range keys {
+			if name, ok := lower[key].(string); ok {
+				return append(names, name)
+			}
+		}
+		// Not a row, so look for lists of objects inside it
+		sorted := make([]string, 0, len(v))
+		for key := range v {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			switch v[key].(type) {
+			case []interface{}, map[string]interface{}:
+				names = collectObjectNames(v[key], keys, names)
+			}
+		}
+	}
+	return names
+}
+
+func objectNamesFromRecords(output, objectType string, keys []string) ([]string, error) {
+	records, err := parseRecords(output)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, record := range records {
+		for _, key := range keys {
+			if name := record[key]; name != "" {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	if len(records) > 0 && len(names) == 0 {
+		return nil, fmt.Errorf("no %s name column in listing", objectType)
+	}
+	return uniqueNames(names), nil
+}
+
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	unique := names[:0]
+	for _, name := range names {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// TableStatsQuery returns a catalog query for the statistics of tables in
+// schema, one row per table with the columns table_schema, table_name,
+// row_estimate, total_bytes, index_bytes, last_analyzed and last_vacuumed.
+// An empty schema means the connection's current schema. Names are passed
+// as string literals.
+func TableStatsQuery(dialect Dialect, schema string, tables []string) (string, error) {
+	if len(tables) == 0 {
+		return "", fmt.Errorf("no tables given")
+	}
+	if !hasCatalog(dialect) {
+		return "", fmt.Errorf("table statistics are not supported for %s", dialect)
+	}
+	literals := make([]string, len(tables))
+	for i, table := range tables {
+		literal, err := quoteString(dialect, table)
+		if err != nil {
+			return "", fmt.Errorf("invalid table name: %v", err)
+		}
+		literals[i] = literal
+	}
+	in := "(" + strings.Join(literals, ", ") + ")"
+
+	// SQLite takes the schema as a database name to qualify sqlite_master
+	if dialect == DialectSQLite {
+		master := "sqlite_master"
+		if schema != "" {
+			quoted, err := QuoteIdentifier(dialect, schema)
+			if err != nil {
+				return "", fmt.Errorf("invalid schema name: %v", err)
+			}
+			master = quoted + ".sqlite_master"
+		}
+		// SQLite keeps no sizes, and row counts only in the optional sqlite_stat1
+		return `SELECT name AS table_name
+FROM ` + master + `
+WHERE type = 'table' AND name IN ` + in + `
+ORDER BY name`, nil
+	}
+
+	sch, err := schemaExpression(dialect, schema)
+	if err != nil {
+		return "", err
+	}
+
+	switch dialect {
+	case DialectPostgres:
+		return `SELECT n.nspname AS table_schema, c.relname AS table_name, c.reltuples AS row_estimate,
+  pg_total_relation_size(c.oid) AS total_bytes, pg_indexes_size(c.oid) AS index_bytes,
+  GREATEST(s.last_analyze, s.last_autoanalyze) AS last_analyzed,
+  GREATEST(s.last_vacuum, s.last_autovacuum) AS last_vacuumed
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_stat_all_tables s ON s.relid = c.oid
+WHERE c.relkind IN ('r', 'p', 'm') AND n.nspname = ` + sch + ` AND c.relname IN ` + in + `
+ORDER BY c.relname`, nil
+	case DialectMySQL:
+		// MySQL does not record when statistics were gathered
+		return `SELECT table_schema AS table_schema, table_name AS table_name, table_rows AS row_estimate,
+  data_length + index_length AS total_bytes, index_length AS index_bytes
+FROM information_schema.tables
+WHERE table_type = 'BASE TABLE' AND table_schema = ` + sch + ` AND table_name IN ` + in + `
+ORDER BY table_name`, nil
+	case DialectSQLServer:
+		return `SELECT s.name AS table_schema, t.name AS table_name,
+  SUM(CASE WHEN p.index_id IN (0, 1) THEN p.row_count ELSE 0 END) AS row_estimate,
+  SUM(p.reserved_page_count) * 8192 AS total_bytes,
+  SUM(CASE WHEN p.index_id > 1 THEN p.reserved_page_count ELSE 0 END) * 8192 AS index_bytes,
+  (SELECT MAX(STATS_DATE(st.object_id, st.stats_id)) FROM sys.stats st WHERE st.object_id = t.object_id) AS last_analyzed
+FROM sys.tables t
+JOIN sys.schemas s ON s.schema_id = t.schema_id
+JOIN sys.dm_db_partition_stats p ON p.object_id = t.object_id
+WHERE s.name = ` + sch + ` AND t.name IN ` + in + `
+GROUP BY s.name, t.name, t.object_id
+ORDER BY t.name`, nil
+	case DialectOracle:
+		// Segment sizes need DBA views, so only the optimizer statistics are read
+		return `SELECT owner AS table_schema, table_name AS table_name, num_rows AS row_estimate, last_analyzed AS last_analyzed
+FROM all_tables
+WHERE owner = ` + sch + ` AND table_name IN ` + in + `
+ORDER BY table_name`, nil
+	default:
+		return "", fmt.Errorf("table statistics are not supported for %s", dialect)
+	}
+}
+
+// ParseTableStats reads the output of a TableStatsQuery
+func ParseTableStats(output string) ([]TableStats, error) {
+	rows, err := resultRows(output)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]TableStats, 0, len(rows))
+	for _, row := range rows {
+		lower := lowerKeys(row)
+		stat := TableStats{
+			Schema:       stringValue(lower["table_schema"]),
+			Table:        stringValue(lower["table_name"]),
+			RowEstimate:  optionalCount(lower["row_estimate"]),
+			TotalBytes:   optionalCount(lower["total_bytes"]),
+			IndexBytes:   optionalCount(lower["index_bytes"]),
+			LastAnalyzed: stringValue(lower["last_analyzed"]),
+			LastVacuumed: stringValue(lower["last_vacuumed"]),
+		}
+		if stat.Table != "" {
+			stats = append(stats, stat)
+		}
+	}
+	return stats, nil
+}
+
+// lowerKeys returns row with its column names in lower case, as Oracle and
+// SQL Server may return them in upper case
+func lowerKeys(row map[string]interface{}) map[string]interface{} {
+	lower := make(map[string]interface{}, len(row))
+	for key, value := range row {
+		lower[strings.ToLower(key)] = value
+	}
+	return lower
+}
+
+// optionalCount reads a count that may be NULL. Negative values, which
+// Postgres reports for tables that were never analysed, read as unknown.
+func optionalCount(value interface{}) *int64 {
+	if value == nil {
+		return nil
+	}
+	n := numberValue(value)
+	if n < 0 {
+		return nil
+	}
+	count := int64(math.Round(n))
+	return &count
+}
+
+// ExactCountQuery returns a query counting the rows of each table exactly,
+// one row per table with the columns table_name and row_count. Every table
+// is read in full.
+func ExactCountQuery(dialect Dialect, schema string, tables []string) (string, error) {
+	if len(tables) == 0 {
+		return "", fmt.Errorf("no tables given")
+	}
+	count := "COUNT(*)"
+	if dialect == DialectSQLServer {
+		count = "COUNT_BIG(*)"
+	}
+
+	selects := make([]string, len(tables))
+	for i, table := range tables {
+		literal, err := quoteString(dialect, table)
+		if err != nil {
+			return "", fmt.Errorf("invalid table name: %v", err)
+		}
+		from, err := QuoteIdentifier(dialect, table)
+		if err != nil {
+			return "", fmt.Errorf("invalid table name: %v", err)
+		}
+		if schema != "" {
+			quoted, err := QuoteIdentifier(dialect, schema)
+			if err != nil {
+				return "", fmt.Errorf("invalid schema name: %v", err)
+			}
+			from = quoted + "." + from
+		}
+		selects[i] = "SELECT " + literal + " AS table_name, " + count + " AS row_count FROM " + from
+	}
+	return strings.Join(selects, "\nUNION ALL\n"), nil
+}
+
+// ParseExactCounts reads the output of an ExactCountQuery into row counts
+// by table name
+func ParseExactCounts(output string) (map[string]int64, error) {
+	rows, err := resultRows(output)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		lower := lowerKeys(row)
+		if count := optionalCount(lower["row_count"]); count != nil {
+			counts[stringValue(lower["table_name"])] = *count
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,100 @@
+package sqlpp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTableNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{"rows", `[{"TABLE_SCHEMA": "public", "TABLE_NAME": "orders"}, {"TABLE_SCHEMA": "public", "TABLE_NAME": "users"}]`, []string{"orders", "users"}},
+		{"name column", `[{"name": "orders", "type": "table"}]`, []string{"orders"}},
+		{"nested list", `{"tables": ["orders", "users", "orders"]}`, []string{"orders", "users"}},
+		{"result sets", "[{\"table\": \"a\"}]\n[{\"table\": \"b\"}]", []string{"a", "b"}},
+		{"table output", "+------------+\n| table_name |\n+------------+\n| orders     |\n+------------+", []string{"orders"}},
+		{"empty", "[]", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := ParseTableNames(tt.output)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+
+	_, err := ParseTableNames("kind\n----\nview")
+	assert.ErrorContains(t, err, "no table name column")
+}
+
+func TestParseObjectNames(t *testing.T) {
+	views, err := ParseObjectNames(`[{"table_schema": "public", "table_name": "recent_orders"}]`, ObjectView)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"recent_orders"}, views)
+
+	functions, err := ParseObjectNames(`[{"specific_name": "order_total_1", "routine_name": "order_total"}, {"specific_name": "order_total_2", "routine_name": "order_total"}]`, ObjectFunction)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order_total"}, functions, "overloads are listed once")
+
+	_, err = ParseObjectNames("kind\n----\nx", ObjectProcedure)
+	assert.ErrorContains(t, err, "no procedure name column")
+
+	_, err = ParseObjectNames("[]", "trigger")
+	assert.ErrorContains(t, err, `unknown object type "trigger"`)
+}
+
+func TestTableStatsQuery(t *testing.T) {
+	for _, dialect := range []Dialect{DialectPostgres, DialectMySQL, DialectSQLite, DialectSQLServer, DialectOracle} {
+		query, err := TableStatsQuery(dialect, "", []string{"orders", "o'clock"})
+		require.NoError(t, err, dialect)
+		assert.Contains(t, query, "'orders', ", dialect)
+		assert.Contains(t, query, "'o''clock')", dialect)
+		assert.True(t, IsReadOnlySQL(query), dialect)
+	}
+
+	query, err := TableStatsQuery(DialectSQLite, "archive", []string{"orders"})
+	require.NoError(t, err)
+	assert.Contains(t, query, `FROM "archive".sqlite_master`)
+
+	_, err = TableStatsQuery(DialectPostgres, "", nil)
+	assert.ErrorContains(t, err, "no tables given")
+
+	_, err = TableStatsQuery(Dialect("acme"), "", []string{"orders"})
+	assert.ErrorContains(t, err, "table statistics are not supported for acme")
+}
+
+func TestParseTableStats(t *testing.T) {
+	stats, err := ParseTableStats(`[
+		{"TABLE_SCHEMA": "dbo", "TABLE_NAME": "orders", "ROW_ESTIMATE": "1250000", "TOTAL_BYTES": 335544320, "INDEX_BYTES": 52428800, "LAST_ANALYZED": "2024-05-01 10:00:00"},
+		{"table_name": "lines", "row_estimate": null}
+	]`)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+
+	assert.Equal(t, "dbo", stats[0].Schema)
+	assert.Equal(t, int64(1250000), *stats[0].RowEstimate)
+	assert.Equal(t, int64(52428800), *stats[0].IndexBytes)
+	assert.Equal(t, "2024-05-01 10:00:00", stats[0].LastAnalyzed)
+
+	assert.Equal(t, TableStats{Table: "lines"}, stats[1])
+}
+
+func TestExactCountQuery(t *testing.T) {
+	query, err := ExactCountQuery(DialectPostgres, "sales", []string{"orders", "Order Lines"})
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT 'orders' AS table_name, COUNT(*) AS row_count FROM "sales"."orders"`+"\nUNION ALL\n"+
+		`SELECT 'Order Lines' AS table_name, COUNT(*) AS row_count FROM "sales"."Order Lines"`, query)
+
+	query, err = ExactCountQuery(DialectSQLServer, "", []string{"orders"})
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT N'orders' AS table_name, COUNT_BIG(*) AS row_count FROM [orders]", query)
+
+	counts, err := ParseExactCounts(`[{"table_name": "orders", "row_count": 42}, {"TABLE_NAME": "lines", "ROW_COUNT": "7"}]`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"orders": 42, "lines": 7}, counts)
+}
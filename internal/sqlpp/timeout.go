@@ -0,0 +1,87 @@
+package sqlpp
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type callTimeoutKey struct{}
+
+type timeoutLimitKey struct{}
+
+// WithCallTimeout returns a context that asks for calls made with it to be
+// limited to d instead of the connection or server default. The executor caps
+// d at its configured maximum.
+func WithCallTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, callTimeoutKey{}, d)
+}
+
+// callTimeoutFrom returns the per-call timeout requested for the context, if any
+func callTimeoutFrom(ctx context.Context) time.Duration {
+	d, _ := ctx.Value(callTimeoutKey{}).(time.Duration)
+	return d
+}
+
+// timeoutLimit is the time limit applied to a run and the setting it came from
+type timeoutLimit struct {
+	duration time.Duration
+	source   string
+}
+
+// SetTimeouts configures per-connection default timeouts and the maximum a
+// per-call timeout may request. A zero duration from perConnection means the
+// connection uses the executor's default timeout; a zero max means no cap.
+func (e *Executor) SetTimeouts(perConnection func(connection string) time.Duration, max time.Duration) {
+	e.connectionTimeout = perConnection
+	e.maxTimeout = max
+}
+
+// timeoutFor picks the time limit for a run on connection: the per-call
+// timeout (capped at the maximum), else the connection default, else the
+// server default
+func (e *Executor) timeoutFor(ctx context.Context, connection string) timeoutLimit {
+	if d := callTimeoutFrom(ctx); d > 0 {
+		if e.maxTimeout > 0 && d > e.maxTimeout {
+			return timeoutLimit{duration: e.maxTimeout, source: "sqlpp.max_timeout"}
+		}
+		return timeoutLimit{duration: d, source: "timeout_seconds argument"}
+	}
+
+	if e.connectionTimeout != nil && connection != "" {
+		if d := e.connectionTimeout(connection); d > 0 {
+			return timeoutLimit{duration: d, source: fmt.Sprintf("timeout for connection %s", connection)}
+		}
+	}
+
+	return timeoutLimit{duration: e.timeout, source: "sqlpp.timeout"}
+}
+
+// withTimeout derives the execution context for a run on connection. The
+// applied limit is recorded in the context so a timeout can be reported
+// against the setting that caused it.
+func (e *Executor) withTimeout(ctx context.Context, connection string) (context.Context, context.CancelFunc) {
+	// Already limited, e.g. a pooled call falling back to a new process
+	if _, ok := ctx.Value(timeoutLimitKey{}).(timeoutLimit); ok {
+		return context.WithCancel(ctx)
+	}
+
+	limit := e.timeoutFor(ctx, connection)
+
+	// A caller deadline that falls first is the limit that will fire
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < limit.duration {
+		limit = timeoutLimit{duration: time.Until(deadline).Round(time.Millisecond), source: "caller deadline"}
+	}
+
+	ctx = context.WithValue(ctx, timeoutLimitKey{}, limit)
+	return context.WithTimeout(ctx, limit.duration)
+}
+
+// timeoutMessage describes a timeout, naming the limit that was hit if known
+func timeoutMessage(ctx context.Context) string {
+	limit, ok := ctx.Value(timeoutLimitKey{}).(timeoutLimit)
+	if !ok {
+		return "sqlpp command timed out"
+	}
+	return fmt.Sprintf("sqlpp command timed out after %s (%s)", limit.duration, limit.source)
+}
@@ -0,0 +1,66 @@
+package sqlpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_TimeoutFor(t *testing.T) {
+	executor := NewExecutor("sqlpp", 300, logrus.New())
+	executor.SetTimeouts(func(connection string) time.Duration {
+		if connection == "warehouse" {
+			return 30 * time.Minute
+		}
+		return 0
+	}, time.Hour)
+
+	ctx := context.Background()
+
+	limit := executor.timeoutFor(ctx, "main")
+	assert.Equal(t, 300*time.Second, limit.duration)
+	assert.Equal(t, "sqlpp.timeout", limit.source)
+
+	limit = executor.timeoutFor(ctx, "warehouse")
+	assert.Equal(t, 30*time.Minute, limit.duration)
+	assert.Equal(t, "timeout for connection warehouse", limit.source)
+
+	limit = executor.timeoutFor(WithCallTimeout(ctx, 10*time.Second), "warehouse")
+	assert.Equal(t, 10*time.Second, limit.duration)
+	assert.Equal(t, "timeout_seconds argument", limit.source)
+
+	limit = executor.timeoutFor(WithCallTimeout(ctx, 2*time.Hour), "main")
+	assert.Equal(t, time.Hour, limit.duration)
+	assert.Equal(t, "sqlpp.max_timeout", limit.source)
+}
+
+func TestExecuteSQLCommand_CallTimeout(t *testing.T) {
+	// Create a mock sqlpp executable that hangs until killed
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+exec sleep 30
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	executor := NewExecutor(mockSqlpp, 30, logger)
+
+	ctx := WithCallTimeout(context.Background(), 200*time.Millisecond)
+	result, err := executor.ExecuteSQLCommand(ctx, "main", "SELECT 1", "")
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	assert.False(t, result.Success)
+	assert.Equal(t, "sqlpp command timed out after 200ms (timeout_seconds argument)", result.Error)
+	assert.Equal(t, string(CodeTimeout), result.ErrorCode)
+}
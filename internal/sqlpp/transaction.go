@@ -0,0 +1,433 @@
+package sqlpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// transactionEndTimeout bounds the rollback sent when a transaction is ended
+// by the server rather than by a tool call
+const transactionEndTimeout = 30 * time.Second
+
+var (
+	// ErrNoTransaction is returned when a session has no transaction open on
+	// a connection
+	ErrNoTransaction = errors.New("no open transaction")
+
+	// ErrTransactionOpen is returned when beginning a transaction on a
+	// connection that already has one in the session
+	ErrTransactionOpen = errors.New("a transaction is already open")
+
+	// ErrTransactionAborted is returned for a transaction the server rolled
+	// back, until the session acknowledges it with Rollback
+	ErrTransactionAborted = errors.New("transaction was rolled back")
+)
+
+// TransactionOptions configures a TransactionManager
+type TransactionOptions struct {
+	IdleTimeout       time.Duration // unused time after which a transaction is rolled back (0 = never)
+	Delimiter         string        // batch delimiter line understood by sqlpp
+	BeginStatement    string
+	CommitStatement   string
+	RollbackStatement string
+}
+
+// Transaction describes a transaction open in an MCP session
+type Transaction struct {
+	ID         string    `json:"id"`
+	Session    string    `json:"session,omitempty"`
+	Connection string    `json:"connection"`
+	Output     string    `json:"output,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	LastUsed   time.Time `json:"last_used"`
+	Statements int       `json:"statements"` // statements run after BEGIN
+}
+
+// transactionKey identifies the transaction of a session on a connection
+type transactionKey struct {
+	session    string
+	connection string
+}
+
+// SlotFunc waits for an execution slot for connection and returns the
+// function that frees it
+type SlotFunc func(ctx context.Context, connection string) (func(), error)
+
+// transaction is an open transaction and the sqlpp process holding it
+type transaction struct {
+	mu      sync.Mutex // serialises statements
+	info    Transaction
+	worker  *worker
+	release func() // frees the execution slot the process holds, if any
+	timer   *time.Timer
+	aborted string // why the server rolled it back; empty while open
+}
+
+// stop stops the transaction's sqlpp process and frees its execution slot
+func (t *transaction) stop() {
+	if t.worker != nil {
+		t.worker.close()
+	}
+	if t.release != nil {
+		t.release()
+		t.release = nil
+	}
+}
+
+// TransactionManager binds a dedicated long-lived sqlpp process to each
+// transaction, so statements from separate tool calls of one MCP session run
+// on the same database connection. A session has at most one transaction per
+// connection.
+//
+// When a statement fails, the call is cancelled or the transaction sits idle
+// too long, the process is stopped and the database rolls the transaction
+// back. The transaction then stays marked as aborted, failing further
+// statements, until the session acknowledges it with Rollback, so work meant
+// for the transaction never silently runs outside it.
+type TransactionManager struct {
+	executor *Executor
+	opts     TransactionOptions
+	slots    SlotFunc // nil = open transactions are not limited
+
+	mu     sync.Mutex
+	open   map[transactionKey]*transaction
+	nextID int
+	closed bool
+}
+
+// NewTransactionManager creates a transaction manager that starts sqlpp
+// processes with executor's settings and interceptors
+func NewTransactionManager(executor *Executor, opts TransactionOptions) *TransactionManager {
+	return &TransactionManager{
+		executor: executor,
+		opts:     opts,
+		open:     make(map[transactionKey]*transaction),
+	}
+}
+
+// SetSlots makes each open transaction hold an execution slot from slots for
+// as long as its sqlpp process runs, so transactions count against the same
+// concurrency limits as other sqlpp calls
+func (m *TransactionManager) SetSlots(slots SlotFunc) {
+	m.slots = slots
+}
+
+// IdleTimeout returns how long a transaction may go unused before it is
+// rolled back, or 0 if it never is
+func (m *TransactionManager) IdleTimeout() time.Duration {
+	return m.opts.IdleTimeout
+}
+
+// Begin starts a transaction for session on connection. Statements in the
+// transaction produce output in the given format. The returned result is that
+// of the begin statement; when it failed no transaction is open.
+func (m *TransactionManager) Begin(ctx context.Context, session, connection, output string) (*Transaction, *types.SqlppResult, error) {
+	key := transactionKey{session: session, connection: connection}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, nil, errors.New("transaction manager is closed")
+	}
+	if t, ok := m.open[key]; ok {
+		m.mu.Unlock()
+		return nil, nil, fmt.Errorf("%w on connection %s (%s)", ErrTransactionOpen, connection, t.info.ID)
+	}
+
+	// Hold the transaction while it starts so calls for it wait
+	m.nextID++
+	now := time.Now()
+	t := &transaction{info: Transaction{
+		ID:         fmt.Sprintf("tx-%d", m.nextID),
+		Session:    session,
+		Connection: connection,
+		Output:     output,
+		StartedAt:  now,
+		LastUsed:   now,
+	}}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m.open[key] = t
+	m.mu.Unlock()
+
+	if m.slots != nil {
+		release, err := m.slots(ctx, connection)
+		if err != nil {
+			t.aborted = "no execution slot was free"
+			m.remove(key, t)
+			return nil, nil, err
+		}
+		t.release = release
+	}
+
+	w, err := m.executor.startWorker(workerKey{session: session, connection: connection, output: output}, m.opts.Delimiter)
+	if err != nil {
+		t.stop()
+		t.aborted = "sqlpp could not be started"
+		m.remove(key, t)
+		return nil, nil, fmt.Errorf("failed to start sqlpp for transaction: %w", err)
+	}
+	t.worker = w
+
+	result, err := m.run(ctx, t, m.opts.BeginStatement)
+	if err != nil || !result.Success {
+		t.stop()
+		t.aborted = "the begin statement failed"
+		m.remove(key, t)
+		return nil, result, err
+	}
+
+	if m.opts.IdleTimeout > 0 {
+		t.timer = time.AfterFunc(m.opts.IdleTimeout, func() { m.expire(t) })
+	}
+
+	m.executor.logger.WithFields(logrus.Fields{
+		"transaction": t.info.ID,
+		"session":     session,
+		"connection":  connection,
+		"pid":         w.pid(),
+	}).Info("Transaction started")
+
+	info := t.info
+	return &info, result, nil
+}
+
+// Active reports whether session has a transaction on connection, including
+// one the server rolled back that has not been acknowledged yet
+func (m *TransactionManager) Active(session, connection string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.open[transactionKey{session: session, connection: connection}]
+	return ok
+}
+
+// Execute runs command in the session's transaction on connection. output
+// must be empty or the format the transaction was begun with. A failed
+// statement ends the transaction.
+func (m *TransactionManager) Execute(ctx context.Context, session, connection, command, output string) (*types.SqlppResult, error) {
+	t, err := m.lock(session, connection)
+	if err != nil {
+		return nil, err
+	}
+	defer t.mu.Unlock()
+
+	if output != "" && output != t.info.Output {
+		return nil, fmt.Errorf("transaction %s returns %s output; begin the transaction with the output format you need", t.info.ID, outputName(t.info.Output))
+	}
+
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+
+	result, err := m.run(ctx, t, command)
+	t.info.Statements++
+	t.info.LastUsed = time.Now()
+
+	if err != nil || !result.Success {
+		// The worker is gone, and the transaction with it
+		t.stop()
+		t.aborted = "a statement failed"
+		m.executor.logger.WithFields(logrus.Fields{
+			"transaction": t.info.ID,
+			"connection":  connection,
+		}).Warn("Transaction rolled back after a failed statement")
+
+		if err != nil {
+			return nil, fmt.Errorf("%w (transaction %s was rolled back)", err, t.info.ID)
+		}
+		result.Error = fmt.Sprintf("%s (transaction %s was rolled back)", result.Error, t.info.ID)
+		return result, nil
+	}
+
+	if t.timer != nil {
+		t.timer.Reset(m.opts.IdleTimeout)
+	}
+	return result, nil
+}
+
+// Commit commits the session's transaction on connection. The transaction is
+// over afterwards whether or not the commit statement succeeded.
+func (m *TransactionManager) Commit(ctx context.Context, session, connection string) (*Transaction, *types.SqlppResult, error) {
+	return m.end(ctx, session, connection, m.opts.CommitStatement, false)
+}
+
+// Rollback rolls back the session's transaction on connection. For a
+// transaction the server already rolled back it only clears the transaction
+// and returns a nil result.
+func (m *TransactionManager) Rollback(ctx context.Context, session, connection string) (*Transaction, *types.SqlppResult, error) {
+	return m.end(ctx, session, connection, m.opts.RollbackStatement, true)
+}
+
+// end runs statement in the transaction and stops its sqlpp process
+func (m *TransactionManager) end(ctx context.Context, session, connection, statement string, acknowledge bool) (*Transaction, *types.SqlppResult, error) {
+	key := transactionKey{session: session, connection: connection}
+
+	m.mu.Lock()
+	t, ok := m.open[key]
+	m.mu.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("%w on connection %s", ErrNoTransaction, connection)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	m.remove(key, t)
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	info := t.info
+
+	if t.aborted != "" {
+		if acknowledge {
+			return &info, nil, nil
+		}
+		return nil, nil, fmt.Errorf("%w: %s (%s)", ErrTransactionAborted, t.aborted, info.ID)
+	}
+
+	result, err := m.run(ctx, t, statement)
+	t.stop()
+
+	m.executor.logger.WithFields(logrus.Fields{
+		"transaction": info.ID,
+		"connection":  connection,
+		"statement":   statement,
+		"success":     err == nil && result.Success,
+	}).Info("Transaction ended")
+
+	return &info, result, err
+}
+
+// EndSession rolls back every transaction of session, e.g. when the client
+// disconnects
+func (m *TransactionManager) EndSession(session string) {
+	m.mu.Lock()
+	var keys []transactionKey
+	for key := range m.open {
+		if key.session == session {
+			keys = append(keys, key)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, key := range keys {
+		m.rollbackQuietly(key.session, key.connection, "session ended")
+	}
+}
+
+// Close rolls back all open transactions and refuses new ones
+func (m *TransactionManager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	var keys []transactionKey
+	for key := range m.open {
+		keys = append(keys, key)
+	}
+	m.mu.Unlock()
+
+	for _, key := range keys {
+		m.rollbackQuietly(key.session, key.connection, "server shutting down")
+	}
+	return nil
+}
+
+// rollbackQuietly ends a transaction nobody is waiting on, logging the outcome
+func (m *TransactionManager) rollbackQuietly(session, connection, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), transactionEndTimeout)
+	defer cancel()
+
+	info, result, err := m.Rollback(ctx, session, connection)
+	if info == nil {
+		return
+	}
+	fields := logrus.Fields{
+		"transaction": info.ID,
+		"connection":  connection,
+		"reason":      reason,
+	}
+	if err != nil {
+		m.executor.logger.WithFields(fields).WithError(err).Warn("Failed to roll back transaction")
+	} else if result != nil {
+		m.executor.logger.WithFields(fields).Warn("Rolled back open transaction")
+	}
+}
+
+// expire rolls back t when it has been idle for the idle timeout. The
+// transaction stays registered as aborted so its session learns about it.
+func (m *TransactionManager) expire(t *transaction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// A statement may have run between the timer firing and taking the lock
+	if t.aborted != "" || time.Since(t.info.LastUsed) < m.opts.IdleTimeout {
+		return
+	}
+	m.mu.Lock()
+	current := m.open[transactionKey{session: t.info.Session, connection: t.info.Connection}] == t
+	m.mu.Unlock()
+	if !current {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transactionEndTimeout)
+	defer cancel()
+	if result, err := m.run(ctx, t, m.opts.RollbackStatement); err != nil || !result.Success {
+		m.executor.logger.WithField("transaction", t.info.ID).Warn("Rollback of idle transaction failed, stopping its sqlpp process")
+	}
+	t.stop()
+	t.aborted = fmt.Sprintf("idle for more than %s", m.opts.IdleTimeout)
+
+	m.executor.logger.WithFields(logrus.Fields{
+		"transaction": t.info.ID,
+		"session":     t.info.Session,
+		"connection":  t.info.Connection,
+	}).Warn("Rolled back idle transaction")
+}
+
+// lock returns the session's open transaction on connection with its lock held
+func (m *TransactionManager) lock(session, connection string) (*transaction, error) {
+	m.mu.Lock()
+	t, ok := m.open[transactionKey{session: session, connection: connection}]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w on connection %s", ErrNoTransaction, connection)
+	}
+
+	t.mu.Lock()
+	if t.aborted != "" {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s; call rollback_transaction to clear %s before running more statements", ErrTransactionAborted, t.aborted, t.info.ID)
+	}
+	return t, nil
+}
+
+// remove forgets t if it is still the transaction registered under key
+func (m *TransactionManager) remove(key transactionKey, t *transaction) {
+	m.mu.Lock()
+	if m.open[key] == t {
+		delete(m.open, key)
+	}
+	m.mu.Unlock()
+}
+
+// run sends one statement to the transaction's sqlpp process through the
+// executor's interceptors
+func (m *TransactionManager) run(ctx context.Context, t *transaction, input string) (*types.SqlppResult, error) {
+	req := stdinRequest(OpSQL, input, t.info.Connection, t.info.Output)
+	return m.executor.invoke(ctx, req, func(ctx context.Context, req *Request) (*types.SqlppResult, error) {
+		return m.executor.runOnWorker(ctx, req, t.worker, m.opts.Delimiter)
+	})
+}
+
+// outputName describes an output format for messages
+func outputName(output string) string {
+	if output == "" {
+		return "sqlpp's default"
+	}
+	return output
+}
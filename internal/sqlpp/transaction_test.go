@@ -0,0 +1,153 @@
+package sqlpp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTransactions(t *testing.T, idle time.Duration) *TransactionManager {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	m := NewTransactionManager(NewExecutor(writePoolMock(t), 30, logger), TransactionOptions{
+		IdleTimeout:       idle,
+		Delimiter:         testDelimiter,
+		BeginStatement:    "BEGIN",
+		CommitStatement:   "COMMIT",
+		RollbackStatement: "ROLLBACK",
+	})
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestTransactionManager_Commit(t *testing.T) {
+	m := newTestTransactions(t, 0)
+	ctx := context.Background()
+
+	tx, result, err := m.Begin(ctx, "session-1", "main", "json")
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	assert.Equal(t, "tx-1", tx.ID)
+	assert.Contains(t, result.Output, "BEGIN")
+
+	// Statements run on the process that began the transaction
+	update, err := m.Execute(ctx, "session-1", "main", "UPDATE t SET x = 1", "")
+	require.NoError(t, err)
+	check, err := m.Execute(ctx, "session-1", "main", "SELECT x FROM t", "json")
+	require.NoError(t, err)
+	assert.Equal(t, pidOf(result.Output), pidOf(update.Output))
+	assert.Equal(t, pidOf(result.Output), pidOf(check.Output))
+
+	_, err = m.Execute(ctx, "session-1", "main", "SELECT 1", "csv")
+	assert.ErrorContains(t, err, "transaction tx-1 returns json output")
+
+	// Other sessions and connections are not affected
+	assert.False(t, m.Active("session-2", "main"))
+	assert.False(t, m.Active("session-1", "other"))
+	_, _, err = m.Begin(ctx, "session-1", "main", "")
+	assert.ErrorIs(t, err, ErrTransactionOpen)
+
+	tx, result, err = m.Commit(ctx, "session-1", "main")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Contains(t, result.Output, "COMMIT")
+	assert.Equal(t, 2, tx.Statements)
+
+	assert.False(t, m.Active("session-1", "main"))
+	_, err = m.Execute(ctx, "session-1", "main", "SELECT 1", "")
+	assert.ErrorIs(t, err, ErrNoTransaction)
+}
+
+func TestTransactionManager_FailedStatement(t *testing.T) {
+	m := newTestTransactions(t, 0)
+	ctx := context.Background()
+
+	_, _, err := m.Begin(ctx, "session-1", "main", "")
+	require.NoError(t, err)
+
+	result, err := m.Execute(ctx, "session-1", "main", "UPDATE t SET FAIL = 1", "")
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "transaction tx-1 was rolled back")
+
+	// Later statements must not run outside the transaction
+	_, err = m.Execute(ctx, "session-1", "main", "DELETE FROM t", "")
+	assert.ErrorIs(t, err, ErrTransactionAborted)
+	_, _, err = m.Commit(ctx, "session-1", "main")
+	assert.ErrorIs(t, err, ErrTransactionAborted)
+	assert.False(t, m.Active("session-1", "main"))
+}
+
+func TestTransactionManager_IdleTimeout(t *testing.T) {
+	m := newTestTransactions(t, 100*time.Millisecond)
+	ctx := context.Background()
+
+	_, _, err := m.Begin(ctx, "session-1", "main", "")
+	require.NoError(t, err)
+
+	// Use resets the idle timer
+	time.Sleep(60 * time.Millisecond)
+	_, err = m.Execute(ctx, "session-1", "main", "SELECT 1", "")
+	require.NoError(t, err)
+	time.Sleep(60 * time.Millisecond)
+	_, err = m.Execute(ctx, "session-1", "main", "SELECT 2", "")
+	require.NoError(t, err)
+
+	time.Sleep(300 * time.Millisecond)
+	_, err = m.Execute(ctx, "session-1", "main", "SELECT 3", "")
+	require.ErrorIs(t, err, ErrTransactionAborted)
+	assert.Contains(t, err.Error(), "idle for more than 100ms")
+
+	// Rolling back acknowledges the abort
+	tx, result, err := m.Rollback(ctx, "session-1", "main")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Equal(t, "tx-1", tx.ID)
+	assert.False(t, m.Active("session-1", "main"))
+}
+
+func TestTransactionManager_EndSession(t *testing.T) {
+	m := newTestTransactions(t, 0)
+	ctx := context.Background()
+
+	_, _, err := m.Begin(ctx, "session-1", "main", "")
+	require.NoError(t, err)
+	_, _, err = m.Begin(ctx, "session-1", "other", "")
+	require.NoError(t, err)
+	_, _, err = m.Begin(ctx, "session-2", "main", "")
+	require.NoError(t, err)
+
+	m.EndSession("session-1")
+	assert.False(t, m.Active("session-1", "main"))
+	assert.False(t, m.Active("session-1", "other"))
+	assert.True(t, m.Active("session-2", "main"))
+}
+
+func TestTransactionManager_Slots(t *testing.T) {
+	m := newTestTransactions(t, 0)
+	limiter := NewLimitedExecutor(nil, LimitOptions{MaxConcurrent: 1, QueueTimeout: 50 * time.Millisecond}, m.executor.logger)
+	m.SetSlots(limiter.Acquire)
+	ctx := context.Background()
+
+	// An open transaction holds its slot between statements
+	_, _, err := m.Begin(ctx, "session-1", "main", "")
+	require.NoError(t, err)
+	_, _, err = m.Begin(ctx, "session-2", "main", "")
+	assert.ErrorIs(t, err, ErrQueueTimeout)
+	assert.False(t, m.Active("session-2", "main"))
+
+	// Ending it, or a failed statement rolling it back, frees the slot
+	_, _, err = m.Commit(ctx, "session-1", "main")
+	require.NoError(t, err)
+	_, _, err = m.Begin(ctx, "session-2", "main", "")
+	require.NoError(t, err)
+	_, err = m.Execute(ctx, "session-2", "main", "UPDATE t SET FAIL = 1", "")
+	require.NoError(t, err)
+	_, _, err = m.Begin(ctx, "session-3", "main", "")
+	require.NoError(t, err)
+}
@@ -0,0 +1,223 @@
+package sqlpp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// detectTimeout bounds each probe of the sqlpp executable at startup
+const detectTimeout = 10 * time.Second
+
+var (
+	versionPattern = regexp.MustCompile(`v?(\d+)\.(\d+)(?:\.(\d+))?`)
+	flagPattern    = regexp.MustCompile(`(?:^|[\s,\[])(--[a-zA-Z][a-zA-Z0-9-]*)`)
+	commandPattern = regexp.MustCompile(`(?:^|[\s,\[])(@[a-zA-Z][a-zA-Z0-9-]*)`)
+)
+
+// Version is a sqlpp release version
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion reads the first MAJOR.MINOR[.PATCH] version found in s, so
+// both "1.4.2" and "sqlpp version v1.4.2 (linux/amd64)" are accepted
+func ParseVersion(s string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version number in %q", strings.TrimSpace(s))
+	}
+
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 as v is older than, equal to or newer than o
+func (v Version) Compare(o Version) int {
+	switch {
+	case v.Major != o.Major:
+		return compareInt(v.Major, o.Major)
+	case v.Minor != o.Minor:
+		return compareInt(v.Minor, o.Minor)
+	default:
+		return compareInt(v.Patch, o.Patch)
+	}
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Capabilities describes what the installed sqlpp supports, as reported by
+// its --version and --help output
+type Capabilities struct {
+	// Version is the detected version, or nil if sqlpp did not report one
+	Version *Version
+	// Flags are the long command-line flags listed by --help, e.g. --stdin
+	Flags []string
+	// Commands are the @ commands listed by --help, e.g. @schema-tables
+	Commands []string
+}
+
+// Supports reports whether sqlpp may support a flag (--name) or @ command
+// (@name). Help output that lists no flags or no @ commands at all says
+// nothing about them, and neither does no detection (nil capabilities, as in
+// replay mode), so those are assumed to be supported.
+func (c *Capabilities) Supports(feature string) bool {
+	if c == nil {
+		return true
+	}
+
+	list := c.list(feature)
+	if len(list) == 0 {
+		return true
+	}
+	return contains(list, feature)
+}
+
+// Lists reports whether the --help output positively lists a flag or @
+// command. Features that only work when sqlpp really has them, such as the
+// --delimiter worker protocol, are checked with Lists rather than Supports.
+func (c *Capabilities) Lists(feature string) bool {
+	if c == nil {
+		return false
+	}
+	return contains(c.list(feature), feature)
+}
+
+// list returns the detected flags or @ commands, whichever feature is
+func (c *Capabilities) list(feature string) []string {
+	if strings.HasPrefix(feature, "@") {
+		return c.Commands
+	}
+	return c.Flags
+}
+
+// contains reports whether the sorted list holds s
+func contains(list []string, s string) bool {
+	i := sort.SearchStrings(list, s)
+	return i < len(list) && list[i] == s
+}
+
+// VersionString returns the detected version, or "unknown"
+func (c *Capabilities) VersionString() string {
+	if c == nil || c.Version == nil {
+		return "unknown"
+	}
+	return c.Version.String()
+}
+
+// parseCapabilities builds capabilities from --version and --help output
+func parseCapabilities(versionOutput, helpOutput string) *Capabilities {
+	caps := &Capabilities{
+		Flags:    uniqueMatches(flagPattern, helpOutput),
+		Commands: uniqueMatches(commandPattern, helpOutput),
+	}
+	if v, err := ParseVersion(versionOutput); err == nil {
+		caps.Version = &v
+	}
+	return caps
+}
+
+// uniqueMatches returns the sorted, de-duplicated first submatches of re in s
+func uniqueMatches(re *regexp.Regexp, s string) []string {
+	seen := make(map[string]bool)
+	var matches []string
+	for _, m := range re.FindAllStringSubmatch(s, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			matches = append(matches, m[1])
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// SetMinVersion makes ValidateExecutable reject sqlpp releases older than min
+func (e *Executor) SetMinVersion(min Version) {
+	e.minVersion = &min
+}
+
+// Capabilities returns what ValidateExecutable detected, or nil if it has
+// not run
+func (e *Executor) Capabilities() *Capabilities {
+	return e.capabilities
+}
+
+// detectCapabilities probes sqlpp with --help, which must succeed, and
+// --version, which older releases may not support
+func (e *Executor) detectCapabilities(ctx context.Context) (*Capabilities, error) {
+	help, err := e.probe(ctx, "--help")
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := e.probe(ctx, "--version")
+	if err != nil {
+		e.logger.WithError(err).Debug("sqlpp --version failed")
+		version = ""
+	}
+
+	return parseCapabilities(version, help), nil
+}
+
+// probe runs sqlpp with a single argument and returns its combined output
+func (e *Executor) probe(ctx context.Context, arg string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, detectTimeout)
+	defer cancel()
+
+	// Probes run in the configured directory and environment but without
+	// --config, which is only checked against the help output afterwards
+	cmd := exec.CommandContext(ctx, e.executablePath, arg)
+	e.processOptionsFor("").apply(cmd)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderrStr := strings.TrimSpace(stderr.String()); stderrStr != "" {
+			return "", fmt.Errorf("sqlpp executable validation failed: %s", stderrStr)
+		}
+		return "", fmt.Errorf("sqlpp executable not found or not working: %w", err)
+	}
+
+	return stdout.String() + stderr.String(), nil
+}
+
+// checkMinVersion enforces the configured minimum version, if any
+func (e *Executor) checkMinVersion(caps *Capabilities) error {
+	if e.minVersion == nil {
+		return nil
+	}
+	if caps.Version == nil {
+		return fmt.Errorf("could not determine sqlpp version; %s or newer is required", e.minVersion)
+	}
+	if caps.Version.Compare(*e.minVersion) < 0 {
+		return fmt.Errorf("sqlpp %s is older than the required minimum version %s", caps.Version, e.minVersion)
+	}
+	return nil
+}
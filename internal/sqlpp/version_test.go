@@ -0,0 +1,150 @@
+package sqlpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Version
+		wantErr  bool
+	}{
+		{"1.4.2", Version{1, 4, 2}, false},
+		{"sqlpp version v2.0.11 (linux/amd64)", Version{2, 0, 11}, false},
+		{"1.5", Version{1, 5, 0}, false},
+		{"sqlpp dev build", Version{}, true},
+		{"", Version{}, true},
+	}
+
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.input)
+		if tt.wantErr {
+			assert.Error(t, err, tt.input)
+			continue
+		}
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, v)
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	assert.Equal(t, 0, Version{1, 4, 2}.Compare(Version{1, 4, 2}))
+	assert.Equal(t, -1, Version{1, 4, 2}.Compare(Version{1, 10, 0}))
+	assert.Equal(t, 1, Version{2, 0, 0}.Compare(Version{1, 99, 99}))
+	assert.Equal(t, -1, Version{1, 4, 1}.Compare(Version{1, 4, 2}))
+}
+
+func TestCapabilities_Supports(t *testing.T) {
+	caps := parseCapabilities("sqlpp 1.4.0", `Usage: sqlpp [options] [file]
+
+Options:
+  --stdin               Read commands from standard input
+  -c, --connection      Connection name
+  -o, --output          Output format (table, json, yaml, csv)
+
+Commands:
+  @drivers              List drivers
+  @schema-tables [filter]
+`)
+
+	require.NotNil(t, caps.Version)
+	assert.Equal(t, "1.4.0", caps.VersionString())
+	assert.Equal(t, []string{"--connection", "--output", "--stdin"}, caps.Flags)
+	assert.Equal(t, []string{"@drivers", "@schema-tables"}, caps.Commands)
+
+	assert.True(t, caps.Supports("--stdin"))
+	assert.False(t, caps.Supports("--list-connections"))
+	assert.True(t, caps.Supports("@schema-tables"))
+	assert.False(t, caps.Supports("@schema-views"))
+
+	assert.True(t, caps.Lists("--stdin"))
+	assert.False(t, caps.Lists("--delimiter"))
+
+	// Help output that lists nothing says nothing, but lists nothing either
+	bare := parseCapabilities("", "sqlpp help information")
+	assert.Nil(t, bare.Version)
+	assert.Equal(t, "unknown", bare.VersionString())
+	assert.True(t, bare.Supports("--delimiter"))
+	assert.True(t, bare.Supports("@schema-views"))
+	assert.False(t, bare.Lists("--delimiter"))
+
+	// No detection at all
+	var none *Capabilities
+	assert.True(t, none.Supports("@drivers"))
+	assert.False(t, none.Lists("--delimiter"))
+}
+
+func TestValidateExecutable_DetectsVersion(t *testing.T) {
+	// Create a mock sqlpp executable that reports its version
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+if [[ "$1" == "--version" ]]; then
+    echo "sqlpp version 1.4.2"
+    exit 0
+fi
+echo "Usage: sqlpp --stdin --connection NAME"
+echo "  @schema-tables"
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+
+	executor := NewExecutor(mockSqlpp, 30, logger)
+	executor.SetMinVersion(Version{1, 4, 0})
+	require.NoError(t, executor.ValidateExecutable(context.Background()))
+
+	caps := executor.Capabilities()
+	require.NotNil(t, caps)
+	assert.Equal(t, "1.4.2", caps.VersionString())
+	assert.True(t, caps.Supports("@schema-tables"))
+	assert.False(t, caps.Supports("@schema-views"))
+
+	executor = NewExecutor(mockSqlpp, 30, logger)
+	executor.SetMinVersion(Version{1, 5, 0})
+	err = executor.ValidateExecutable(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sqlpp 1.4.2 is older than the required minimum version 1.5.0")
+	assert.Nil(t, executor.Capabilities())
+}
+
+func TestValidateExecutable_UnknownVersion(t *testing.T) {
+	// Create a mock sqlpp executable without --version support
+	tmpDir := t.TempDir()
+	mockSqlpp := filepath.Join(tmpDir, "mock-sqlpp")
+
+	mockScript := `#!/bin/bash
+if [[ "$1" == "--version" ]]; then
+    echo "unknown flag: --version" >&2
+    exit 1
+fi
+echo "sqlpp help information"
+`
+
+	err := os.WriteFile(mockSqlpp, []byte(mockScript), 0755)
+	require.NoError(t, err)
+
+	logger := logrus.New()
+
+	// Without a minimum version the server still starts
+	executor := NewExecutor(mockSqlpp, 30, logger)
+	require.NoError(t, executor.ValidateExecutable(context.Background()))
+	assert.Nil(t, executor.Capabilities().Version)
+
+	executor = NewExecutor(mockSqlpp, 30, logger)
+	executor.SetMinVersion(Version{1, 0, 0})
+	err = executor.ValidateExecutable(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "could not determine sqlpp version")
+}
@@ -0,0 +1,185 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// Values of the on_error argument of execute_sql_command
+const (
+	onErrorStop     = "stop"
+	onErrorContinue = "continue"
+)
+
+// BatchError is returned when batches of a SQL command failed. It carries the
+// outcome of every batch so callers can report them.
+type BatchError struct {
+	Execution *types.BatchExecution
+	Report    string // text report of all batches
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	for _, batch := range e.Execution.Batches {
+		if batch.Status == types.BatchFailed {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d batches failed\n\n%s", failed, len(e.Execution.Batches), e.Report)
+}
+
+// getOnErrorArg reads the on_error argument, defaulting to stop
+func (h *ToolHandler) getOnErrorArg(arguments map[string]interface{}) (string, error) {
+	onError := h.getStringArg(arguments, "on_error", onErrorStop)
+	if onError != onErrorStop && onError != onErrorContinue {
+		return "", fmt.Errorf("on_error must be %q or %q", onErrorStop, onErrorContinue)
+	}
+	return onError, nil
+}
+
+// executeBatches runs batches one after another with run, each in its own
+// sqlpp invocation, so every batch gets its own duration and output and no
+// extra SQL is sent. Session state such as USE or temporary tables only
+// carries from one batch to the next inside a transaction, whose worker runs
+// them all. After a failure the remaining batches are skipped unless onError
+// is continue; a cancelled call skips them either way.
+func (h *ToolHandler) executeBatches(ctx context.Context, batches []sqlpp.Batch, onError string, run sqlRunFunc) (*ToolResult, error) {
+	execution := &types.BatchExecution{
+		Batches: make([]types.BatchResult, len(batches)),
+	}
+
+	var last, failed *types.SqlppResult
+	stop := false
+	for i, batch := range batches {
+		br := &execution.Batches[i]
+		br.Index = i + 1
+		br.Line = batch.Line
+
+		if stop || ctx.Err() != nil {
+			br.Status = types.BatchSkipped
+			continue
+		}
+
+		result, attempts := h.runBatch(ctx, batch, br, run)
+		execution.DurationMs += br.DurationMs
+		execution.Attempts += attempts
+		execution.OutputSize += br.OutputSize
+		execution.Truncated = execution.Truncated || br.Truncated
+		last = result
+
+		if br.Status == types.BatchFailed {
+			if failed == nil {
+				failed = result
+			}
+			stop = onError == onErrorStop
+		}
+	}
+
+	// Summarize with the first failed batch, or the last one run
+	summary := last
+	if failed != nil {
+		summary = failed
+	}
+	if summary != nil {
+		execution.ExitCode = summary.ExitCode
+		execution.Args = summary.Args
+		execution.Stderr = summary.Stderr
+		execution.Error = summary.Error
+		execution.ErrorCode = summary.ErrorCode
+	}
+	execution.Success = failed == nil
+
+	report := h.formatBatches(execution)
+	if failed != nil {
+		return nil, &BatchError{Execution: execution, Report: report}
+	}
+	return &ToolResult{Text: report, Structured: execution}, nil
+}
+
+// runBatch runs a batch as many times as its separator asks, stopping at the
+// first failed run, and fills in br. It returns the result of the last run
+// and the executions made across all runs, including retries.
+func (h *ToolHandler) runBatch(ctx context.Context, batch sqlpp.Batch, br *types.BatchResult, run sqlRunFunc) (*types.SqlppResult, int) {
+	br.Status = types.BatchSucceeded
+
+	var outputs []string
+	var result *types.SqlppResult
+	attempts := 0
+	for i := 0; i < batch.Repeat; i++ {
+		var err error
+		result, err = run(ctx, batch.SQL)
+		br.Runs++
+		if err != nil {
+			// sqlpp did not run to completion, e.g. the call was cancelled
+			result = &types.SqlppResult{
+				ExitCode:  -1,
+				Error:     err.Error(),
+				ErrorCode: string(sqlpp.ClassifyError(err)),
+			}
+		}
+
+		attempts += result.Attempts
+		br.DurationMs += result.DurationMs
+		br.ExitCode = result.ExitCode
+		br.Stderr = result.Stderr
+		br.Truncated = br.Truncated || result.Truncated
+		br.OutputSize += result.OutputSize
+		if result.ResultHandle != "" {
+			br.ResultHandle = result.ResultHandle
+		}
+		if result.Output != "" {
+			outputs = append(outputs, result.Output)
+		}
+
+		if err != nil || !result.Success {
+			br.Status = types.BatchFailed
+			br.Error = result.Error
+			br.ErrorCode = result.ErrorCode
+			break
+		}
+	}
+
+	br.Output = strings.Join(outputs, "\n")
+	return result, attempts
+}
+
+// formatBatches renders the outcome of each batch with its output
+func (h *ToolHandler) formatBatches(execution *types.BatchExecution) string {
+	var b strings.Builder
+	total := len(execution.Batches)
+	for i, batch := range execution.Batches {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+
+		fmt.Fprintf(&b, "-- batch %d of %d (line %d): %s", batch.Index, total, batch.Line, batch.Status)
+		if batch.Status == types.BatchSkipped {
+			continue
+		}
+		fmt.Fprintf(&b, " in %dms", batch.DurationMs)
+		if batch.Runs > 1 {
+			fmt.Fprintf(&b, " (%d runs)", batch.Runs)
+		}
+		if batch.Status == types.BatchFailed {
+			if batch.ErrorCode != "" {
+				fmt.Fprintf(&b, " [%s]", batch.ErrorCode)
+			}
+			fmt.Fprintf(&b, ": %s", batch.Error)
+		}
+
+		if batch.Output != "" {
+			b.WriteString("\n")
+			b.WriteString(h.formatSqlppResult(&types.SqlppResult{
+				Output:       batch.Output,
+				Truncated:    batch.Truncated,
+				OutputSize:   batch.OutputSize,
+				ResultHandle: batch.ResultHandle,
+			}))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,270 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// Describe table tool
+func (h *ToolHandler) createDescribeTableTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"connection": {
+				Type:        "string",
+				Description: "Database connection name to use",
+			},
+			"table": {
+				Type:        "string",
+				Description: "Table name, as stored in the catalog (e.g. upper case for unquoted Oracle names)",
+			},
+			"schema": {
+				Type:        "string",
+				Description: "Schema of the table (attached database for SQLite). Defaults to the connection's current schema.",
+			},
+			"timeout_seconds": timeoutSecondsSchema(),
+		},
+		Required: []string{"connection", "table"},
+	}
+	return Tool{
+		Name: "describe_table",
+		Description: "Describe one table: its columns with types, nullability and defaults, primary key, unique keys, foreign keys, indexes and check constraints. " +
+			"The catalog queries for the connection's database (SQLite, Postgres, MySQL, SQL Server or Oracle) are chosen automatically. Use it before writing queries against a table.",
+		InputSchema:  &schema,
+		OutputSchema: tableOutputSchema(),
+	}
+}
+
+// tableOutputSchema describes the structured content of describe_table,
+// matching sqlpp.TableDescription
+func tableOutputSchema() *jsonschema.Schema {
+	names := &jsonschema.Schema{Type: "array", Items: &jsonschema.Schema{Type: "string"}}
+	key := &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"name":    {Type: "string"},
+			"columns": names,
+		},
+		Required: []string{"columns"},
+	}
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"schema": {Type: "string"},
+			"table":  {Type: "string"},
+			"columns": {Type: "array", Items: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"name":     {Type: "string"},
+					"type":     {Type: "string"},
+					"nullable": {Type: "boolean"},
+					"default":  {Type: "string", Description: "Default expression; absent if there is none"},
+				},
+				Required: []string{"name", "type", "nullable"},
+			}},
+			"primary_key": key,
+			"unique_keys": {Type: "array", Items: key},
+			"foreign_keys": {Type: "array", Items: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"name":               {Type: "string"},
+					"columns":            names,
+					"referenced_schema":  {Type: "string"},
+					"referenced_table":   {Type: "string"},
+					"referenced_columns": names,
+					"on_delete":          {Type: "string"},
+					"on_update":          {Type: "string"},
+				},
+				Required: []string{"columns", "referenced_table", "referenced_columns"},
+			}},
+			"indexes": {Type: "array", Items: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"name":    {Type: "string"},
+					"columns": names,
+					"unique":  {Type: "boolean"},
+					"primary": {Type: "boolean"},
+				},
+				Required: []string{"name", "columns", "unique", "primary"},
+			}},
+			"check_constraints": {Type: "array", Items: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"name":       {Type: "string"},
+					"definition": {Type: "string"},
+				},
+				Required: []string{"definition"},
+			}},
+		},
+		Required: []string{"table", "columns"},
+	}
+}
+
+func (h *ToolHandler) executeDescribeTable(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	connection := h.getStringArg(arguments, "connection", "")
+	table := h.getStringArg(arguments, "table", "")
+	schemaName := h.getStringArg(arguments, "schema", "")
+
+	if connection == "" {
+		return nil, fmt.Errorf("connection parameter is required")
+	}
+
+	if table == "" {
+		return nil, fmt.Errorf("table parameter is required")
+	}
+
+	dialect, err := h.connectionDialect(ctx, connection)
+	if err != nil {
+		return nil, fmt.Errorf("cannot describe table: %w", err)
+	}
+	queries, err := sqlpp.DescribeTableQueries(dialect, schemaName, table)
+	if err != nil {
+		return nil, fmt.Errorf("cannot describe table: %w", err)
+	}
+
+	ctx, err = h.withCallTimeout(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs [3]string
+	for i, query := range []string{queries.Columns, queries.Constraints, queries.Indexes} {
+		outputs[i], err = h.catalogQuery(ctx, connection, query)
+		var execErr *ExecutionError
+		if i == 1 && queries.ConstraintsFallback != "" && errors.As(err, &execErr) {
+			h.logger.WithError(err).WithField("connection", connection).Debug("Constraint catalog query failed, retrying without check constraints")
+			outputs[i], err = h.catalogQuery(ctx, connection, queries.ConstraintsFallback)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error describing table: %w", err)
+		}
+	}
+
+	desc, err := sqlpp.BuildTableDescription(dialect, schemaName, table, outputs[0], outputs[1], outputs[2])
+	if err != nil {
+		return nil, fmt.Errorf("error describing table: %w", err)
+	}
+	return &ToolResult{Text: formatTableDescription(desc), Structured: desc}, nil
+}
+
+// catalogQuery runs a generated read-only query and returns its JSON output.
+// It runs outside any open transaction, so a failed catalog query cannot roll
+// the transaction back; it does not see the transaction's uncommitted changes.
+func (h *ToolHandler) catalogQuery(ctx context.Context, connection, query string) (string, error) {
+	// Catalog rows are always read as JSON, whatever the connection's default output
+	result, err := h.executor.ExecuteSQLCommand(ctx, connection, query, "json")
+	if err != nil {
+		return "", err
+	}
+	if !result.Success {
+		return "", &ExecutionError{Result: result}
+	}
+	return h.fullOutput(ctx, result)
+}
+
+// fullOutput returns all the output of result. Output past the in-memory
+// limit, such as the catalog of a large schema, is read back from the result
+// store, since generated queries need every row to be parsed, up to the
+// catalog read limit.
+func (h *ToolHandler) fullOutput(ctx context.Context, result *types.SqlppResult) (string, error) {
+	if !result.Truncated {
+		return result.Output, nil
+	}
+	if result.ResultHandle == "" || h.results == nil {
+		return "", fmt.Errorf("output is too large to read: %d bytes, and was not kept", result.OutputSize)
+	}
+	if result.OutputSize > h.maxCatalogBytes {
+		return "", fmt.Errorf("output is too large to read: %d bytes, more than sqlpp.max_catalog_bytes (%d); narrow the request, e.g. to one schema", result.OutputSize, h.maxCatalogBytes)
+	}
+	output, err := h.results.ReadAll(sqlpp.SessionIDFrom(ctx), result.ResultHandle, h.maxCatalogBytes)
+	if err != nil {
+		return "", fmt.Errorf("error reading %d bytes of output: %w", result.OutputSize, err)
+	}
+	return output, nil
+}
+
+// formatTableDescription renders a table description as text, one column,
+// key or index per line
+func formatTableDescription(desc *sqlpp.TableDescription) string {
+	var b strings.Builder
+	name := desc.Table
+	if desc.Schema != "" {
+		name = desc.Schema + "." + desc.Table
+	}
+	fmt.Fprintf(&b, "Table %s\n\nColumns:\n", name)
+	for _, column := range desc.Columns {
+		fmt.Fprintf(&b, "  %s %s", column.Name, column.Type)
+		if !column.Nullable {
+			b.WriteString(" NOT NULL")
+		}
+		if column.Default != nil {
+			b.WriteString(" DEFAULT " + *column.Default)
+		}
+		b.WriteString("\n")
+	}
+
+	if desc.PrimaryKey != nil {
+		fmt.Fprintf(&b, "\nPrimary key: %s\n", keyName(desc.PrimaryKey.Name, desc.PrimaryKey.Columns))
+	}
+	if len(desc.UniqueKeys) > 0 {
+		b.WriteString("\nUnique keys:\n")
+		for _, key := range desc.UniqueKeys {
+			fmt.Fprintf(&b, "  %s\n", keyName(key.Name, key.Columns))
+		}
+	}
+	if len(desc.ForeignKeys) > 0 {
+		b.WriteString("\nForeign keys:\n")
+		for _, fk := range desc.ForeignKeys {
+			referenced := fk.ReferencedTable
+			if fk.ReferencedSchema != "" {
+				referenced = fk.ReferencedSchema + "." + referenced
+			}
+			fmt.Fprintf(&b, "  %s -> %s (%s)", keyName(fk.Name, fk.Columns), referenced, strings.Join(fk.ReferencedColumns, ", "))
+			if fk.OnDelete != "" {
+				b.WriteString(" ON DELETE " + fk.OnDelete)
+			}
+			if fk.OnUpdate != "" {
+				b.WriteString(" ON UPDATE " + fk.OnUpdate)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(desc.Indexes) > 0 {
+		b.WriteString("\nIndexes:\n")
+		for _, index := range desc.Indexes {
+			b.WriteString("  " + keyName(index.Name, index.Columns))
+			if index.Primary {
+				b.WriteString(" PRIMARY")
+			} else if index.Unique {
+				b.WriteString(" UNIQUE")
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(desc.CheckConstraints) > 0 {
+		b.WriteString("\nCheck constraints:\n")
+		for _, check := range desc.CheckConstraints {
+			if check.Name != "" {
+				b.WriteString("  " + check.Name + ": " + check.Definition + "\n")
+			} else {
+				b.WriteString("  " + check.Definition + "\n")
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// keyName renders a named column list as "name (a, b)"
+func keyName(name string, columns []string) string {
+	list := "(" + strings.Join(columns, ", ") + ")"
+	if name == "" {
+		return list
+	}
+	return name + " " + list
+}
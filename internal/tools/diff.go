@@ -0,0 +1,245 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+)
+
+// Diff schema tool
+func (h *ToolHandler) createDiffSchemaTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"source": {
+				Type:        "string",
+				Description: "Connection with the schema to compare against, e.g. prod",
+			},
+			"target": {
+				Type:        "string",
+				Description: "Connection to compare with it, e.g. staging. Objects only in target are added, objects only in source are removed. May be the same as source when the schemas differ.",
+			},
+			"source_schema": {
+				Type:        "string",
+				Description: "Schema to read in source (attached database for SQLite). Defaults to the connection's current schema.",
+			},
+			"target_schema": {
+				Type:        "string",
+				Description: "Schema to read in target. Defaults to the connection's current schema.",
+			},
+			"timeout_seconds": timeoutSecondsSchema(),
+		},
+		Required: []string{"source", "target"},
+	}
+	return Tool{
+		Name: "diff_schema",
+		Description: "Compare the schemas of two connections, such as staging and prod: tables, views, procedures and functions by name, and the columns (type, nullability, default) and indexes of the tables and views in both. " +
+			"Returns the added, removed and changed objects. Use it to review schema changes before a release.",
+		InputSchema:  &schema,
+		OutputSchema: diffOutputSchema(),
+	}
+}
+
+// diffOutputSchema describes the structured content of diff_schema,
+// matching sqlpp.SchemaDiff
+func diffOutputSchema() *jsonschema.Schema {
+	changes := &jsonschema.Schema{Type: "array", Items: &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"type": {
+				Type: "string",
+				Enum: []any{sqlpp.ObjectTable, sqlpp.ObjectView, sqlpp.ObjectColumn, sqlpp.ObjectIndex, sqlpp.ObjectProcedure, sqlpp.ObjectFunction},
+			},
+			"table":  {Type: "string", Description: "Table or view of a column or index"},
+			"name":   {Type: "string"},
+			"detail": {Type: "string", Description: "Column type or index columns of an added or removed column or index"},
+			"changes": {Type: "array", Items: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"field": {
+						Type: "string",
+						Enum: []any{"type", "nullable", "default", "columns", "unique", "primary", "name"},
+					},
+					"source": {Type: "string"},
+					"target": {Type: "string"},
+				},
+				Required: []string{"field", "source", "target"},
+			}},
+		},
+		Required: []string{"type", "name"},
+	}}
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"source":  {Type: "string"},
+			"target":  {Type: "string"},
+			"added":   changes,
+			"removed": changes,
+			"changed": changes,
+		},
+		Required: []string{"source", "target", "added", "removed", "changed"},
+	}
+}
+
+// schemaDiffResult is the structured content of diff_schema
+type schemaDiffResult struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	sqlpp.SchemaDiff
+}
+
+func (h *ToolHandler) executeDiffSchema(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	source := h.getStringArg(arguments, "source", "")
+	target := h.getStringArg(arguments, "target", "")
+	sourceSchema := h.getStringArg(arguments, "source_schema", "")
+	targetSchema := h.getStringArg(arguments, "target_schema", "")
+
+	if source == "" {
+		return nil, fmt.Errorf("source parameter is required")
+	}
+
+	if target == "" {
+		return nil, fmt.Errorf("target parameter is required")
+	}
+
+	if source == target && sourceSchema == targetSchema {
+		return nil, fmt.Errorf("source and target are the same schema")
+	}
+
+	ctx, err := h.withCallTimeout(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both schemas are read side by side
+	var snapshots [2]*sqlpp.SchemaSnapshot
+	var errs [2]error
+	var wg sync.WaitGroup
+	for i, side := range [2][2]string{{source, sourceSchema}, {target, targetSchema}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			snapshots[i], errs[i] = h.schemaSnapshot(ctx, side[0], side[1])
+		}()
+	}
+	wg.Wait()
+	for i, connection := range []string{source, target} {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("error reading schema of %s: %w", connection, errs[i])
+		}
+	}
+
+	result := schemaDiffResult{
+		Source:     source,
+		Target:     target,
+		SchemaDiff: sqlpp.DiffSchemas(snapshots[0], snapshots[1]),
+	}
+	return &ToolResult{Text: formatSchemaDiff(result), Structured: result}, nil
+}
+
+// schemaSnapshot reads the objects of a schema and the columns and indexes of
+// its tables and views from the catalog
+func (h *ToolHandler) schemaSnapshot(ctx context.Context, connection, schemaName string) (*sqlpp.SchemaSnapshot, error) {
+	dialect, err := h.connectionDialect(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+	objectsQuery, err := sqlpp.SchemaObjectsQuery(dialect, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	columnsQuery, err := sqlpp.SchemaColumnsQuery(dialect, schemaName)
+	if err != nil {
+		return nil, err
+	}
+	indexesQuery, err := sqlpp.SchemaIndexesQuery(dialect, schemaName)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := h.catalogQuery(ctx, connection, objectsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error reading objects: %w", err)
+	}
+	snapshot, err := sqlpp.ParseSchemaObjectNames(output)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err = h.catalogQuery(ctx, connection, columnsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error reading columns: %w", err)
+	}
+	if snapshot.Columns, err = sqlpp.ParseSchemaColumns(output); err != nil {
+		return nil, err
+	}
+
+	output, err = h.catalogQuery(ctx, connection, indexesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error reading indexes: %w", err)
+	}
+	if snapshot.Indexes, err = sqlpp.ParseSchemaIndexes(output); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// formatSchemaDiff renders a schema diff as a summary line followed by one
+// line per added, removed and changed object
+func formatSchemaDiff(result schemaDiffResult) string {
+	if result.Empty() {
+		return fmt.Sprintf("No schema differences between %s and %s", result.Source, result.Target)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Schema differences from %s to %s: %d added, %d removed, %d changed\n",
+		result.Source, result.Target, len(result.Added), len(result.Removed), len(result.Changed))
+	if len(result.Added) > 0 {
+		fmt.Fprintf(&b, "\nAdded (only in %s):\n", result.Target)
+		for _, change := range result.Added {
+			fmt.Fprintf(&b, "  %s\n", changeLine(change))
+		}
+	}
+	if len(result.Removed) > 0 {
+		fmt.Fprintf(&b, "\nRemoved (only in %s):\n", result.Source)
+		for _, change := range result.Removed {
+			fmt.Fprintf(&b, "  %s\n", changeLine(change))
+		}
+	}
+	if len(result.Changed) > 0 {
+		b.WriteString("\nChanged:\n")
+		for _, change := range result.Changed {
+			fields := make([]string, len(change.Changes))
+			for i, field := range change.Changes {
+				fields[i] = fmt.Sprintf("%s %s -> %s", field.Field, orNone(field.Source), orNone(field.Target))
+			}
+			fmt.Fprintf(&b, "  %s: %s\n", changeLine(change), strings.Join(fields, "; "))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// changeLine names a changed object, e.g. "column orders.total numeric(10,2)"
+func changeLine(change sqlpp.SchemaChange) string {
+	line := change.Type + " "
+	if change.Table != "" {
+		line += change.Table + "."
+	}
+	line += change.Name
+	if change.Detail != "" {
+		line += " " + change.Detail
+	}
+	return line
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
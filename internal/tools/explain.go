@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+)
+
+// Explain query tool
+func (h *ToolHandler) createExplainQueryTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"connection": {
+				Type:        "string",
+				Description: "Database connection name to use",
+			},
+			"query": {
+				Type:        "string",
+				Description: "Single SQL statement to explain. It is planned but not run.",
+			},
+			"parameters":      parametersSchema(),
+			"timeout_seconds": timeoutSecondsSchema(),
+		},
+		Required: []string{"connection", "query"},
+	}
+	return Tool{
+		Name: "explain_query",
+		Description: "Show the plan the database would use for a query without running it, as a tree of operations with the tables and indexes they use and the planner's row and cost estimates. " +
+			"The EXPLAIN syntax for the connection's database (SQLite, Postgres, MySQL or SQL Server) is chosen automatically. Use it to check for full scans before running expensive queries.",
+		InputSchema:  &schema,
+		OutputSchema: planOutputSchema(),
+	}
+}
+
+// planOutputSchema describes the structured content of explain_query,
+// matching sqlpp.QueryPlan
+func planOutputSchema() *jsonschema.Schema {
+	node := &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"operation":      {Type: "string"},
+			"relation":       {Type: "string", Description: "Table or view the operation reads"},
+			"index":          {Type: "string", Description: "Index the operation uses"},
+			"estimated_rows": {Type: "number"},
+			"estimated_cost": {Type: "number", Description: "Planner cost in the database's own units"},
+			"detail":         {Type: "string", Description: "Conditions, sort keys and other notes"},
+			"children":       {Type: "array", Description: "Operations feeding this one, with the same fields"},
+		},
+		Required: []string{"operation"},
+	}
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"dialect":   {Type: "string"},
+			"statement": {Type: "string", Description: "EXPLAIN statement sent to the database"},
+			"nodes":     {Type: "array", Items: node, Description: "Top-level operations of the plan"},
+		},
+		Required: []string{"dialect", "statement", "nodes"},
+	}
+}
+
+func (h *ToolHandler) executeExplainQuery(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	connection := h.getStringArg(arguments, "connection", "")
+	query := h.getStringArg(arguments, "query", "")
+
+	if connection == "" {
+		return nil, fmt.Errorf("connection parameter is required")
+	}
+
+	if query == "" {
+		return nil, fmt.Errorf("query parameter is required")
+	}
+
+	params, err := getParametersArg(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect, err := h.connectionDialect(ctx, connection)
+	if err != nil {
+		return nil, fmt.Errorf("cannot explain query: %w", err)
+	}
+	if params != nil {
+		query, err = sqlpp.BindParameters(query, dialect, *params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	statement, err := sqlpp.ExplainStatement(dialect, query)
+	if err != nil {
+		return nil, fmt.Errorf("cannot explain query: %w", err)
+	}
+
+	ctx, err = h.withCallTimeout(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Plans are always read as JSON, whatever the connection's default
+	// output. They run outside any open transaction, so a plan that fails
+	// does not roll it back.
+	result, err := h.executor.ExecuteSQLCommand(ctx, connection, statement, "json")
+	if err != nil {
+		return nil, fmt.Errorf("error explaining query: %w", err)
+	}
+	if !result.Success {
+		return nil, &ExecutionError{Result: result}
+	}
+	output, err := h.fullOutput(ctx, result)
+	if err != nil {
+		return nil, fmt.Errorf("error reading query plan: %w", err)
+	}
+
+	nodes, err := sqlpp.ParsePlan(dialect, output)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s query plan: %w\n\n%s", dialect, err, truncateForLogging(output))
+	}
+
+	plan := sqlpp.QueryPlan{Dialect: dialect, Statement: statement, Nodes: nodes}
+	return &ToolResult{Text: formatPlan(nodes), Structured: plan}, nil
+}
+
+// formatPlan renders plan nodes as an indented tree, one operation per line
+func formatPlan(nodes []*sqlpp.PlanNode) string {
+	var b strings.Builder
+	var write func(nodes []*sqlpp.PlanNode, depth int)
+	write = func(nodes []*sqlpp.PlanNode, depth int) {
+		for _, node := range nodes {
+			b.WriteString(strings.Repeat("  ", depth))
+			b.WriteString("-> ")
+			b.WriteString(node.Operation)
+			if node.Relation != "" {
+				b.WriteString(" on " + node.Relation)
+			}
+			if node.Index != "" {
+				b.WriteString(" using " + node.Index)
+			}
+
+			var estimates []string
+			if node.EstimatedRows != 0 {
+				estimates = append(estimates, "rows="+strconv.FormatFloat(node.EstimatedRows, 'f', -1, 64))
+			}
+			if node.EstimatedCost != 0 {
+				estimates = append(estimates, "cost="+strconv.FormatFloat(node.EstimatedCost, 'f', -1, 64))
+			}
+			if len(estimates) > 0 {
+				b.WriteString(" (" + strings.Join(estimates, " ") + ")")
+			}
+
+			if node.Detail != "" {
+				b.WriteString(": " + node.Detail)
+			}
+			b.WriteString("\n")
+			write(node.Children, depth+1)
+		}
+	}
+	write(nodes, 0)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,190 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// parametersSchema describes the parameters argument of execute_sql_command
+func parametersSchema() *jsonschema.Schema {
+	types := make([]string, len(sqlpp.ParamTypes))
+	for i, t := range sqlpp.ParamTypes {
+		types[i] = string(t)
+	}
+	return &jsonschema.Schema{
+		Types: []string{"array", "object"},
+		Description: "Values bound into the command: an array for ? placeholders in order, or an object for :name placeholders. " +
+			"Each value is a string, number, boolean or null, or {\"type\": ..., \"value\": ...} with type one of " + strings.Join(types, ", ") +
+			". Values are written as literals for the connection's database, so they never need quoting or escaping.",
+	}
+}
+
+// getParametersArg reads the parameters argument, or returns nil if there is none
+func getParametersArg(arguments map[string]interface{}) (*sqlpp.Parameters, error) {
+	raw, ok := arguments["parameters"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	var params sqlpp.Parameters
+	switch values := raw.(type) {
+	case []interface{}:
+		for i, value := range values {
+			param, err := parseParameter(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parameter %d: %v", sqlpp.ErrInvalidParameters, i+1, err)
+			}
+			params.Positional = append(params.Positional, param)
+		}
+	case map[string]interface{}:
+		params.Named = make(map[string]sqlpp.Parameter, len(values))
+		for name, value := range values {
+			param, err := parseParameter(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parameter %s: %v", sqlpp.ErrInvalidParameters, name, err)
+			}
+			params.Named[name] = param
+		}
+	default:
+		return nil, fmt.Errorf("%w: parameters must be an array or an object", sqlpp.ErrInvalidParameters)
+	}
+
+	if len(params.Positional) == 0 && len(params.Named) == 0 {
+		return nil, nil
+	}
+	return &params, nil
+}
+
+// parseParameter reads one parameter: a plain JSON value whose type is
+// inferred, or an object giving the type and value explicitly
+func parseParameter(value interface{}) (sqlpp.Parameter, error) {
+	switch v := value.(type) {
+	case nil:
+		return sqlpp.Parameter{Type: sqlpp.ParamNull}, nil
+	case string:
+		return sqlpp.Parameter{Type: sqlpp.ParamString, Value: v}, nil
+	case bool:
+		return sqlpp.Parameter{Type: sqlpp.ParamBoolean, Value: v}, nil
+	case float64:
+		if v == float64(int64(v)) {
+			return sqlpp.Parameter{Type: sqlpp.ParamInteger, Value: v}, nil
+		}
+		return sqlpp.Parameter{Type: sqlpp.ParamNumber, Value: v}, nil
+	case map[string]interface{}:
+		typeName, ok := v["type"].(string)
+		if !ok {
+			return sqlpp.Parameter{}, fmt.Errorf("object values need a type and a value")
+		}
+		for key := range v {
+			if key != "type" && key != "value" {
+				return sqlpp.Parameter{}, fmt.Errorf("unexpected field %q", key)
+			}
+		}
+		for _, t := range sqlpp.ParamTypes {
+			if string(t) == typeName {
+				return sqlpp.Parameter{Type: t, Value: v["value"]}, nil
+			}
+		}
+		return sqlpp.Parameter{}, fmt.Errorf("unknown type %q", typeName)
+	default:
+		return sqlpp.Parameter{}, fmt.Errorf("arrays are not supported as values")
+	}
+}
+
+// bindParameters binds params into command in the dialect of connection
+func (h *ToolHandler) bindParameters(ctx context.Context, connection, command string, params sqlpp.Parameters) (string, error) {
+	dialect, err := h.connectionDialect(ctx, connection)
+	if err != nil {
+		return "", fmt.Errorf("cannot bind parameters: %w", err)
+	}
+	return sqlpp.BindParameters(command, dialect, params)
+}
+
+// connectionDialect returns the SQL dialect of connection, worked out from its
+// driver in list_connections and, for unfamiliar driver names, the driver's
+// description in list_drivers. Dialects are cached per connection.
+func (h *ToolHandler) connectionDialect(ctx context.Context, connection string) (sqlpp.Dialect, error) {
+	h.dialectsMu.Lock()
+	dialect, ok := h.dialects[connection]
+	h.dialectsMu.Unlock()
+	if ok {
+		return dialect, nil
+	}
+
+	connections, err := h.listConnections(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error looking up connection %s: %w", connection, err)
+	}
+
+	for _, c := range connections {
+		if c.Name == connection {
+			return h.resolveDialect(ctx, connection, c.Driver)
+		}
+	}
+	return "", fmt.Errorf("connection %s is not in list_connections", connection)
+}
+
+// listConnections runs list_connections and parses its output
+func (h *ToolHandler) listConnections(ctx context.Context) ([]types.Connection, error) {
+	result, err := h.executor.ListConnections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, &ExecutionError{Result: result}
+	}
+	return sqlpp.ParseConnections(result.Output)
+}
+
+// resolveDialect returns the SQL dialect of connection, which uses driver,
+// and caches it like connectionDialect. Callers that already listed the
+// connections use it to skip listing them again.
+func (h *ToolHandler) resolveDialect(ctx context.Context, connection, driver string) (sqlpp.Dialect, error) {
+	h.dialectsMu.Lock()
+	dialect, ok := h.dialects[connection]
+	h.dialectsMu.Unlock()
+	if ok {
+		return dialect, nil
+	}
+
+	dialect, ok = sqlpp.DialectFor(driver, "")
+	if !ok {
+		dialect, ok = h.driverDialect(ctx, driver)
+	}
+	if !ok {
+		return "", fmt.Errorf("the SQL dialect of driver %q used by connection %s is not known", driver, connection)
+	}
+
+	h.dialectsMu.Lock()
+	if h.dialects == nil {
+		h.dialects = make(map[string]sqlpp.Dialect)
+	}
+	h.dialects[connection] = dialect
+	h.dialectsMu.Unlock()
+
+	return dialect, nil
+}
+
+// driverDialect looks for the dialect in the description list_drivers gives
+// for driver
+func (h *ToolHandler) driverDialect(ctx context.Context, driver string) (sqlpp.Dialect, bool) {
+	result, err := h.executor.ListDrivers(ctx)
+	if err != nil || !result.Success {
+		return "", false
+	}
+	drivers, err := sqlpp.ParseDrivers(result.Output)
+	if err != nil {
+		return "", false
+	}
+	for _, d := range drivers {
+		if d.Name == driver {
+			return sqlpp.DialectFor(d.Name, d.Description)
+		}
+	}
+	return "", false
+}
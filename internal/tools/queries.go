@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+)
+
+// runningQueries is the structured content of list_running_queries
+type runningQueries struct {
+	Queries []sqlpp.RunningQuery `json:"queries"`
+}
+
+// cancelledQuery is the structured content of cancel_query
+type cancelledQuery struct {
+	ID        string `json:"id"`
+	Cancelled bool   `json:"cancelled"`
+}
+
+// List running queries tool
+func (h *ToolHandler) createListRunningQueriesTool() Tool {
+	schema := jsonschema.Schema{
+		Type:       "object",
+		Properties: map[string]*jsonschema.Schema{},
+	}
+	output := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"queries": {
+				Type: "array",
+				Items: &jsonschema.Schema{
+					Type: "object",
+					Properties: map[string]*jsonschema.Schema{
+						"id":          {Type: "string", Description: "Query ID to pass to cancel_query"},
+						"connection":  {Type: "string"},
+						"operation":   {Type: "string", Description: "schema, sql, list_connections or list_drivers"},
+						"fingerprint": {Type: "string", Description: "Statement with literals replaced by ?"},
+						"started_at":  {Type: "string", Description: "Start time (RFC 3339)"},
+						"elapsed_ms":  {Type: "integer"},
+						"session":     {Type: "string", Description: "MCP session that started the query"},
+						"pid":         {Type: "integer"},
+						"pooled":      {Type: "boolean", Description: "Whether the query runs on a pooled worker"},
+					},
+					Required: []string{"id", "operation", "fingerprint", "started_at", "elapsed_ms", "pid", "pooled"},
+				},
+			},
+		},
+		Required: []string{"queries"},
+	}
+	return Tool{
+		Name:         "list_running_queries",
+		Description:  "List the sqlpp queries this session is currently running, with their IDs for cancel_query",
+		InputSchema:  &schema,
+		OutputSchema: &output,
+	}
+}
+
+// Cancel query tool
+func (h *ToolHandler) createCancelQueryTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"id": {
+				Type:        "string",
+				Description: "ID of the query to cancel, from list_running_queries",
+			},
+		},
+		Required: []string{"id"},
+	}
+	output := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"id":        {Type: "string"},
+			"cancelled": {Type: "boolean"},
+		},
+		Required: []string{"id", "cancelled"},
+	}
+	return Tool{
+		Name:         "cancel_query",
+		Description:  "Cancel a sqlpp query this session is running. The process is interrupted first and killed if it does not stop within the grace period.",
+		InputSchema:  &schema,
+		OutputSchema: &output,
+	}
+}
+
+func (h *ToolHandler) executeListRunningQueries(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	if h.queries == nil {
+		return nil, fmt.Errorf("unknown tool: list_running_queries")
+	}
+
+	queries := runningQueries{Queries: h.queries.List(sqlpp.SessionIDFrom(ctx))}
+
+	formatted, err := json.MarshalIndent(queries, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error formatting running queries: %w", err)
+	}
+
+	return &ToolResult{Text: string(formatted), Structured: queries}, nil
+}
+
+func (h *ToolHandler) executeCancelQuery(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	if h.queries == nil {
+		return nil, fmt.Errorf("unknown tool: cancel_query")
+	}
+
+	id := h.getStringArg(arguments, "id", "")
+	if id == "" {
+		return nil, fmt.Errorf("id parameter is required")
+	}
+
+	if err := h.queries.Cancel(sqlpp.SessionIDFrom(ctx), id); err != nil {
+		return nil, fmt.Errorf("error cancelling query %s: %w", id, err)
+	}
+
+	return &ToolResult{
+		Text:       fmt.Sprintf("Cancellation requested for query %s", id),
+		Structured: cancelledQuery{ID: id, Cancelled: true},
+	}, nil
+}
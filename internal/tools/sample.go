@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+)
+
+// Sample table rows tool
+func (h *ToolHandler) createSampleTableRowsTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"connection": {
+				Type:        "string",
+				Description: "Database connection name to use",
+			},
+			"table": {
+				Type:        "string",
+				Description: "Table or view name. It is quoted, so give it exactly as the catalog stores it.",
+			},
+			"schema": {
+				Type:        "string",
+				Description: "Schema of the table. Defaults to the connection's current schema.",
+			},
+			"columns": {
+				Type:        "array",
+				Items:       &jsonschema.Schema{Type: "string"},
+				Description: "Columns to return (default all)",
+			},
+			"limit": {
+				Type:        "integer",
+				Description: fmt.Sprintf("Number of rows to return (default %d, max %d)", h.sampleDefault, h.sampleMax),
+			},
+			"mode": {
+				Type:        "string",
+				Enum:        []any{string(sqlpp.SampleFirst), string(sqlpp.SampleRandom)},
+				Description: "first returns the first rows the database reads (default); random returns randomly chosen rows, reading only a fraction of large tables where the database supports TABLESAMPLE",
+			},
+			"output": {
+				Type:        "string",
+				Description: "Output format (json, table, csv, etc.)",
+			},
+			"timeout_seconds": timeoutSecondsSchema(),
+		},
+		Required: []string{"connection", "table"},
+	}
+	return Tool{
+		Name:         "sample_table_rows",
+		Description:  "Return a few example rows from a table, using the LIMIT, TOP or TABLESAMPLE syntax of the connection's database. Use it to see what the data in a table looks like.",
+		InputSchema:  &schema,
+		OutputSchema: executionOutputSchema(),
+	}
+}
+
+func (h *ToolHandler) executeSampleTableRows(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	connection := h.getStringArg(arguments, "connection", "")
+	output := h.getStringArg(arguments, "output", "")
+	req := sqlpp.SampleRequest{
+		Schema: h.getStringArg(arguments, "schema", ""),
+		Table:  h.getStringArg(arguments, "table", ""),
+		Limit:  h.getIntArg(arguments, "limit", h.sampleDefault),
+		Mode:   sqlpp.SampleMode(h.getStringArg(arguments, "mode", string(sqlpp.SampleFirst))),
+	}
+
+	if connection == "" {
+		return nil, fmt.Errorf("connection parameter is required")
+	}
+
+	if req.Table == "" {
+		return nil, fmt.Errorf("table parameter is required")
+	}
+
+	columns, err := getColumnsArg(arguments)
+	if err != nil {
+		return nil, err
+	}
+	req.Columns = columns
+
+	if req.Limit < 1 {
+		return nil, fmt.Errorf("limit must be at least 1")
+	}
+	if req.Limit > h.sampleMax {
+		req.Limit = h.sampleMax
+	}
+
+	dialect, err := h.connectionDialect(ctx, connection)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sample table: %w", err)
+	}
+
+	ctx, err = h.withCallTimeout(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	var estimatedRows float64
+	if req.Mode == sqlpp.SampleRandom {
+		estimatedRows = h.estimateRows(ctx, dialect, connection, req.Schema, req.Table)
+	}
+	query, err := sqlpp.SampleQuery(dialect, req, estimatedRows)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sample table: %w", err)
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"connection": connection,
+		"query":      query,
+	}).Debug("Sampling table rows")
+
+	result, err := h.sqlRunner(ctx, connection, output)(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error sampling table: %w", err)
+	}
+
+	return h.sqlppToolResult(result)
+}
+
+// estimateRows returns the planner's row estimate for a table, or 0 where
+// the dialect does not need one or it cannot be read. The sample then reads
+// the whole table, so a failed estimate costs time but not correctness.
+func (h *ToolHandler) estimateRows(ctx context.Context, dialect sqlpp.Dialect, connection, schema, table string) float64 {
+	query, err := sqlpp.RowEstimateQuery(dialect, schema, table)
+	if err != nil || query == "" {
+		return 0
+	}
+
+	// A best-effort estimate runs outside any open transaction, which its
+	// failure must not roll back
+	result, err := h.executor.ExecuteSQLCommand(ctx, connection, query, "json")
+	if err == nil && !result.Success {
+		err = fmt.Errorf("%s", result.Error)
+	}
+	var estimate float64
+	if err == nil {
+		estimate, err = sqlpp.ParseRowEstimate(result.Output)
+	}
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"connection": connection,
+			"table":      table,
+			"error":      err,
+		}).Debug("Could not estimate table size for sampling")
+		return 0
+	}
+	return estimate
+}
+
+// getColumnsArg reads the optional columns argument as a list of names
+func getColumnsArg(arguments map[string]interface{}) ([]string, error) {
+	raw, ok := arguments["columns"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	values, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("columns must be an array of column names")
+	}
+	columns := make([]string, len(values))
+	for i, value := range values {
+		name, ok := value.(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("columns must be an array of column names")
+		}
+		columns[i] = name
+	}
+	return columns, nil
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+)
+
+// Execute SQL file tool
+func (h *ToolHandler) createExecuteSQLFileTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"connection": {
+				Type:        "string",
+				Description: "Database connection name to use",
+			},
+			"path": {
+				Type:        "string",
+				Description: "Script path relative to a script root, e.g. reports/daily_sales.sql. Available scripts are listed as resources.",
+			},
+			"variables": {
+				Type:                 "object",
+				Description:          "Variables defined for the script with #define, as name/value pairs. Values become SQL literals: strings are quoted, so scripts use ${name} without quotes.",
+				AdditionalProperties: &jsonschema.Schema{Types: []string{"string", "number", "boolean"}},
+			},
+			"output": {
+				Type:        "string",
+				Description: "Output format (json, table, csv, etc.)",
+			},
+			"timeout_seconds": timeoutSecondsSchema(),
+		},
+		Required: []string{"connection", "path"},
+	}
+	return Tool{
+		Name:         "execute_sql_file",
+		Description:  "Execute a vetted .sql script from the server's script directories by its relative path",
+		InputSchema:  &schema,
+		OutputSchema: executionOutputSchema(),
+	}
+}
+
+func (h *ToolHandler) executeSQLFile(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	if h.scripts == nil {
+		return nil, fmt.Errorf("unknown tool: execute_sql_file")
+	}
+
+	connection := h.getStringArg(arguments, "connection", "")
+	path := h.getStringArg(arguments, "path", "")
+	output := h.getStringArg(arguments, "output", "")
+
+	if connection == "" {
+		return nil, fmt.Errorf("connection parameter is required")
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+
+	variables, err := getVariablesArg(arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	_, content, err := h.scripts.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Variables are written as literals of the connection's dialect
+	var dialect sqlpp.Dialect
+	if len(variables) > 0 {
+		dialect, err = h.connectionDialect(ctx, connection)
+		if err != nil {
+			return nil, fmt.Errorf("cannot bind variables: %w", err)
+		}
+	}
+	input, err := sqlpp.ScriptInput(content, dialect, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = h.withCallTimeout(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := h.sqlRunner(ctx, connection, output)(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("error executing SQL file %s: %w", path, err)
+	}
+
+	return h.sqlppToolResult(result)
+}
+
+// getVariablesArg reads the variables argument as name/value pairs; the
+// values are checked when they are written as literals
+func getVariablesArg(arguments map[string]interface{}) (map[string]interface{}, error) {
+	raw, ok := arguments["variables"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("variables must be an object of name/value pairs")
+	}
+	return values, nil
+}
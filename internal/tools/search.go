@@ -0,0 +1,288 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/sirupsen/logrus"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+)
+
+const (
+	// defaultSearchResults is how many matches search_schema returns when
+	// the call does not set a limit
+	defaultSearchResults = 50
+
+	// minSearchScore is the weakest match search_schema returns
+	minSearchScore = 0.4
+
+	// maxSearchConcurrency is how many connections search_schema reads at once
+	maxSearchConcurrency = 4
+)
+
+// Search schema tool
+func (h *ToolHandler) createSearchSchemaTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"term": {
+				Type:        "string",
+				Description: "What to look for, e.g. customer email. Case, word order, separators and small typos are ignored.",
+			},
+			"connections": {
+				Type:        "array",
+				Items:       &jsonschema.Schema{Type: "string"},
+				Description: "Connections to search (default all connections in list_connections)",
+			},
+			"limit": {
+				Type:        "integer",
+				Description: fmt.Sprintf("Maximum number of matches to return (default %d)", defaultSearchResults),
+			},
+			"timeout_seconds": timeoutSecondsSchema(),
+		},
+		Required: []string{"term"},
+	}
+	return Tool{
+		Name: "search_schema",
+		Description: "Search the names and comments of tables, views, columns, procedures and functions across one or more connections, best matches first. " +
+			"Use it to find where data lives when the table or column name is not known.",
+		InputSchema:  &schema,
+		OutputSchema: searchOutputSchema(),
+	}
+}
+
+// searchOutputSchema describes the structured content of search_schema
+func searchOutputSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"matches": {Type: "array", Items: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"connection": {Type: "string"},
+					"type": {
+						Type: "string",
+						Enum: []any{sqlpp.ObjectTable, sqlpp.ObjectView, sqlpp.ObjectColumn, sqlpp.ObjectProcedure, sqlpp.ObjectFunction},
+					},
+					"schema":     {Type: "string"},
+					"name":       {Type: "string", Description: "Object name; the table or view of a column"},
+					"column":     {Type: "string"},
+					"comment":    {Type: "string"},
+					"score":      {Type: "number", Description: "Match quality from 0 to 1"},
+					"matched_on": {Type: "string", Enum: []any{"name", "comment"}},
+				},
+				Required: []string{"connection", "type", "name", "score", "matched_on"},
+			}},
+			"searched": {Type: "array", Items: &jsonschema.Schema{Type: "string"}, Description: "Connections that were searched"},
+			"errors": {Type: "array", Items: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"connection": {Type: "string"},
+					"error":      {Type: "string"},
+				},
+				Required: []string{"connection", "error"},
+			}},
+		},
+		Required: []string{"matches", "searched"},
+	}
+}
+
+// schemaSearchMatch is a match in one connection
+type schemaSearchMatch struct {
+	Connection string `json:"connection"`
+	sqlpp.SchemaMatch
+}
+
+// connectionError reports a connection that could not be searched
+type connectionError struct {
+	Connection string `json:"connection"`
+	Error      string `json:"error"`
+}
+
+// schemaSearchResult is the structured content of search_schema
+type schemaSearchResult struct {
+	Matches  []schemaSearchMatch `json:"matches"`
+	Searched []string            `json:"searched"`
+	Errors   []connectionError   `json:"errors,omitempty"`
+}
+
+func (h *ToolHandler) executeSearchSchema(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	term := strings.TrimSpace(h.getStringArg(arguments, "term", ""))
+	limit := h.getIntArg(arguments, "limit", defaultSearchResults)
+
+	if term == "" {
+		return nil, fmt.Errorf("term parameter is required")
+	}
+
+	if limit < 1 {
+		return nil, fmt.Errorf("limit must be at least 1")
+	}
+
+	connections, err := getStringListArg(arguments, "connections")
+	if err != nil {
+		return nil, err
+	}
+
+	// One listing gives the names to search and each connection's driver
+	all := len(connections) == 0
+	listed, err := h.listConnections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing connections: %w", err)
+	}
+	drivers := make(map[string]string, len(listed))
+	for _, c := range listed {
+		drivers[c.Name] = c.Driver
+		if all {
+			connections = append(connections, c.Name)
+		}
+	}
+	if len(connections) == 0 {
+		return nil, fmt.Errorf("no connections to search")
+	}
+
+	ctx, err = h.withCallTimeout(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([][]sqlpp.SchemaMatch, len(connections))
+	errs := make([]error, len(connections))
+	dialects := make([]sqlpp.Dialect, len(connections))
+	for i, connection := range connections {
+		driver, ok := drivers[connection]
+		if !ok {
+			errs[i] = fmt.Errorf("connection %s is not in list_connections", connection)
+			continue
+		}
+		dialects[i], errs[i] = h.resolveDialect(ctx, connection, driver)
+	}
+
+	// Connections are searched side by side, a few at a time
+	slots := make(chan struct{}, maxSearchConcurrency)
+	var wg sync.WaitGroup
+	for i, connection := range connections {
+		if errs[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			matches[i], errs[i] = h.searchConnection(ctx, connection, dialects[i], term)
+		}()
+	}
+	wg.Wait()
+
+	result := schemaSearchResult{Matches: []schemaSearchMatch{}, Searched: []string{}}
+	for i, connection := range connections {
+		if errs[i] != nil {
+			h.logger.WithFields(logrus.Fields{
+				"connection": connection,
+				"error":      errs[i],
+			}).Warn("Schema search failed for connection")
+			result.Errors = append(result.Errors, connectionError{Connection: connection, Error: errs[i].Error()})
+			continue
+		}
+		result.Searched = append(result.Searched, connection)
+		for _, match := range matches[i] {
+			result.Matches = append(result.Matches, schemaSearchMatch{Connection: connection, SchemaMatch: match})
+		}
+	}
+	if len(result.Searched) == 0 {
+		return nil, fmt.Errorf("schema search failed for every connection: %s", formatConnectionErrors(result.Errors))
+	}
+
+	sort.SliceStable(result.Matches, func(i, j int) bool {
+		if result.Matches[i].Score != result.Matches[j].Score {
+			return result.Matches[i].Score > result.Matches[j].Score
+		}
+		return result.Matches[i].Connection < result.Matches[j].Connection
+	})
+	if len(result.Matches) > limit {
+		result.Matches = result.Matches[:limit]
+	}
+
+	return &ToolResult{Text: formatSchemaSearch(term, result), Structured: result}, nil
+}
+
+// searchConnection lists the schema objects of connection, which speaks
+// dialect, and ranks them against term
+func (h *ToolHandler) searchConnection(ctx context.Context, connection string, dialect sqlpp.Dialect, term string) ([]sqlpp.SchemaMatch, error) {
+	query, err := sqlpp.SearchCatalogQuery(dialect)
+	if err != nil {
+		return nil, err
+	}
+	output, err := h.catalogQuery(ctx, connection, query)
+	if err != nil {
+		return nil, fmt.Errorf("error reading catalog: %w", err)
+	}
+	objects, err := sqlpp.ParseSchemaObjects(output)
+	if err != nil {
+		return nil, fmt.Errorf("error reading catalog: %w", err)
+	}
+	return sqlpp.RankSchemaObjects(term, objects, minSearchScore), nil
+}
+
+// getStringListArg reads an optional argument holding a list of non-empty
+// strings
+func getStringListArg(arguments map[string]interface{}, key string) ([]string, error) {
+	raw, ok := arguments[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	values, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", key)
+	}
+	list := make([]string, len(values))
+	for i, value := range values {
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("%s must be an array of strings", key)
+		}
+		list[i] = s
+	}
+	return list, nil
+}
+
+// formatSchemaSearch renders search results as text, one match per line
+func formatSchemaSearch(term string, result schemaSearchResult) string {
+	var b strings.Builder
+	if len(result.Matches) == 0 {
+		fmt.Fprintf(&b, "No matches for %q in %s", term, strings.Join(result.Searched, ", "))
+	} else {
+		fmt.Fprintf(&b, "Matches for %q:\n", term)
+		for _, match := range result.Matches {
+			name := match.Name
+			if match.Schema != "" {
+				name = match.Schema + "." + name
+			}
+			if match.Column != "" {
+				name += "." + match.Column
+			}
+			fmt.Fprintf(&b, "  %s: %s %s (%.2f", match.Connection, match.Type, name, match.Score)
+			if match.MatchedOn == "comment" {
+				fmt.Fprintf(&b, ", comment: %s", truncateForLogging(match.Comment))
+			}
+			b.WriteString(")\n")
+		}
+	}
+	if len(result.Errors) > 0 {
+		fmt.Fprintf(&b, "\nNot searched: %s", formatConnectionErrors(result.Errors))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func formatConnectionErrors(errs []connectionError) string {
+	parts := make([]string, len(errs))
+	for i, err := range errs {
+		parts[i] = err.Connection + " (" + err.Error + ")"
+	}
+	return strings.Join(parts, "; ")
+}
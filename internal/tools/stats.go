@@ -0,0 +1,231 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+)
+
+// Table statistics tool
+func (h *ToolHandler) createTableStatsTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"connection": {
+				Type:        "string",
+				Description: "Database connection name to use",
+			},
+			"table": {
+				Type:        "string",
+				Description: "Table name, as stored in the catalog. Leave out to use filter instead.",
+			},
+			"filter": {
+				Type:        "string",
+				Description: "Filter pattern passed to @schema-tables, e.g. user% (default all tables). Ignored when table is given.",
+			},
+			"schema": {
+				Type:        "string",
+				Description: "Schema of the tables (attached database for SQLite). Defaults to the connection's current schema.",
+			},
+			"exact_count": {
+				Type:        "boolean",
+				Description: "Also count the rows of each table with COUNT(*), which reads every table in full (default false)",
+			},
+			"timeout_seconds": timeoutSecondsSchema(),
+		},
+		Required: []string{"connection"},
+	}
+	return Tool{
+		Name: "get_table_stats",
+		Description: "Show the approximate row count, total and index size, and last analyze and vacuum times of a table or the tables matching a filter, read from the database's catalog without scanning the tables. " +
+			"Use it to judge which tables are small enough to scan.",
+		InputSchema:  &schema,
+		OutputSchema: tableStatsOutputSchema(),
+	}
+}
+
+// tableStatsOutputSchema describes the structured content of
+// get_table_stats, matching sqlpp.TableStats
+func tableStatsOutputSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"tables": {Type: "array", Items: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"schema":        {Type: "string"},
+					"table":         {Type: "string"},
+					"row_estimate":  {Type: "integer", Description: "Planner row estimate from the last analyze"},
+					"exact_rows":    {Type: "integer", Description: "COUNT(*) result, when exact_count is set"},
+					"total_bytes":   {Type: "integer", Description: "Table size including indexes"},
+					"index_bytes":   {Type: "integer"},
+					"last_analyzed": {Type: "string"},
+					"last_vacuumed": {Type: "string"},
+				},
+				Required: []string{"table"},
+			}},
+		},
+		Required: []string{"tables"},
+	}
+}
+
+// tableStatsResult is the structured content of get_table_stats
+type tableStatsResult struct {
+	Tables []sqlpp.TableStats `json:"tables"`
+}
+
+func (h *ToolHandler) executeTableStats(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	connection := h.getStringArg(arguments, "connection", "")
+	table := h.getStringArg(arguments, "table", "")
+	filter := h.getStringArg(arguments, "filter", "")
+	schemaName := h.getStringArg(arguments, "schema", "")
+	exact := h.getBoolArg(arguments, "exact_count", false)
+
+	if connection == "" {
+		return nil, fmt.Errorf("connection parameter is required")
+	}
+
+	dialect, err := h.connectionDialect(ctx, connection)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read table statistics: %w", err)
+	}
+
+	ctx, err = h.withCallTimeout(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []string{table}
+	if table == "" {
+		tables, err = h.listTables(ctx, connection, filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(tables) == 0 {
+			return &ToolResult{Text: "No tables found", Structured: tableStatsResult{Tables: []sqlpp.TableStats{}}}, nil
+		}
+	}
+
+	query, err := sqlpp.TableStatsQuery(dialect, schemaName, tables)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read table statistics: %w", err)
+	}
+	output, err := h.catalogQuery(ctx, connection, query)
+	if err != nil {
+		return nil, fmt.Errorf("error reading table statistics: %w", err)
+	}
+	stats, err := sqlpp.ParseTableStats(output)
+	if err != nil {
+		return nil, fmt.Errorf("error reading table statistics: %w", err)
+	}
+	if table != "" && len(stats) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+
+	if exact && len(stats) > 0 {
+		found := make([]string, len(stats))
+		for i, stat := range stats {
+			found[i] = stat.Table
+		}
+		query, err := sqlpp.ExactCountQuery(dialect, schemaName, found)
+		if err != nil {
+			return nil, fmt.Errorf("cannot count rows: %w", err)
+		}
+		output, err := h.catalogQuery(ctx, connection, query)
+		if err != nil {
+			return nil, fmt.Errorf("error counting rows: %w", err)
+		}
+		counts, err := sqlpp.ParseExactCounts(output)
+		if err != nil {
+			return nil, fmt.Errorf("error counting rows: %w", err)
+		}
+		for i := range stats {
+			if count, ok := counts[stats[i].Table]; ok {
+				stats[i].ExactRows = &count
+			}
+		}
+	}
+
+	return &ToolResult{Text: formatTableStats(stats), Structured: tableStatsResult{Tables: stats}}, nil
+}
+
+// listTables returns the names of the tables @schema-tables lists for filter
+func (h *ToolHandler) listTables(ctx context.Context, connection, filter string) ([]string, error) {
+	return h.listObjects(ctx, connection, sqlpp.ObjectTable, filter)
+}
+
+// listObjects returns the names of the objects of objectType (table, view,
+// procedure or function) that the matching @schema command lists for filter
+func (h *ToolHandler) listObjects(ctx context.Context, connection, objectType, filter string) ([]string, error) {
+	result, err := h.executor.ExecuteSchemaCommand(ctx, objectType+"s", connection, filter, "json")
+	if err != nil {
+		return nil, fmt.Errorf("error listing %ss: %w", objectType, err)
+	}
+	if !result.Success {
+		return nil, &ExecutionError{Result: result}
+	}
+	output, err := h.fullOutput(ctx, result)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s listing: %w", objectType, err)
+	}
+
+	names, err := sqlpp.ParseObjectNames(output, objectType)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s listing: %w\n\n%s", objectType, err, truncateForLogging(output))
+	}
+	return names, nil
+}
+
+// formatTableStats renders table statistics as text, one table per line
+func formatTableStats(stats []sqlpp.TableStats) string {
+	lines := make([]string, len(stats))
+	for i, stat := range stats {
+		name := stat.Table
+		if stat.Schema != "" {
+			name = stat.Schema + "." + stat.Table
+		}
+
+		var parts []string
+		if stat.RowEstimate != nil {
+			parts = append(parts, "~"+strconv.FormatInt(*stat.RowEstimate, 10)+" rows")
+		}
+		if stat.ExactRows != nil {
+			parts = append(parts, strconv.FormatInt(*stat.ExactRows, 10)+" rows exactly")
+		}
+		if stat.TotalBytes != nil {
+			parts = append(parts, formatBytes(*stat.TotalBytes)+" total")
+		}
+		if stat.IndexBytes != nil {
+			parts = append(parts, formatBytes(*stat.IndexBytes)+" indexes")
+		}
+		if stat.LastAnalyzed != "" {
+			parts = append(parts, "analyzed "+stat.LastAnalyzed)
+		}
+		if stat.LastVacuumed != "" {
+			parts = append(parts, "vacuumed "+stat.LastVacuumed)
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "no statistics")
+		}
+		lines[i] = name + ": " + strings.Join(parts, ", ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + " " + "KMGTP"[exp:exp+1] + "iB"
+}
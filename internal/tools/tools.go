@@ -1,12 +1,16 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 	"github.com/sirupsen/logrus"
 	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
 )
 
 const (
@@ -22,30 +26,245 @@ func truncateForLogging(output string) string {
 	return output[:MaxLogOutputLength] + "... (truncated)"
 }
 
+const (
+	// defaultPageSize is the default number of bytes returned by fetch_result_page
+	defaultPageSize = 64 * 1024
+
+	// maxPageSize is the largest page fetch_result_page will return
+	maxPageSize = 1024 * 1024
+
+	// defaultSampleRows is the default number of rows sample_table_rows returns
+	defaultSampleRows = 10
+
+	// maxSampleRows is the default cap on the rows sample_table_rows returns
+	maxSampleRows = 100
+
+	// maxBatchRepeat is the default cap on the count after GO
+	maxBatchRepeat = 100
+
+	// maxCatalogBytes is the default cap on spilled output read back whole
+	// to be parsed
+	maxCatalogBytes = 64 * 1024 * 1024
+)
+
 // ToolHandler handles MCP tool execution
 type ToolHandler struct {
 	executor sqlpp.ExecutorInterface
 	logger   *logrus.Logger
+	results  *sqlpp.ResultStore
+	queries  *sqlpp.QueryRegistry
+	caps     *sqlpp.Capabilities
+	scripts  *sqlpp.ScriptLibrary
+
+	transactions *sqlpp.TransactionManager
+	schemaCache  *sqlpp.CachingExecutor
+
+	// Row limits of sample_table_rows
+	sampleDefault int
+	sampleMax     int
+
+	// Largest GO n repeat count execute_sql_command accepts
+	maxBatchRepeat int
+
+	// Largest spilled catalog or plan output read back to be parsed
+	maxCatalogBytes int64
+
+	// SQL dialect per connection, for the tools that generate SQL
+	dialectsMu sync.Mutex
+	dialects   map[string]sqlpp.Dialect
+}
+
+// Option configures optional ToolHandler features
+type Option func(*ToolHandler)
+
+// WithResultStore enables the fetch_result_page tool for output spilled to store
+func WithResultStore(store *sqlpp.ResultStore) Option {
+	return func(h *ToolHandler) {
+		h.results = store
+	}
+}
+
+// WithQueryRegistry enables the list_running_queries and cancel_query tools
+// for the in-flight queries tracked by registry
+func WithQueryRegistry(registry *sqlpp.QueryRegistry) Option {
+	return func(h *ToolHandler) {
+		h.queries = registry
+	}
+}
+
+// WithScriptLibrary enables the execute_sql_file tool for scripts in lib
+func WithScriptLibrary(lib *sqlpp.ScriptLibrary) Option {
+	return func(h *ToolHandler) {
+		h.scripts = lib
+	}
+}
+
+// WithTransactions enables the begin_transaction, commit_transaction and
+// rollback_transaction tools, and runs SQL in a session's open transaction
+func WithTransactions(transactions *sqlpp.TransactionManager) Option {
+	return func(h *ToolHandler) {
+		h.transactions = transactions
+	}
+}
+
+// WithSchemaCache drops cached schema results when DDL runs in a transaction
+// or a transaction commits. DDL run outside transactions goes through the
+// executor, which cache also wraps.
+func WithSchemaCache(cache *sqlpp.CachingExecutor) Option {
+	return func(h *ToolHandler) {
+		h.schemaCache = cache
+	}
+}
+
+// WithSampleLimits sets the rows sample_table_rows returns when no limit is
+// given, and the cap on limits. Zero keeps the built-in value.
+func WithSampleLimits(defaultRows, maxRows int) Option {
+	return func(h *ToolHandler) {
+		if defaultRows > 0 {
+			h.sampleDefault = defaultRows
+		}
+		if maxRows > 0 {
+			h.sampleMax = maxRows
+		}
+	}
+}
+
+// WithBatchRepeatLimit sets the largest repeat count a GO separator may ask
+// for. Zero keeps the built-in value.
+func WithBatchRepeatLimit(maxRepeat int) Option {
+	return func(h *ToolHandler) {
+		if maxRepeat > 0 {
+			h.maxBatchRepeat = maxRepeat
+		}
+	}
+}
+
+// WithCatalogLimit sets how much spilled output the tools that parse their
+// generated queries' output read back. Zero keeps the built-in value.
+func WithCatalogLimit(maxBytes int64) Option {
+	return func(h *ToolHandler) {
+		if maxBytes > 0 {
+			h.maxCatalogBytes = maxBytes
+		}
+	}
+}
+
+// WithCapabilities limits the sqlpp tools to those the detected sqlpp
+// version supports
+func WithCapabilities(caps *sqlpp.Capabilities) Option {
+	return func(h *ToolHandler) {
+		h.caps = caps
+	}
 }
 
 // NewToolHandler creates a new tool handler
-func NewToolHandler(executor sqlpp.ExecutorInterface, logger *logrus.Logger) *ToolHandler {
-	return &ToolHandler{
-		executor: executor,
-		logger:   logger,
+func NewToolHandler(executor sqlpp.ExecutorInterface, logger *logrus.Logger, opts ...Option) *ToolHandler {
+	h := &ToolHandler{
+		executor:      executor,
+		logger:        logger,
+		sampleDefault: defaultSampleRows,
+		sampleMax:     maxSampleRows,
+
+		maxBatchRepeat:  maxBatchRepeat,
+		maxCatalogBytes: maxCatalogBytes,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	h.sampleDefault = min(h.sampleDefault, h.sampleMax)
+	return h
 }
 
 // Tool represents a simplified tool definition
 type Tool struct {
-	Name        string
-	Description string
-	InputSchema *jsonschema.Schema
+	Name         string
+	Description  string
+	InputSchema  *jsonschema.Schema
+	OutputSchema *jsonschema.Schema
+}
+
+// ToolResult is the outcome of a tool call: text for the model and
+// structured content matching the tool's output schema
+type ToolResult struct {
+	Text       string
+	Structured interface{}
+}
+
+// ExecutionError is returned when sqlpp ran but the command failed. It carries
+// the result so callers can report the execution metadata.
+type ExecutionError struct {
+	Result *types.SqlppResult
+}
+
+func (e *ExecutionError) Error() string {
+	if e.Result.ErrorCode != "" {
+		return fmt.Sprintf("sqlpp command failed [%s]: %s", e.Result.ErrorCode, e.Result.Error)
+	}
+	return fmt.Sprintf("sqlpp command failed: %s", e.Result.Error)
+}
+
+// toolRequirements lists the sqlpp flags each sqlpp tool uses. @ commands
+// are not listed: --help need not mention them, and a sqlpp without one
+// reports that when the tool runs.
+var toolRequirements = map[string][]string{
+	"list_schema_all":        {"--stdin"},
+	"list_schema_tables":     {"--stdin"},
+	"list_schema_views":      {"--stdin"},
+	"list_schema_procedures": {"--stdin"},
+	"list_schema_functions":  {"--stdin"},
+	"list_connections":       {"--list-connections"},
+	"execute_sql_command":    {"--stdin"},
+	"execute_sql_file":       {"--stdin"},
+	"list_drivers":           {"--stdin"},
+	"explain_query":          {"--stdin", "--list-connections"},
+	"describe_table":         {"--stdin", "--list-connections"},
+	"sample_table_rows":      {"--stdin", "--list-connections"},
+	"get_table_stats":        {"--stdin", "--list-connections"},
+	"search_schema":          {"--stdin", "--list-connections"},
+	"diff_schema":            {"--stdin", "--list-connections"},
+	"begin_transaction":      {"--stdin", "--delimiter"},
+	"commit_transaction":     {"--stdin", "--delimiter"},
+	"rollback_transaction":   {"--stdin", "--delimiter"},
+}
+
+// unsupportedFeature returns the first flag the named tool needs that sqlpp
+// does not support, or "" if the tool is supported
+func (h *ToolHandler) unsupportedFeature(name string) string {
+	for _, feature := range toolRequirements[name] {
+		if !h.caps.Supports(feature) {
+			return feature
+		}
+	}
+	return ""
 }
 
 // GetTools returns all available MCP tools
 func (h *ToolHandler) GetTools() []Tool {
-	return []Tool{
+	var tools []Tool
+	for _, tool := range h.allTools() {
+		if h.unsupportedFeature(tool.Name) == "" {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// UnsupportedTools returns the tools left out of GetTools because sqlpp
+// lacks a feature they need, mapped to the missing flag
+func (h *ToolHandler) UnsupportedTools() map[string]string {
+	unsupported := make(map[string]string)
+	for _, tool := range h.allTools() {
+		if feature := h.unsupportedFeature(tool.Name); feature != "" {
+			unsupported[tool.Name] = feature
+		}
+	}
+	return unsupported
+}
+
+// allTools returns every tool enabled by the handler's options, before
+// filtering by sqlpp capabilities
+func (h *ToolHandler) allTools() []Tool {
+	tools := []Tool{
 		h.createSchemaAllTool(),
 		h.createSchemaTablesTool(),
 		h.createSchemaViewsTool(),
@@ -54,38 +273,105 @@ func (h *ToolHandler) GetTools() []Tool {
 		h.createListConnectionsTool(),
 		h.createExecuteSQLTool(),
 		h.createDriversTool(),
+		h.createExplainQueryTool(),
+		h.createDescribeTableTool(),
+		h.createSampleTableRowsTool(),
+		h.createTableStatsTool(),
+		h.createSearchSchemaTool(),
+		h.createDiffSchemaTool(),
+	}
+
+	if h.results != nil {
+		tools = append(tools, h.createFetchResultPageTool())
+	}
+
+	if h.queries != nil {
+		tools = append(tools, h.createListRunningQueriesTool(), h.createCancelQueryTool())
+	}
+
+	if h.scripts != nil {
+		tools = append(tools, h.createExecuteSQLFileTool())
 	}
+
+	if h.transactions != nil {
+		tools = append(tools, h.createBeginTransactionTool(), h.createCommitTransactionTool(), h.createRollbackTransactionTool())
+	}
+
+	return tools
+}
+
+// ExecuteTool executes a tool with the given name and arguments and returns
+// its text output. The context is passed through to sqlpp so a cancelled tool
+// call stops the process.
+func (h *ToolHandler) ExecuteTool(ctx context.Context, name string, arguments map[string]interface{}) (string, error) {
+	result, err := h.ExecuteToolResult(ctx, name, arguments)
+	if err != nil {
+		return "", err
+	}
+	return result.Text, nil
 }
 
-// ExecuteTool executes a tool with the given name and arguments
-func (h *ToolHandler) ExecuteTool(name string, arguments map[string]interface{}) (string, error) {
+// ExecuteToolResult executes a tool like ExecuteTool, also returning the
+// structured content for the call. When sqlpp ran but failed, the error is an
+// *ExecutionError, or a *BatchError for a command run as several batches.
+func (h *ToolHandler) ExecuteToolResult(ctx context.Context, name string, arguments map[string]interface{}) (*ToolResult, error) {
 	h.logger.WithFields(logrus.Fields{
 		"tool":      name,
 		"arguments": arguments,
 	}).Debug("Executing tool")
 
-	var result string
+	if feature := h.unsupportedFeature(name); feature != "" {
+		return nil, fmt.Errorf("tool %s is not supported by sqlpp %s (requires %s)", name, h.caps.VersionString(), feature)
+	}
+
+	var result *ToolResult
 	var err error
 
 	switch name {
 	case "list_schema_all":
-		result, err = h.executeSchemaCommand("all", arguments)
+		result, err = h.executeSchemaCommand(ctx, "all", arguments)
 	case "list_schema_tables":
-		result, err = h.executeSchemaCommand("tables", arguments)
+		result, err = h.executeSchemaCommand(ctx, "tables", arguments)
 	case "list_schema_views":
-		result, err = h.executeSchemaCommand("views", arguments)
+		result, err = h.executeSchemaCommand(ctx, "views", arguments)
 	case "list_schema_procedures":
-		result, err = h.executeSchemaCommand("procedures", arguments)
+		result, err = h.executeSchemaCommand(ctx, "procedures", arguments)
 	case "list_schema_functions":
-		result, err = h.executeSchemaCommand("functions", arguments)
+		result, err = h.executeSchemaCommand(ctx, "functions", arguments)
 	case "list_connections":
-		result, err = h.executeListConnections(arguments)
+		result, err = h.executeListConnections(ctx, arguments)
 	case "execute_sql_command":
-		result, err = h.executeSQL(arguments)
+		result, err = h.executeSQL(ctx, arguments)
+	case "execute_sql_file":
+		result, err = h.executeSQLFile(ctx, arguments)
 	case "list_drivers":
-		result, err = h.executeDrivers(arguments)
+		result, err = h.executeDrivers(ctx, arguments)
+	case "explain_query":
+		result, err = h.executeExplainQuery(ctx, arguments)
+	case "describe_table":
+		result, err = h.executeDescribeTable(ctx, arguments)
+	case "sample_table_rows":
+		result, err = h.executeSampleTableRows(ctx, arguments)
+	case "get_table_stats":
+		result, err = h.executeTableStats(ctx, arguments)
+	case "search_schema":
+		result, err = h.executeSearchSchema(ctx, arguments)
+	case "diff_schema":
+		result, err = h.executeDiffSchema(ctx, arguments)
+	case "fetch_result_page":
+		result, err = h.executeFetchResultPage(ctx, arguments)
+	case "list_running_queries":
+		result, err = h.executeListRunningQueries(ctx, arguments)
+	case "cancel_query":
+		result, err = h.executeCancelQuery(ctx, arguments)
+	case "begin_transaction":
+		result, err = h.executeBeginTransaction(ctx, arguments)
+	case "commit_transaction":
+		result, err = h.executeCommitTransaction(ctx, arguments)
+	case "rollback_transaction":
+		result, err = h.executeRollbackTransaction(ctx, arguments)
 	default:
-		return "", fmt.Errorf("unknown tool: %s", name)
+		return nil, fmt.Errorf("unknown tool: %s", name)
 	}
 
 	// Log tool execution result
@@ -97,14 +383,14 @@ func (h *ToolHandler) ExecuteTool(name string, arguments map[string]interface{})
 	} else {
 		h.logger.WithFields(logrus.Fields{
 			"tool":        name,
-			"result_size": len(result),
+			"result_size": len(result.Text),
 		}).Debug("Tool execution succeeded")
 
 		// Log truncated result at TRACE level for detailed debugging
 		if h.logger.Level <= logrus.TraceLevel {
 			h.logger.WithFields(logrus.Fields{
 				"tool":           name,
-				"result_preview": truncateForLogging(result),
+				"result_preview": truncateForLogging(result.Text),
 			}).Trace("Tool execution result preview")
 		}
 	}
@@ -116,45 +402,50 @@ func (h *ToolHandler) ExecuteTool(name string, arguments map[string]interface{})
 func (h *ToolHandler) createSchemaAllTool() Tool {
 	schema := h.createSchemaToolSchema()
 	return Tool{
-		Name:        "list_schema_all",
-		Description: "Retrieve all schema information (tables, views, procedures, functions) from the database",
-		InputSchema: &schema,
+		Name:         "list_schema_all",
+		Description:  "Retrieve all schema information (tables, views, procedures, functions) from the database",
+		InputSchema:  &schema,
+		OutputSchema: executionOutputSchema(),
 	}
 }
 
 func (h *ToolHandler) createSchemaTablesTool() Tool {
 	schema := h.createSchemaToolSchema()
 	return Tool{
-		Name:        "list_schema_tables",
-		Description: "Retrieve table schema information from the database",
-		InputSchema: &schema,
+		Name:         "list_schema_tables",
+		Description:  "Retrieve table schema information from the database",
+		InputSchema:  &schema,
+		OutputSchema: executionOutputSchema(),
 	}
 }
 
 func (h *ToolHandler) createSchemaViewsTool() Tool {
 	schema := h.createSchemaToolSchema()
 	return Tool{
-		Name:        "list_schema_views",
-		Description: "Retrieve view schema information from the database",
-		InputSchema: &schema,
+		Name:         "list_schema_views",
+		Description:  "Retrieve view schema information from the database",
+		InputSchema:  &schema,
+		OutputSchema: executionOutputSchema(),
 	}
 }
 
 func (h *ToolHandler) createSchemaProceduresTool() Tool {
 	schema := h.createSchemaToolSchema()
 	return Tool{
-		Name:        "list_schema_procedures",
-		Description: "Retrieve stored procedure schema information from the database",
-		InputSchema: &schema,
+		Name:         "list_schema_procedures",
+		Description:  "Retrieve stored procedure schema information from the database",
+		InputSchema:  &schema,
+		OutputSchema: executionOutputSchema(),
 	}
 }
 
 func (h *ToolHandler) createSchemaFunctionsTool() Tool {
 	schema := h.createSchemaToolSchema()
 	return Tool{
-		Name:        "list_schema_functions",
-		Description: "Retrieve function schema information from the database",
-		InputSchema: &schema,
+		Name:         "list_schema_functions",
+		Description:  "Retrieve function schema information from the database",
+		InputSchema:  &schema,
+		OutputSchema: executionOutputSchema(),
 	}
 }
 
@@ -174,11 +465,24 @@ func (h *ToolHandler) createSchemaToolSchema() jsonschema.Schema {
 				Type:        "string",
 				Description: "Output format (json, table, csv, etc.)",
 			},
+			"refresh": {
+				Type:        "boolean",
+				Description: "Fetch fresh results instead of using the server's schema cache (optional)",
+			},
+			"timeout_seconds": timeoutSecondsSchema(),
 		},
 		Required: []string{"connection"},
 	}
 }
 
+// timeoutSecondsSchema describes the optional per-call timeout argument
+func timeoutSecondsSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "integer",
+		Description: "Time limit for this call in seconds, overriding the connection default (capped by the server maximum)",
+	}
+}
+
 // List connections tool
 func (h *ToolHandler) createListConnectionsTool() Tool {
 	schema := jsonschema.Schema{
@@ -186,9 +490,10 @@ func (h *ToolHandler) createListConnectionsTool() Tool {
 		Properties: map[string]*jsonschema.Schema{},
 	}
 	return Tool{
-		Name:        "list_connections",
-		Description: "List all available database connections",
-		InputSchema: &schema,
+		Name:         "list_connections",
+		Description:  "List all available database connections",
+		InputSchema:  &schema,
+		OutputSchema: executionOutputSchema(),
 	}
 }
 
@@ -203,19 +508,30 @@ func (h *ToolHandler) createExecuteSQLTool() Tool {
 			},
 			"command": {
 				Type:        "string",
-				Description: "SQL command(s) to execute. Multiple commands can be separated by GO statements",
+				Description: "SQL command(s) to execute. Batches separated by GO lines run one after another and are reported separately; GO n runs a batch n times",
 			},
 			"output": {
 				Type:        "string",
 				Description: "Output format (json, table, csv, etc.)",
 			},
+			"parameters": parametersSchema(),
+			"on_error": {
+				Type:        "string",
+				Enum:        []any{onErrorStop, onErrorContinue},
+				Description: "What to do when a batch fails: stop skips the remaining batches (default), continue runs them anyway",
+			},
+			"timeout_seconds": timeoutSecondsSchema(),
 		},
 		Required: []string{"connection", "command"},
 	}
+
+	outputSchema := executionOutputSchema()
+	outputSchema.Properties["batches"] = batchesOutputSchema()
 	return Tool{
-		Name:        "execute_sql_command",
-		Description: "Execute SQL commands against the database",
-		InputSchema: &schema,
+		Name:         "execute_sql_command",
+		Description:  "Execute SQL commands against the database",
+		InputSchema:  &schema,
+		OutputSchema: outputSchema,
 	}
 }
 
@@ -226,83 +542,298 @@ func (h *ToolHandler) createDriversTool() Tool {
 		Properties: map[string]*jsonschema.Schema{},
 	}
 	return Tool{
-		Name:        "list_drivers",
-		Description: "List all available database drivers",
-		InputSchema: &schema,
+		Name:         "list_drivers",
+		Description:  "List all available database drivers",
+		InputSchema:  &schema,
+		OutputSchema: executionOutputSchema(),
+	}
+}
+
+// Fetch result page tool
+func (h *ToolHandler) createFetchResultPageTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"handle": {
+				Type:        "string",
+				Description: "Result handle returned with a truncated tool result",
+			},
+			"offset": {
+				Type:        "integer",
+				Description: "Byte offset to start reading from (default 0); use next_offset from the previous page",
+			},
+			"limit": {
+				Type:        "integer",
+				Description: fmt.Sprintf("Maximum number of bytes to return (default %d, max %d)", defaultPageSize, maxPageSize),
+			},
+		},
+		Required: []string{"handle"},
+	}
+	return Tool{
+		Name:         "fetch_result_page",
+		Description:  "Read a page of a large result that was truncated in an earlier tool response",
+		InputSchema:  &schema,
+		OutputSchema: resultPageOutputSchema(),
+	}
+}
+
+// executionOutputSchema describes the structured content of tools that run
+// sqlpp, matching types.ExecutionMetadata
+func executionOutputSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"success": {
+				Type:        "boolean",
+				Description: "Whether sqlpp exited successfully",
+			},
+			"exit_code": {
+				Type:        "integer",
+				Description: "sqlpp exit code; -1 if the process was killed or did not exit normally",
+			},
+			"duration_ms": {
+				Type:        "integer",
+				Description: "Wall-clock execution time in milliseconds",
+			},
+			"args": {
+				Type:        "array",
+				Items:       &jsonschema.Schema{Type: "string"},
+				Description: "Arguments sqlpp was invoked with",
+			},
+			"stderr": {
+				Type:        "string",
+				Description: "Diagnostics written by sqlpp, including warnings on success",
+			},
+			"error": {
+				Type:        "string",
+				Description: "Error message when the command failed",
+			},
+			"error_code": {
+				Type: "string",
+				Enum: []any{
+					string(sqlpp.CodeUnknownConnection),
+					string(sqlpp.CodeAuthFailed),
+					string(sqlpp.CodeSyntaxError),
+					string(sqlpp.CodePermissionDenied),
+					string(sqlpp.CodeTimeout),
+					string(sqlpp.CodeCancelled),
+					string(sqlpp.CodeExecutableMissing),
+					string(sqlpp.CodeDriverError),
+					string(sqlpp.CodeConnectionFailed),
+					string(sqlpp.CodeDeadlock),
+					string(sqlpp.CodeSerialization),
+					string(sqlpp.CodeBusy),
+					string(sqlpp.CodeUnknown),
+				},
+				Description: "Failure category when the command failed",
+			},
+			"attempts": {
+				Type:        "integer",
+				Description: "Number of times the command was run, including retries of transient failures",
+			},
+			"truncated": {
+				Type:        "boolean",
+				Description: "Whether the text output was cut at the in-memory limit",
+			},
+			"output_size": {
+				Type:        "integer",
+				Description: "Total size of the sqlpp output in bytes",
+			},
+			"result_handle": {
+				Type:        "string",
+				Description: "Handle for fetch_result_page when the full output was stored",
+			},
+			"cached": {
+				Type:        "boolean",
+				Description: "Whether the result came from the schema cache instead of a new sqlpp run",
+			},
+		},
+		Required: []string{"success", "exit_code", "duration_ms", "args", "truncated", "output_size"},
+	}
+}
+
+// batchesOutputSchema describes the per-batch results of a command run as
+// several batches, matching types.BatchResult
+func batchesOutputSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "array",
+		Description: "Outcome of each GO-separated batch, present when the command had several batches",
+		Items: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"index": {Type: "integer", Description: "1-based position of the batch"},
+				"line":  {Type: "integer", Description: "Line of the command the batch starts on"},
+				"status": {
+					Type: "string",
+					Enum: []any{types.BatchSucceeded, types.BatchFailed, types.BatchSkipped},
+				},
+				"output":        {Type: "string"},
+				"duration_ms":   {Type: "integer"},
+				"exit_code":     {Type: "integer"},
+				"runs":          {Type: "integer", Description: "Times the batch ran, from the GO repeat count"},
+				"stderr":        {Type: "string"},
+				"error":         {Type: "string"},
+				"error_code":    {Type: "string"},
+				"truncated":     {Type: "boolean"},
+				"output_size":   {Type: "integer"},
+				"result_handle": {Type: "string"},
+			},
+			Required: []string{"index", "line", "status", "duration_ms", "exit_code", "runs"},
+		},
+	}
+}
+
+// resultPageOutputSchema describes the structured content of fetch_result_page,
+// matching sqlpp.ResultPage
+func resultPageOutputSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"handle":      {Type: "string"},
+			"offset":      {Type: "integer"},
+			"next_offset": {Type: "integer"},
+			"total_size":  {Type: "integer"},
+			"eof":         {Type: "boolean"},
+			"data":        {Type: "string"},
+		},
+		Required: []string{"handle", "offset", "next_offset", "total_size", "eof", "data"},
 	}
 }
 
 // Tool execution methods
-func (h *ToolHandler) executeSchemaCommand(schemaType string, arguments map[string]interface{}) (string, error) {
+func (h *ToolHandler) executeSchemaCommand(ctx context.Context, schemaType string, arguments map[string]interface{}) (*ToolResult, error) {
 	connection := h.getStringArg(arguments, "connection", "")
 	filter := h.getStringArg(arguments, "filter", "")
 	output := h.getStringArg(arguments, "output", "")
 
 	if connection == "" {
-		return "", fmt.Errorf("connection parameter is required")
+		return nil, fmt.Errorf("connection parameter is required")
 	}
 
-	result, err := h.executor.ExecuteSchemaCommand(schemaType, connection, filter, output)
+	ctx, err := h.withCallTimeout(ctx, arguments)
 	if err != nil {
-		return "", fmt.Errorf("error executing schema command: %w", err)
+		return nil, err
+	}
+	if h.getBoolArg(arguments, "refresh", false) {
+		ctx = sqlpp.WithCacheBypass(ctx)
 	}
 
-	if !result.Success {
-		return "", fmt.Errorf("sqlpp command failed: %s", result.Error)
+	result, err := h.executor.ExecuteSchemaCommand(ctx, schemaType, connection, filter, output)
+	if err != nil {
+		return nil, fmt.Errorf("error executing schema command: %w", err)
 	}
 
-	return h.formatResult(result.Output), nil
+	return h.sqlppToolResult(result)
 }
 
-func (h *ToolHandler) executeListConnections(arguments map[string]interface{}) (string, error) {
-	result, err := h.executor.ListConnections()
+func (h *ToolHandler) executeListConnections(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	result, err := h.executor.ListConnections(ctx)
 	if err != nil {
-		return "", fmt.Errorf("error listing connections: %w", err)
-	}
-
-	if !result.Success {
-		return "", fmt.Errorf("sqlpp command failed: %s", result.Error)
+		return nil, fmt.Errorf("error listing connections: %w", err)
 	}
 
-	return h.formatResult(result.Output), nil
+	return h.sqlppToolResult(result)
 }
 
-func (h *ToolHandler) executeSQL(arguments map[string]interface{}) (string, error) {
+func (h *ToolHandler) executeSQL(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
 	connection := h.getStringArg(arguments, "connection", "")
 	command := h.getStringArg(arguments, "command", "")
 	output := h.getStringArg(arguments, "output", "")
 
 	if connection == "" {
-		return "", fmt.Errorf("connection parameter is required")
+		return nil, fmt.Errorf("connection parameter is required")
 	}
 
 	if command == "" {
-		return "", fmt.Errorf("command parameter is required")
+		return nil, fmt.Errorf("command parameter is required")
 	}
 
-	result, err := h.executor.ExecuteSQLCommand(connection, command, output)
+	onError, err := h.getOnErrorArg(arguments)
 	if err != nil {
-		return "", fmt.Errorf("error executing SQL command: %w", err)
+		return nil, err
 	}
 
-	if !result.Success {
-		return "", fmt.Errorf("sqlpp command failed: %s", result.Error)
+	// Bind parameters before anything runs, so bad ones fail the call early
+	params, err := getParametersArg(arguments)
+	if err != nil {
+		return nil, err
+	}
+	if params != nil {
+		command, err = h.bindParameters(ctx, connection, command, *params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, err = h.withCallTimeout(ctx, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	run := h.sqlRunner(ctx, connection, output)
+
+	// Commands with GO separators run one batch at a time
+	if batches := sqlpp.SplitBatches(command); len(batches) > 1 || (len(batches) == 1 && batches[0].Repeat > 1) {
+		for _, batch := range batches {
+			if batch.Repeat > h.maxBatchRepeat {
+				return nil, fmt.Errorf("batch at line %d repeats %d times, more than the maximum of %d", batch.Line, batch.Repeat, h.maxBatchRepeat)
+			}
+		}
+		// timeout_seconds limits the whole command, not each of the sqlpp
+		// invocations its batches take
+		if seconds := h.getIntArg(arguments, "timeout_seconds", 0); seconds > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+			defer cancel()
+		}
+		return h.executeBatches(ctx, batches, onError, run)
+	}
+
+	result, err := run(ctx, command)
+	if err != nil {
+		return nil, fmt.Errorf("error executing SQL command: %w", err)
 	}
 
-	return h.formatResult(result.Output), nil
+	return h.sqlppToolResult(result)
 }
 
-func (h *ToolHandler) executeDrivers(arguments map[string]interface{}) (string, error) {
-	result, err := h.executor.ListDrivers()
+func (h *ToolHandler) executeDrivers(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	result, err := h.executor.ListDrivers(ctx)
 	if err != nil {
-		return "", fmt.Errorf("error listing drivers: %w", err)
+		return nil, fmt.Errorf("error listing drivers: %w", err)
 	}
 
-	if !result.Success {
-		return "", fmt.Errorf("sqlpp command failed: %s", result.Error)
+	return h.sqlppToolResult(result)
+}
+
+func (h *ToolHandler) executeFetchResultPage(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	if h.results == nil {
+		return nil, fmt.Errorf("unknown tool: fetch_result_page")
+	}
+
+	handle := h.getStringArg(arguments, "handle", "")
+	offset := h.getIntArg(arguments, "offset", 0)
+	limit := h.getIntArg(arguments, "limit", defaultPageSize)
+
+	if handle == "" {
+		return nil, fmt.Errorf("handle parameter is required")
+	}
+
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	page, err := h.results.Read(sqlpp.SessionIDFrom(ctx), handle, int64(offset), int64(limit))
+	if err != nil {
+		return nil, fmt.Errorf("error reading result page: %w", err)
+	}
+
+	formatted, err := json.MarshalIndent(page, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error formatting result page: %w", err)
 	}
 
-	return h.formatResult(result.Output), nil
+	return &ToolResult{Text: string(formatted), Structured: page}, nil
 }
 
 // Helper methods
@@ -315,6 +846,70 @@ func (h *ToolHandler) getStringArg(arguments map[string]interface{}, key, defaul
 	return defaultValue
 }
 
+func (h *ToolHandler) getBoolArg(arguments map[string]interface{}, key string, defaultValue bool) bool {
+	if val, ok := arguments[key].(bool); ok {
+		return val
+	}
+	return defaultValue
+}
+
+func (h *ToolHandler) getIntArg(arguments map[string]interface{}, key string, defaultValue int) int {
+	switch val := arguments[key].(type) {
+	case float64:
+		return int(val)
+	case int:
+		return val
+	case int64:
+		return int(val)
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return int(n)
+		}
+	}
+	return defaultValue
+}
+
+// withCallTimeout applies the optional timeout_seconds argument to ctx
+func (h *ToolHandler) withCallTimeout(ctx context.Context, arguments map[string]interface{}) (context.Context, error) {
+	seconds := h.getIntArg(arguments, "timeout_seconds", 0)
+	if seconds < 0 {
+		return nil, fmt.Errorf("timeout_seconds must not be negative")
+	}
+	if seconds == 0 {
+		return ctx, nil
+	}
+	return sqlpp.WithCallTimeout(ctx, time.Duration(seconds)*time.Second), nil
+}
+
+// sqlppToolResult converts a sqlpp result into a tool result, or an
+// *ExecutionError if the command failed
+func (h *ToolHandler) sqlppToolResult(result *types.SqlppResult) (*ToolResult, error) {
+	if !result.Success {
+		return nil, &ExecutionError{Result: result}
+	}
+
+	return &ToolResult{
+		Text:       h.formatSqlppResult(result),
+		Structured: result.Metadata(),
+	}, nil
+}
+
+// formatSqlppResult formats sqlpp output, noting when it was truncated and
+// how to retrieve the rest
+func (h *ToolHandler) formatSqlppResult(result *types.SqlppResult) string {
+	if !result.Truncated {
+		return h.formatResult(result.Output)
+	}
+
+	note := fmt.Sprintf("[output truncated: showing the first %d of %d bytes", len(result.Output), result.OutputSize)
+	if result.ResultHandle != "" {
+		note += fmt.Sprintf("; call fetch_result_page with handle %q to read the full output", result.ResultHandle)
+	}
+	note += "]"
+
+	return result.Output + "\n\n" + note
+}
+
 func (h *ToolHandler) formatResult(output string) string {
 	// Try to parse as JSON for better formatting
 	var jsonData interface{}
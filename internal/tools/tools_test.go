@@ -1,7 +1,11 @@
 package tools
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
@@ -16,27 +20,27 @@ type MockExecutor struct {
 	mock.Mock
 }
 
-func (m *MockExecutor) ExecuteSchemaCommand(schemaType, connection, filter, output string) (*types.SqlppResult, error) {
+func (m *MockExecutor) ExecuteSchemaCommand(ctx context.Context, schemaType, connection, filter, output string) (*types.SqlppResult, error) {
 	args := m.Called(schemaType, connection, filter, output)
 	return args.Get(0).(*types.SqlppResult), args.Error(1)
 }
 
-func (m *MockExecutor) ExecuteSQLCommand(connection, command, output string) (*types.SqlppResult, error) {
+func (m *MockExecutor) ExecuteSQLCommand(ctx context.Context, connection, command, output string) (*types.SqlppResult, error) {
 	args := m.Called(connection, command, output)
 	return args.Get(0).(*types.SqlppResult), args.Error(1)
 }
 
-func (m *MockExecutor) ListConnections() (*types.SqlppResult, error) {
+func (m *MockExecutor) ListConnections(ctx context.Context) (*types.SqlppResult, error) {
 	args := m.Called()
 	return args.Get(0).(*types.SqlppResult), args.Error(1)
 }
 
-func (m *MockExecutor) ListDrivers() (*types.SqlppResult, error) {
+func (m *MockExecutor) ListDrivers(ctx context.Context) (*types.SqlppResult, error) {
 	args := m.Called()
 	return args.Get(0).(*types.SqlppResult), args.Error(1)
 }
 
-func (m *MockExecutor) ValidateExecutable() error {
+func (m *MockExecutor) ValidateExecutable(ctx context.Context) error {
 	args := m.Called()
 	return args.Error(0)
 }
@@ -62,11 +66,12 @@ func TestGetTools(t *testing.T) {
 
 	tools := handler.GetTools()
 
-	assert.Len(t, tools, 8)
+	assert.Len(t, tools, 14)
 
 	toolNames := make([]string, len(tools))
 	for i, tool := range tools {
 		toolNames[i] = tool.Name
+		assert.NotNil(t, tool.OutputSchema, "tool %s has no output schema", tool.Name)
 	}
 
 	expectedTools := []string{
@@ -78,6 +83,12 @@ func TestGetTools(t *testing.T) {
 		"list_connections",
 		"execute_sql_command",
 		"list_drivers",
+		"explain_query",
+		"describe_table",
+		"sample_table_rows",
+		"get_table_stats",
+		"search_schema",
+		"diff_schema",
 	}
 
 	for _, expected := range expectedTools {
@@ -85,6 +96,46 @@ func TestGetTools(t *testing.T) {
 	}
 }
 
+func TestGetTools_Capabilities(t *testing.T) {
+	logger := logrus.New()
+	v := sqlpp.Version{Major: 1, Minor: 2}
+	caps := &sqlpp.Capabilities{
+		Version:  &v,
+		Flags:    []string{"--connection", "--output", "--stdin"},
+		Commands: []string{"@drivers", "@schema-all", "@schema-tables"},
+	}
+	handler := NewToolHandler(&MockExecutor{}, logger, WithCapabilities(caps))
+
+	toolNames := make([]string, 0)
+	for _, tool := range handler.GetTools() {
+		toolNames = append(toolNames, tool.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"list_schema_all",
+		"list_schema_tables",
+		"list_schema_views",
+		"list_schema_procedures",
+		"list_schema_functions",
+		"execute_sql_command",
+		"list_drivers",
+	}, toolNames)
+
+	assert.Equal(t, map[string]string{
+		"list_connections":  "--list-connections",
+		"explain_query":     "--list-connections",
+		"describe_table":    "--list-connections",
+		"sample_table_rows": "--list-connections",
+		"get_table_stats":   "--list-connections",
+		"search_schema":     "--list-connections",
+		"diff_schema":       "--list-connections",
+	}, handler.UnsupportedTools())
+
+	// Unsupported tools are rejected without running sqlpp
+	_, err := handler.ExecuteTool(context.Background(), "list_connections", map[string]interface{}{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tool list_connections is not supported by sqlpp 1.2.0 (requires --list-connections)")
+}
+
 func TestExecuteTool_SchemaCommand_Success(t *testing.T) {
 	mockExecutor := &MockExecutor{}
 	logger := logrus.New()
@@ -103,13 +154,46 @@ func TestExecuteTool_SchemaCommand_Success(t *testing.T) {
 		"output":     "json",
 	}
 
-	result, err := handler.ExecuteTool("list_schema_tables", arguments)
+	result, err := handler.ExecuteTool(context.Background(), "list_schema_tables", arguments)
 	require.NoError(t, err)
 	assert.Contains(t, result, "table1")
 
 	mockExecutor.AssertExpectations(t)
 }
 
+func TestExecuteTool_SchemaCommand_Cache(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	cache := sqlpp.NewCachingExecutor(mockExecutor, time.Minute, logger)
+	handler := NewToolHandler(cache, logger, WithSchemaCache(cache))
+
+	mockExecutor.On("ExecuteSchemaCommand", "tables", "main", "", "json").Return(&types.SqlppResult{Success: true, Output: `["users"]`}, nil).Times(3)
+	mockExecutor.On("ExecuteSQLCommand", "main", "CREATE TABLE orders (id int)", "").Return(&types.SqlppResult{Success: true}, nil).Once()
+
+	arguments := map[string]interface{}{"connection": "main", "output": "json"}
+	result, err := handler.ExecuteToolResult(context.Background(), "list_schema_tables", arguments)
+	require.NoError(t, err)
+	assert.False(t, result.Structured.(types.ExecutionMetadata).Cached)
+
+	result, err = handler.ExecuteToolResult(context.Background(), "list_schema_tables", arguments)
+	require.NoError(t, err)
+	assert.True(t, result.Structured.(types.ExecutionMetadata).Cached)
+	assert.Contains(t, result.Text, "users")
+
+	// refresh bypasses the cache
+	_, err = handler.ExecuteToolResult(context.Background(), "list_schema_tables", map[string]interface{}{"connection": "main", "output": "json", "refresh": true})
+	require.NoError(t, err)
+
+	// DDL on the connection invalidates it
+	_, err = handler.ExecuteTool(context.Background(), "execute_sql_command", map[string]interface{}{"connection": "main", "command": "CREATE TABLE orders (id int)"})
+	require.NoError(t, err)
+	result, err = handler.ExecuteToolResult(context.Background(), "list_schema_tables", arguments)
+	require.NoError(t, err)
+	assert.False(t, result.Structured.(types.ExecutionMetadata).Cached)
+
+	mockExecutor.AssertExpectations(t)
+}
+
 func TestExecuteTool_SchemaCommand_MissingConnection(t *testing.T) {
 	mockExecutor := &MockExecutor{}
 	logger := logrus.New()
@@ -120,7 +204,7 @@ func TestExecuteTool_SchemaCommand_MissingConnection(t *testing.T) {
 		"output": "json",
 	}
 
-	result, err := handler.ExecuteTool("list_schema_tables", arguments)
+	result, err := handler.ExecuteTool(context.Background(), "list_schema_tables", arguments)
 	require.Error(t, err)
 	assert.Empty(t, result)
 	assert.Contains(t, err.Error(), "connection parameter is required")
@@ -144,13 +228,804 @@ func TestExecuteTool_ExecuteSQL_Success(t *testing.T) {
 		"output":     "json",
 	}
 
-	result, err := handler.ExecuteTool("execute_sql_command", arguments)
+	result, err := handler.ExecuteTool(context.Background(), "execute_sql_command", arguments)
 	require.NoError(t, err)
 	assert.Contains(t, result, "test")
 
 	mockExecutor.AssertExpectations(t)
 }
 
+func TestExecuteTool_ExecuteSQL_Parameters(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "main", "driver": "mysql"}, {"name": "warehouse", "driver": "acme"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", `SELECT * FROM users WHERE name = 'O''Brien\\' AND active = TRUE`, "").Return(&types.SqlppResult{Success: true, Output: "1 row"}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", "DELETE FROM users WHERE id = 7", "").Return(&types.SqlppResult{Success: true, Output: "1 row"}, nil).Once()
+
+	_, err := handler.ExecuteTool(context.Background(), "execute_sql_command", map[string]interface{}{
+		"connection": "main",
+		"command":    "SELECT * FROM users WHERE name = ? AND active = ?",
+		"parameters": []interface{}{`O'Brien\`, true},
+	})
+	require.NoError(t, err)
+
+	// The connection's dialect is looked up once
+	_, err = handler.ExecuteTool(context.Background(), "execute_sql_command", map[string]interface{}{
+		"connection": "main",
+		"command":    "DELETE FROM users WHERE id = :id",
+		"parameters": map[string]interface{}{"id": map[string]interface{}{"type": "integer", "value": "7"}},
+	})
+	require.NoError(t, err)
+
+	// Bad parameters never reach sqlpp
+	tests := []struct {
+		parameters interface{}
+		expected   string
+	}{
+		{[]interface{}{"a", "b"}, "2 parameters given but the statement has 1 ? placeholders"},
+		{map[string]interface{}{"id": 1.0}, "no placeholder for parameters id"},
+		{[]interface{}{map[string]interface{}{"type": "date", "value": "31/01/2024"}}, "invalid date"},
+		{[]interface{}{map[string]interface{}{"value": "x"}}, "object values need a type and a value"},
+		{[]interface{}{[]interface{}{1.0}}, "arrays are not supported"},
+		{"id", "parameters must be an array or an object"},
+	}
+	for _, tt := range tests {
+		_, err := handler.ExecuteTool(context.Background(), "execute_sql_command", map[string]interface{}{
+			"connection": "main",
+			"command":    "SELECT * FROM users WHERE id = ?",
+			"parameters": tt.parameters,
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, sqlpp.ErrInvalidParameters)
+		assert.Contains(t, err.Error(), tt.expected)
+	}
+
+	// Drivers with unfamiliar names are recognised by their description
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "warehouse", "driver": "acme"}]`,
+	}, nil).Once()
+	mockExecutor.On("ListDrivers").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "acme", "description": "Acme analytics engine"}]`,
+	}, nil).Once()
+	_, err = handler.ExecuteTool(context.Background(), "execute_sql_command", map[string]interface{}{
+		"connection": "warehouse",
+		"command":    "SELECT ?",
+		"parameters": []interface{}{1.0},
+	})
+	assert.ErrorContains(t, err, `the SQL dialect of driver "acme" used by connection warehouse is not known`)
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_ExplainQuery(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "main", "driver": "postgres"}, {"name": "ledger", "driver": "godror"}]`,
+	}, nil).Twice()
+	mockExecutor.On("ExecuteSQLCommand", "main", "EXPLAIN (FORMAT JSON) SELECT * FROM orders WHERE total > 10", "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"QUERY PLAN": [{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 20.5, "Plan Rows": 1000, "Filter": "(total > 10)"}}]}]`,
+	}, nil).Once()
+
+	result, err := handler.ExecuteToolResult(context.Background(), "explain_query", map[string]interface{}{
+		"connection": "main",
+		"query":      "SELECT * FROM orders WHERE total > ?;",
+		"parameters": []interface{}{10.0},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "-> Seq Scan on orders (rows=1000 cost=20.5): Filter: (total > 10)", result.Text)
+
+	plan, ok := result.Structured.(sqlpp.QueryPlan)
+	require.True(t, ok)
+	assert.Equal(t, sqlpp.DialectPostgres, plan.Dialect)
+	require.Len(t, plan.Nodes, 1)
+	assert.Equal(t, "orders", plan.Nodes[0].Relation)
+
+	// Unsupported dialects and multiple statements fail before sqlpp runs
+	_, err = handler.ExecuteTool(context.Background(), "explain_query", map[string]interface{}{
+		"connection": "ledger",
+		"query":      "SELECT * FROM accounts",
+	})
+	assert.ErrorIs(t, err, sqlpp.ErrExplainUnsupported)
+
+	_, err = handler.ExecuteTool(context.Background(), "explain_query", map[string]interface{}{
+		"connection": "main",
+		"query":      "DELETE FROM orders; SELECT 1",
+	})
+	assert.ErrorContains(t, err, "query has 2 statements")
+
+	// Output that is not a plan is reported with the output
+	mockExecutor.On("ExecuteSQLCommand", "main", "EXPLAIN (FORMAT JSON) SELECT 1", "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  "QUERY PLAN\n----------\nResult",
+	}, nil).Once()
+	_, err = handler.ExecuteTool(context.Background(), "explain_query", map[string]interface{}{
+		"connection": "main",
+		"query":      "SELECT 1",
+	})
+	assert.ErrorContains(t, err, "error reading postgres query plan")
+	assert.ErrorContains(t, err, "----------\nResult")
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_DescribeTable(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	queries, err := sqlpp.DescribeTableQueries(sqlpp.DialectSQLite, "", "orders")
+	require.NoError(t, err)
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "main", "driver": "sqlite3"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", queries.Columns, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "id", "type": "INTEGER", "nullable": 0}, {"name": "customer_id", "type": "INTEGER", "nullable": 1}, {"name": "status", "type": "TEXT", "nullable": 0, "default_value": "'new'"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", queries.Constraints, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output: `[{"constraint_type": "PRIMARY KEY", "position": 1, "column_name": "id"},
+			{"constraint_type": "FOREIGN KEY", "position": 1, "column_name": "customer_id", "ref_table": "customers", "ref_column": "id", "on_delete": "CASCADE", "on_update": "NO ACTION"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", queries.Indexes, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"index_name": "orders_customer", "is_unique": 0, "is_primary": 0, "position": 1, "column_name": "customer_id"}]`,
+	}, nil).Once()
+
+	result, err := handler.ExecuteToolResult(context.Background(), "describe_table", map[string]interface{}{
+		"connection": "main",
+		"table":      "orders",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `Table orders
+
+Columns:
+  id INTEGER NOT NULL
+  customer_id INTEGER
+  status TEXT NOT NULL DEFAULT 'new'
+
+Primary key: (id)
+
+Foreign keys:
+  (customer_id) -> customers (id) ON DELETE CASCADE ON UPDATE NO ACTION
+
+Indexes:
+  orders_customer (customer_id)`, result.Text)
+
+	desc, ok := result.Structured.(*sqlpp.TableDescription)
+	require.True(t, ok)
+	assert.Len(t, desc.Columns, 3)
+	assert.Equal(t, []string{"id"}, desc.PrimaryKey.Columns)
+
+	// A failed catalog query is reported as a sqlpp failure
+	mockExecutor.On("ExecuteSQLCommand", "main", mock.Anything, "json").Return(&types.SqlppResult{
+		Success: false,
+		Error:   "no such table: pragma_table_info",
+	}, nil).Once()
+	_, err = handler.ExecuteTool(context.Background(), "describe_table", map[string]interface{}{
+		"connection": "main",
+		"table":      "orders",
+	})
+	assert.ErrorContains(t, err, "no such table: pragma_table_info")
+
+	_, err = handler.ExecuteTool(context.Background(), "describe_table", map[string]interface{}{
+		"connection": "main",
+	})
+	assert.ErrorContains(t, err, "table parameter is required")
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_DescribeTable_OldMySQL(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	queries, err := sqlpp.DescribeTableQueries(sqlpp.DialectMySQL, "", "orders")
+	require.NoError(t, err)
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "main", "driver": "mysql"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", queries.Columns, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "id", "type": "int", "nullable": 0}]`,
+	}, nil).Once()
+	// MySQL before 8.0.16 has no information_schema.check_constraints
+	mockExecutor.On("ExecuteSQLCommand", "main", queries.Constraints, "json").Return(&types.SqlppResult{
+		Success: false,
+		Error:   "Unknown table 'CHECK_CONSTRAINTS' in information_schema",
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", queries.ConstraintsFallback, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"constraint_name": "PRIMARY", "constraint_type": "PRIMARY KEY", "position": 1, "column_name": "id"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", queries.Indexes, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[]`,
+	}, nil).Once()
+
+	result, err := handler.ExecuteToolResult(context.Background(), "describe_table", map[string]interface{}{
+		"connection": "main",
+		"table":      "orders",
+	})
+	require.NoError(t, err)
+	desc, ok := result.Structured.(*sqlpp.TableDescription)
+	require.True(t, ok)
+	assert.Equal(t, []string{"id"}, desc.PrimaryKey.Columns)
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_SampleTableRows(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger, WithSampleLimits(0, 50))
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "main", "driver": "postgres"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", `SELECT "id", "Email" FROM "orders" LIMIT 10`, "csv").Return(&types.SqlppResult{
+		Success: true,
+		Output:  "id,Email\n1,a@example.com",
+	}, nil).Once()
+
+	result, err := handler.ExecuteTool(context.Background(), "sample_table_rows", map[string]interface{}{
+		"connection": "main",
+		"table":      "orders",
+		"columns":    []interface{}{"id", "Email"},
+		"output":     "csv",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "id,Email\n1,a@example.com", result)
+
+	// Random samples of large tables read a fraction of them, and limits
+	// above the cap are capped
+	estimate, err := sqlpp.RowEstimateQuery(sqlpp.DialectPostgres, "sales", "orders")
+	require.NoError(t, err)
+	mockExecutor.On("ExecuteSQLCommand", "main", estimate, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"row_estimate": 5000000}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "main", `SELECT * FROM "sales"."orders" TABLESAMPLE SYSTEM (0.01) ORDER BY random() LIMIT 50`, "").Return(&types.SqlppResult{
+		Success: true,
+		Output:  "[]",
+	}, nil).Once()
+
+	_, err = handler.ExecuteTool(context.Background(), "sample_table_rows", map[string]interface{}{
+		"connection": "main",
+		"schema":     "sales",
+		"table":      "orders",
+		"limit":      1000.0,
+		"mode":       "random",
+	})
+	require.NoError(t, err)
+
+	_, err = handler.ExecuteTool(context.Background(), "sample_table_rows", map[string]interface{}{
+		"connection": "main",
+		"table":      "orders",
+		"columns":    "id",
+	})
+	assert.ErrorContains(t, err, "columns must be an array of column names")
+
+	_, err = handler.ExecuteTool(context.Background(), "sample_table_rows", map[string]interface{}{
+		"connection": "main",
+		"table":      "orders",
+		"limit":      0.0,
+	})
+	assert.ErrorContains(t, err, "limit must be at least 1")
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_TableStats(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "main", "driver": "postgres"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSchemaCommand", "tables", "main", "order%", "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"table_name": "orders"}, {"table_name": "order_lines"}]`,
+	}, nil).Once()
+
+	statsQuery, err := sqlpp.TableStatsQuery(sqlpp.DialectPostgres, "", []string{"orders", "order_lines"})
+	require.NoError(t, err)
+	mockExecutor.On("ExecuteSQLCommand", "main", statsQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output: `[{"table_schema": "public", "table_name": "order_lines", "row_estimate": -1, "total_bytes": 8192, "index_bytes": 0, "last_analyzed": null, "last_vacuumed": null},
+			{"table_schema": "public", "table_name": "orders", "row_estimate": 1250000, "total_bytes": 335544320, "index_bytes": 52428800, "last_analyzed": "2024-05-01T10:00:00Z", "last_vacuumed": null}]`,
+	}, nil).Once()
+
+	countQuery, err := sqlpp.ExactCountQuery(sqlpp.DialectPostgres, "", []string{"order_lines", "orders"})
+	require.NoError(t, err)
+	mockExecutor.On("ExecuteSQLCommand", "main", countQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"table_name": "order_lines", "row_count": 3}, {"table_name": "orders", "row_count": 1249873}]`,
+	}, nil).Once()
+
+	result, err := handler.ExecuteToolResult(context.Background(), "get_table_stats", map[string]interface{}{
+		"connection":  "main",
+		"filter":      "order%",
+		"exact_count": true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "public.order_lines: 3 rows exactly, 8.0 KiB total, 0 B indexes\n"+
+		"public.orders: ~1250000 rows, 1249873 rows exactly, 320.0 MiB total, 50.0 MiB indexes, analyzed 2024-05-01T10:00:00Z", result.Text)
+
+	stats, ok := result.Structured.(tableStatsResult)
+	require.True(t, ok)
+	require.Len(t, stats.Tables, 2)
+	assert.Nil(t, stats.Tables[0].RowEstimate, "never analysed")
+	assert.Equal(t, int64(1250000), *stats.Tables[1].RowEstimate)
+
+	// A single table skips the listing
+	single, err := sqlpp.TableStatsQuery(sqlpp.DialectPostgres, "", []string{"missing"})
+	require.NoError(t, err)
+	mockExecutor.On("ExecuteSQLCommand", "main", single, "json").Return(&types.SqlppResult{Success: true, Output: "[]"}, nil).Once()
+	_, err = handler.ExecuteTool(context.Background(), "get_table_stats", map[string]interface{}{
+		"connection": "main",
+		"table":      "missing",
+	})
+	assert.ErrorContains(t, err, "table missing not found")
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_SearchSchema(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "main", "driver": "postgres"}, {"name": "local", "driver": "sqlite3"}, {"name": "legacy", "driver": "odbc"}]`,
+	}, nil)
+	mockExecutor.On("ListDrivers").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "odbc", "description": "ODBC bridge"}]`,
+	}, nil).Once()
+
+	pgQuery, err := sqlpp.SearchCatalogQuery(sqlpp.DialectPostgres)
+	require.NoError(t, err)
+	mockExecutor.On("ExecuteSQLCommand", "main", pgQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output: `[{"object_type": "table", "object_schema": "public", "object_name": "customers", "column_name": null, "comment": null},
+			{"object_type": "column", "object_schema": "public", "object_name": "customers", "column_name": "email_address", "comment": null},
+			{"object_type": "column", "object_schema": "public", "object_name": "orders", "column_name": "total", "comment": "Order total in the customer's currency"}]`,
+	}, nil).Once()
+
+	sqliteQuery, err := sqlpp.SearchCatalogQuery(sqlpp.DialectSQLite)
+	require.NoError(t, err)
+	mockExecutor.On("ExecuteSQLCommand", "local", sqliteQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"object_type": "table", "object_schema": "main", "object_name": "Customer", "column_name": null, "comment": null}]`,
+	}, nil).Once()
+
+	result, err := handler.ExecuteToolResult(context.Background(), "search_schema", map[string]interface{}{
+		"term": "customer",
+	})
+	require.NoError(t, err)
+
+	search, ok := result.Structured.(schemaSearchResult)
+	require.True(t, ok)
+	assert.Equal(t, []string{"main", "local"}, search.Searched)
+	require.Len(t, search.Errors, 1, "the ODBC connection has no known dialect")
+	assert.Equal(t, "legacy", search.Errors[0].Connection)
+
+	require.GreaterOrEqual(t, len(search.Matches), 3)
+	assert.Equal(t, "local", search.Matches[0].Connection)
+	assert.Equal(t, "Customer", search.Matches[0].Name)
+	assert.Equal(t, 1.0, search.Matches[0].Score)
+	assert.Equal(t, "customers", search.Matches[1].Name)
+	assert.Equal(t, sqlpp.ObjectTable, search.Matches[1].Type)
+	assert.Contains(t, result.Text, "main: table public.customers (0.90)")
+	assert.Contains(t, result.Text, "Not searched: legacy")
+
+	last := search.Matches[len(search.Matches)-1]
+	assert.Equal(t, "total", last.Column)
+	assert.Equal(t, "comment", last.MatchedOn)
+	mockExecutor.AssertNumberOfCalls(t, "ListConnections", 1)
+
+	// Only the named connections are searched
+	mockExecutor.On("ExecuteSQLCommand", "local", sqliteQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"object_type": "table", "object_schema": "main", "object_name": "Customer", "column_name": null, "comment": null}]`,
+	}, nil).Once()
+	result, err = handler.ExecuteToolResult(context.Background(), "search_schema", map[string]interface{}{
+		"term":        "custmer",
+		"connections": []interface{}{"local"},
+		"limit":       1.0,
+	})
+	require.NoError(t, err)
+	search = result.Structured.(schemaSearchResult)
+	require.Len(t, search.Matches, 1)
+	assert.Equal(t, "Customer", search.Matches[0].Name, "one typo is allowed")
+
+	_, err = handler.ExecuteTool(context.Background(), "search_schema", map[string]interface{}{
+		"term":        "customer",
+		"connections": []interface{}{"missing"},
+	})
+	assert.ErrorContains(t, err, "missing (connection missing is not in list_connections)")
+
+	_, err = handler.ExecuteTool(context.Background(), "search_schema", map[string]interface{}{})
+	assert.ErrorContains(t, err, "term parameter is required")
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_DiffSchema(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"name": "prod", "driver": "postgres"}, {"name": "staging", "driver": "postgres"}]`,
+	}, nil)
+
+	objectsQuery, err := sqlpp.SchemaObjectsQuery(sqlpp.DialectPostgres, "")
+	require.NoError(t, err)
+	mockExecutor.On("ExecuteSQLCommand", "prod", objectsQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"object_type": "function", "name": "order_total"}, {"object_type": "table", "name": "legacy"}, {"object_type": "table", "name": "orders"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "staging", objectsQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output: `[{"object_type": "function", "name": "order_total"}, {"object_type": "function", "name": "order_total"},
+			{"object_type": "table", "name": "audit_log"}, {"object_type": "table", "name": "orders"}, {"object_type": "view", "name": "recent_orders"}]`,
+	}, nil).Once()
+
+	columnsQuery, err := sqlpp.SchemaColumnsQuery(sqlpp.DialectPostgres, "")
+	require.NoError(t, err)
+	indexesQuery, err := sqlpp.SchemaIndexesQuery(sqlpp.DialectPostgres, "")
+	require.NoError(t, err)
+	mockExecutor.On("ExecuteSQLCommand", "prod", columnsQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output: `[{"table_name": "legacy", "name": "id", "type": "integer", "nullable": false, "default_value": null},
+			{"table_name": "orders", "name": "id", "type": "integer", "nullable": false, "default_value": null},
+			{"table_name": "orders", "name": "total", "type": "numeric(10,2)", "nullable": true, "default_value": null}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "staging", columnsQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output: `[{"table_name": "audit_log", "name": "id", "type": "bigint", "nullable": false, "default_value": null},
+			{"table_name": "orders", "name": "id", "type": "integer", "nullable": false, "default_value": null},
+			{"table_name": "orders", "name": "total", "type": "numeric(12,2)", "nullable": false, "default_value": "0"},
+			{"table_name": "orders", "name": "created_at", "type": "timestamp with time zone", "nullable": false, "default_value": "now()"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "prod", indexesQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output:  `[{"table_name": "orders", "index_name": "orders_pkey", "is_unique": true, "is_primary": true, "position": 1, "column_name": "id"}]`,
+	}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "staging", indexesQuery, "json").Return(&types.SqlppResult{
+		Success: true,
+		Output: `[{"table_name": "orders", "index_name": "orders_created_at", "is_unique": false, "is_primary": false, "position": 1, "column_name": "created_at"},
+			{"table_name": "orders", "index_name": "orders_pkey", "is_unique": true, "is_primary": true, "position": 1, "column_name": "id"}]`,
+	}, nil).Once()
+
+	result, err := handler.ExecuteToolResult(context.Background(), "diff_schema", map[string]interface{}{
+		"source": "prod",
+		"target": "staging",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Schema differences from prod to staging: 4 added, 1 removed, 1 changed\n"+
+		"\nAdded (only in staging):\n"+
+		"  table audit_log\n"+
+		"  view recent_orders\n"+
+		"  column orders.created_at timestamp with time zone NOT NULL DEFAULT now()\n"+
+		"  index orders.orders_created_at (created_at)\n"+
+		"\nRemoved (only in prod):\n"+
+		"  table legacy\n"+
+		"\nChanged:\n"+
+		"  column orders.total: type numeric(10,2) -> numeric(12,2); nullable true -> false; default (none) -> 0", result.Text)
+
+	diff, ok := result.Structured.(schemaDiffResult)
+	require.True(t, ok)
+	assert.Equal(t, "prod", diff.Source)
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, sqlpp.FieldChange{Field: "type", Source: "numeric(10,2)", Target: "numeric(12,2)"}, diff.Changed[0].Changes[0])
+
+	_, err = handler.ExecuteTool(context.Background(), "diff_schema", map[string]interface{}{
+		"source": "prod",
+		"target": "prod",
+	})
+	assert.ErrorContains(t, err, "source and target are the same schema")
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_DiffSchema_SameConnection(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	handler := NewToolHandler(mockExecutor, logrus.New())
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{Success: true, Output: `[{"name": "main", "driver": "postgres"}]`}, nil)
+
+	// Each side reads the objects, columns and indexes of its own schema
+	outputs := map[string][3]string{
+		"app": {
+			`[{"object_type": "table", "name": "orders"}]`,
+			`[{"table_name": "orders", "name": "id", "type": "integer", "nullable": false}]`,
+			`[]`,
+		},
+		"app_next": {
+			`[{"object_type": "table", "name": "orders"}, {"object_type": "table", "name": "refunds"}]`,
+			`[{"table_name": "orders", "name": "id", "type": "integer", "nullable": false},
+				{"table_name": "refunds", "name": "id", "type": "integer", "nullable": false}]`,
+			`[]`,
+		},
+	}
+	for schema, output := range outputs {
+		objectsQuery, err := sqlpp.SchemaObjectsQuery(sqlpp.DialectPostgres, schema)
+		require.NoError(t, err)
+		require.Contains(t, objectsQuery, "n.nspname = '"+schema+"'")
+		columnsQuery, err := sqlpp.SchemaColumnsQuery(sqlpp.DialectPostgres, schema)
+		require.NoError(t, err)
+		indexesQuery, err := sqlpp.SchemaIndexesQuery(sqlpp.DialectPostgres, schema)
+		require.NoError(t, err)
+		for i, query := range []string{objectsQuery, columnsQuery, indexesQuery} {
+			mockExecutor.On("ExecuteSQLCommand", "main", query, "json").Return(&types.SqlppResult{Success: true, Output: output[i]}, nil).Once()
+		}
+	}
+
+	text, err := handler.ExecuteTool(context.Background(), "diff_schema", map[string]interface{}{
+		"source":        "main",
+		"target":        "main",
+		"source_schema": "app",
+		"target_schema": "app_next",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Schema differences from main to main: 1 added, 0 removed, 0 changed\n"+
+		"\nAdded (only in main):\n"+
+		"  table refunds", text)
+
+	mockExecutor.AssertNotCalled(t, "ExecuteSchemaCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_ExecuteSQL_Batches(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	mockExecutor.On("ExecuteSQLCommand", "test-conn", "CREATE TABLE t (id int)", "").Return(&types.SqlppResult{Success: true, DurationMs: 5, Attempts: 1}, nil).Once()
+	mockExecutor.On("ExecuteSQLCommand", "test-conn", "INSERT INTO t VALUES (1)", "").Return(&types.SqlppResult{Success: true, Output: "1 row affected", DurationMs: 2, Attempts: 1}, nil).Twice()
+	mockExecutor.On("ExecuteSQLCommand", "test-conn", "SELECT count(*) FROM t", "").Return(&types.SqlppResult{Success: true, Output: "2", DurationMs: 1, Attempts: 1}, nil).Once()
+
+	result, err := handler.ExecuteToolResult(context.Background(), "execute_sql_command", map[string]interface{}{
+		"connection": "test-conn",
+		"command":    "CREATE TABLE t (id int)\nGO\nINSERT INTO t VALUES (1)\nGO 2\nSELECT count(*) FROM t\n",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result.Text, "-- batch 2 of 3 (line 3): succeeded in 4ms (2 runs)\n1 row affected\n1 row affected")
+
+	execution, ok := result.Structured.(*types.BatchExecution)
+	require.True(t, ok)
+	assert.True(t, execution.Success)
+	assert.Equal(t, int64(10), execution.DurationMs)
+	assert.Equal(t, 4, execution.Attempts)
+	require.Len(t, execution.Batches, 3)
+	assert.Equal(t, types.BatchResult{Index: 3, Line: 5, Status: types.BatchSucceeded, Output: "2", DurationMs: 1, Runs: 1}, execution.Batches[2])
+
+	// GO n counts are capped
+	_, err = handler.ExecuteToolResult(context.Background(), "execute_sql_command", map[string]interface{}{
+		"connection": "test-conn",
+		"command":    "INSERT INTO t VALUES (1)\nGO 999999999\n",
+	})
+	assert.EqualError(t, err, "batch at line 1 repeats 999999999 times, more than the maximum of 100")
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_ExecuteSQL_BatchFailure(t *testing.T) {
+	command := "SELECT 1\nGO\nSELECT broken\nGO\nSELECT 3\n"
+	failure := &types.SqlppResult{Success: false, ExitCode: 1, Output: "ERROR: syntax error at or near broken", Error: "syntax error at or near broken", ErrorCode: "syntax_error"}
+
+	t.Run("stop", func(t *testing.T) {
+		mockExecutor := &MockExecutor{}
+		handler := NewToolHandler(mockExecutor, logrus.New())
+		mockExecutor.On("ExecuteSQLCommand", "test-conn", "SELECT 1", "").Return(&types.SqlppResult{Success: true, Output: "1"}, nil).Once()
+		mockExecutor.On("ExecuteSQLCommand", "test-conn", "SELECT broken", "").Return(failure, nil).Once()
+
+		_, err := handler.ExecuteToolResult(context.Background(), "execute_sql_command", map[string]interface{}{
+			"connection": "test-conn",
+			"command":    command,
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "1 of 3 batches failed")
+		assert.Contains(t, err.Error(), "-- batch 2 of 3 (line 3): failed in 0ms [syntax_error]: syntax error at or near broken\nERROR: syntax error at or near broken")
+
+		var batchErr *BatchError
+		require.ErrorAs(t, err, &batchErr)
+		execution := batchErr.Execution
+		assert.False(t, execution.Success)
+		assert.Equal(t, 1, execution.ExitCode)
+		assert.Equal(t, "syntax_error", execution.ErrorCode)
+		assert.Equal(t, []string{types.BatchSucceeded, types.BatchFailed, types.BatchSkipped},
+			[]string{execution.Batches[0].Status, execution.Batches[1].Status, execution.Batches[2].Status})
+		assert.Equal(t, "1", execution.Batches[0].Output)
+
+		mockExecutor.AssertExpectations(t)
+	})
+
+	t.Run("continue", func(t *testing.T) {
+		mockExecutor := &MockExecutor{}
+		handler := NewToolHandler(mockExecutor, logrus.New())
+		mockExecutor.On("ExecuteSQLCommand", "test-conn", "SELECT 1", "").Return(&types.SqlppResult{Success: true, Output: "1"}, nil).Once()
+		mockExecutor.On("ExecuteSQLCommand", "test-conn", "SELECT broken", "").Return(failure, nil).Once()
+		mockExecutor.On("ExecuteSQLCommand", "test-conn", "SELECT 3", "").Return((*types.SqlppResult)(nil), context.DeadlineExceeded).Once()
+
+		_, err := handler.ExecuteToolResult(context.Background(), "execute_sql_command", map[string]interface{}{
+			"connection": "test-conn",
+			"command":    command,
+			"on_error":   "continue",
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "2 of 3 batches failed")
+
+		var batchErr *BatchError
+		require.ErrorAs(t, err, &batchErr)
+		last := batchErr.Execution.Batches[2]
+		assert.Equal(t, types.BatchFailed, last.Status)
+		assert.Equal(t, -1, last.ExitCode)
+		assert.Equal(t, "timeout", last.ErrorCode)
+
+		mockExecutor.AssertExpectations(t)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		handler := NewToolHandler(&MockExecutor{}, logrus.New())
+		_, err := handler.ExecuteToolResult(context.Background(), "execute_sql_command", map[string]interface{}{
+			"connection": "test-conn",
+			"command":    command,
+			"on_error":   "ignore",
+		})
+		assert.EqualError(t, err, `on_error must be "stop" or "continue"`)
+	})
+}
+
+func TestExecuteTool_ExecuteSQLFile(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "reports"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "reports", "daily.sql"), []byte("SELECT * FROM sales WHERE day = ${day}"), 0644))
+
+	lib, err := sqlpp.NewScriptLibrary([]string{root})
+	require.NoError(t, err)
+
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger, WithScriptLibrary(lib))
+
+	toolNames := make([]string, 0)
+	for _, tool := range handler.GetTools() {
+		toolNames = append(toolNames, tool.Name)
+	}
+	assert.Contains(t, toolNames, "execute_sql_file")
+
+	expectedResult := &types.SqlppResult{
+		Success: true,
+		Output:  `{"rows": [{"total": 42}]}`,
+	}
+
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{Success: true, Output: `[{"name": "test-conn", "driver": "mysql"}]`}, nil)
+	mockExecutor.On("ExecuteSQLCommand", "test-conn", "#define day '2024-01-31'\nSELECT * FROM sales WHERE day = ${day}", "json").Return(expectedResult, nil)
+
+	result, err := handler.ExecuteTool(context.Background(), "execute_sql_file", map[string]interface{}{
+		"connection": "test-conn",
+		"path":       "reports/daily.sql",
+		"variables":  map[string]interface{}{"day": "2024-01-31"},
+		"output":     "json",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result, "42")
+
+	// Paths outside the script roots are rejected without running sqlpp
+	_, err = handler.ExecuteTool(context.Background(), "execute_sql_file", map[string]interface{}{
+		"connection": "test-conn",
+		"path":       "../../etc/passwd.sql",
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, sqlpp.ErrScriptOutsideRoot)
+
+	_, err = handler.ExecuteTool(context.Background(), "execute_sql_file", map[string]interface{}{
+		"connection": "test-conn",
+		"path":       "reports/daily.sql",
+		"variables":  map[string]interface{}{"day": []interface{}{"a"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "variable day must be a string, number or boolean")
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_Transactions(t *testing.T) {
+	// A mock sqlpp that answers delimited statements, as used for transactions
+	mockSqlpp := filepath.Join(t.TempDir(), "mock-sqlpp")
+	mockScript := `#!/bin/bash
+while IFS= read -r line; do
+	if [[ "$line" == "--end--" ]]; then
+		echo "in transaction: $batch"
+		echo "--end-- 0"
+		batch=""
+	else
+		batch="$batch$line"
+	fi
+done
+`
+	require.NoError(t, os.WriteFile(mockSqlpp, []byte(mockScript), 0755))
+
+	logger := logrus.New()
+	transactions := sqlpp.NewTransactionManager(sqlpp.NewExecutor(mockSqlpp, 30, logger), sqlpp.TransactionOptions{
+		IdleTimeout:       time.Minute,
+		Delimiter:         "--end--",
+		BeginStatement:    "BEGIN",
+		CommitStatement:   "COMMIT",
+		RollbackStatement: "ROLLBACK",
+	})
+	defer transactions.Close()
+
+	mockExecutor := &MockExecutor{}
+	handler := NewToolHandler(mockExecutor, logger, WithTransactions(transactions))
+	ctx := sqlpp.WithSessionID(context.Background(), "session-1")
+
+	result, err := handler.ExecuteToolResult(ctx, "begin_transaction", map[string]interface{}{"connection": "main"})
+	require.NoError(t, err)
+	assert.Contains(t, result.Text, "Transaction tx-1 started on main")
+
+	// SQL on the transaction's connection runs in it; other connections and
+	// sessions are unaffected
+	text, err := handler.ExecuteTool(ctx, "execute_sql_command", map[string]interface{}{
+		"connection": "main",
+		"command":    "UPDATE t SET x = 1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "in transaction: UPDATE t SET x = 1", text)
+
+	mockExecutor.On("ExecuteSQLCommand", "other", "SELECT 1", "").Return(&types.SqlppResult{Success: true, Output: "outside"}, nil).Once()
+	text, err = handler.ExecuteTool(ctx, "execute_sql_command", map[string]interface{}{"connection": "other", "command": "SELECT 1"})
+	require.NoError(t, err)
+	assert.Equal(t, "outside", text)
+
+	// Helper queries such as plans run outside the transaction, so one that
+	// fails leaves it open
+	mockExecutor.On("ListConnections").Return(&types.SqlppResult{Success: true, Output: `[{"name": "main", "driver": "postgres"}]`}, nil)
+	mockExecutor.On("ExecuteSQLCommand", "main", "EXPLAIN (FORMAT JSON) SELECT * FROM t", "json").Return(&types.SqlppResult{Success: false, ExitCode: 1, Error: `relation "t" does not exist`}, nil).Once()
+	_, err = handler.ExecuteTool(ctx, "explain_query", map[string]interface{}{"connection": "main", "query": "SELECT * FROM t"})
+	assert.ErrorContains(t, err, `relation "t" does not exist`)
+
+	mockExecutor.On("ExecuteSQLCommand", "main", "SELECT 2", "").Return(&types.SqlppResult{Success: true, Output: "outside"}, nil).Once()
+	text, err = handler.ExecuteTool(sqlpp.WithSessionID(context.Background(), "session-2"), "execute_sql_command", map[string]interface{}{"connection": "main", "command": "SELECT 2"})
+	require.NoError(t, err)
+	assert.Equal(t, "outside", text)
+
+	result, err = handler.ExecuteToolResult(ctx, "commit_transaction", map[string]interface{}{"connection": "main"})
+	require.NoError(t, err)
+	assert.Equal(t, "Transaction tx-1 on main committed after 1 statements", result.Text)
+
+	_, err = handler.ExecuteToolResult(ctx, "rollback_transaction", map[string]interface{}{"connection": "main"})
+	assert.ErrorIs(t, err, sqlpp.ErrNoTransaction)
+
+	mockExecutor.AssertExpectations(t)
+}
+
 func TestExecuteTool_ExecuteSQL_MissingParameters(t *testing.T) {
 	mockExecutor := &MockExecutor{}
 	logger := logrus.New()
@@ -162,7 +1037,7 @@ func TestExecuteTool_ExecuteSQL_MissingParameters(t *testing.T) {
 		"output":  "json",
 	}
 
-	result, err := handler.ExecuteTool("execute_sql_command", arguments)
+	result, err := handler.ExecuteTool(context.Background(), "execute_sql_command", arguments)
 	require.Error(t, err)
 	assert.Empty(t, result)
 	assert.Contains(t, err.Error(), "connection parameter is required")
@@ -173,12 +1048,28 @@ func TestExecuteTool_ExecuteSQL_MissingParameters(t *testing.T) {
 		"output":     "json",
 	}
 
-	result, err = handler.ExecuteTool("execute_sql_command", arguments)
+	result, err = handler.ExecuteTool(context.Background(), "execute_sql_command", arguments)
 	require.Error(t, err)
 	assert.Empty(t, result)
 	assert.Contains(t, err.Error(), "command parameter is required")
 }
 
+func TestExecuteTool_ExecuteSQL_NegativeTimeout(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	_, err := handler.ExecuteTool(context.Background(), "execute_sql_command", map[string]interface{}{
+		"connection":      "main",
+		"command":         "SELECT 1",
+		"timeout_seconds": float64(-5),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout_seconds must not be negative")
+
+	mockExecutor.AssertNotCalled(t, "ExecuteSQLCommand", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestExecuteTool_ListConnections_WithDefaultConnection(t *testing.T) {
 	mockExecutor := &MockExecutor{}
 	logger := logrus.New()
@@ -196,7 +1087,7 @@ func TestExecuteTool_ListConnections_WithDefaultConnection(t *testing.T) {
 
 	mockExecutor.On("ListConnections").Return(expectedResult, nil)
 
-	result, err := handler.ExecuteTool("list_connections", map[string]interface{}{})
+	result, err := handler.ExecuteTool(context.Background(), "list_connections", map[string]interface{}{})
 	require.NoError(t, err)
 
 	// Verify we have connections returned
@@ -226,7 +1117,7 @@ func TestExecuteTool_ListConnections_EmptyResult(t *testing.T) {
 
 	mockExecutor.On("ListConnections").Return(expectedResult, nil)
 
-	result, err := handler.ExecuteTool("list_connections", map[string]interface{}{})
+	result, err := handler.ExecuteTool(context.Background(), "list_connections", map[string]interface{}{})
 	require.NoError(t, err)
 	assert.Equal(t, "[]", result)
 
@@ -245,7 +1136,7 @@ func TestExecuteTool_ListDrivers_Success(t *testing.T) {
 
 	mockExecutor.On("ListDrivers").Return(expectedResult, nil)
 
-	result, err := handler.ExecuteTool("list_drivers", map[string]interface{}{})
+	result, err := handler.ExecuteTool(context.Background(), "list_drivers", map[string]interface{}{})
 	require.NoError(t, err)
 	assert.Contains(t, result, "mysql")
 
@@ -257,7 +1148,7 @@ func TestExecuteTool_UnknownTool(t *testing.T) {
 	logger := logrus.New()
 	handler := NewToolHandler(mockExecutor, logger)
 
-	result, err := handler.ExecuteTool("unknown_tool", map[string]interface{}{})
+	result, err := handler.ExecuteTool(context.Background(), "unknown_tool", map[string]interface{}{})
 	require.Error(t, err)
 	assert.Empty(t, result)
 	assert.Contains(t, err.Error(), "unknown tool")
@@ -275,14 +1166,215 @@ func TestExecuteTool_SqlppFailure(t *testing.T) {
 
 	mockExecutor.On("ListConnections").Return(expectedResult, nil)
 
-	result, err := handler.ExecuteTool("list_connections", map[string]interface{}{})
+	result, err := handler.ExecuteTool(context.Background(), "list_connections", map[string]interface{}{})
 	require.Error(t, err)
 	assert.Empty(t, result)
 	assert.Contains(t, err.Error(), "Connection failed")
 
+	var execErr *ExecutionError
+	require.ErrorAs(t, err, &execErr)
+	assert.Same(t, expectedResult, execErr.Result)
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_SqlppFailure_ErrorCode(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	expectedResult := &types.SqlppResult{
+		Success:   false,
+		ExitCode:  1,
+		Error:     "connection 'reporting' not found",
+		ErrorCode: "unknown_connection",
+	}
+
+	mockExecutor.On("ExecuteSQLCommand", "reporting", "SELECT 1", "").Return(expectedResult, nil)
+
+	_, err := handler.ExecuteToolResult(context.Background(), "execute_sql_command", map[string]interface{}{
+		"connection": "reporting",
+		"command":    "SELECT 1",
+	})
+	require.Error(t, err)
+	assert.Equal(t, "sqlpp command failed [unknown_connection]: connection 'reporting' not found", err.Error())
+
+	var execErr *ExecutionError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, "unknown_connection", execErr.Result.Metadata().ErrorCode)
+
 	mockExecutor.AssertExpectations(t)
 }
 
+func TestExecuteToolResult_StructuredContent(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	expectedResult := &types.SqlppResult{
+		Success:    true,
+		Output:     "id\n1",
+		OutputSize: 4,
+		ExitCode:   0,
+		DurationMs: 12,
+		Stderr:     "Warning: deprecated option",
+		Args:       []string{"--stdin", "--connection", "main"},
+	}
+
+	mockExecutor.On("ExecuteSQLCommand", "main", "SELECT 1", "").Return(expectedResult, nil)
+
+	result, err := handler.ExecuteToolResult(context.Background(), "execute_sql_command", map[string]interface{}{
+		"connection": "main",
+		"command":    "SELECT 1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "id\n1", result.Text)
+
+	metadata, ok := result.Structured.(types.ExecutionMetadata)
+	require.True(t, ok)
+	assert.True(t, metadata.Success)
+	assert.Equal(t, int64(12), metadata.DurationMs)
+	assert.Equal(t, "Warning: deprecated option", metadata.Stderr)
+	assert.Equal(t, []string{"--stdin", "--connection", "main"}, metadata.Args)
+	assert.Equal(t, int64(4), metadata.OutputSize)
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_ExecuteSQL_TruncatedOutput(t *testing.T) {
+	mockExecutor := &MockExecutor{}
+	logger := logrus.New()
+	handler := NewToolHandler(mockExecutor, logger)
+
+	expectedResult := &types.SqlppResult{
+		Success:      true,
+		Output:       "row 1\nrow 2",
+		Truncated:    true,
+		OutputSize:   5000,
+		ResultHandle: "abc123",
+	}
+
+	mockExecutor.On("ExecuteSQLCommand", "test-conn", "SELECT * FROM big", "").Return(expectedResult, nil)
+
+	arguments := map[string]interface{}{
+		"connection": "test-conn",
+		"command":    "SELECT * FROM big",
+	}
+
+	result, err := handler.ExecuteTool(context.Background(), "execute_sql_command", arguments)
+	require.NoError(t, err)
+	assert.Contains(t, result, "row 1\nrow 2")
+	assert.Contains(t, result, "output truncated")
+	assert.Contains(t, result, `fetch_result_page with handle "abc123"`)
+
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestExecuteTool_SearchSchema_SpilledCatalog(t *testing.T) {
+	// A mock sqlpp whose catalog output is larger than the in-memory limit
+	mockSqlpp := filepath.Join(t.TempDir(), "mock-sqlpp")
+	mockScript := `#!/bin/bash
+if [[ "$1" == "--list-connections" ]]; then
+	echo '[{"name": "main", "driver": "postgres"}]'
+	exit 0
+fi
+cat > /dev/null
+echo '[{"object_type": "table", "object_schema": "public", "object_name": "orders", "column_name": null, "comment": null},'
+echo ' {"object_type": "table", "object_schema": "public", "object_name": "customers", "column_name": null, "comment": null}]'
+`
+	require.NoError(t, os.WriteFile(mockSqlpp, []byte(mockScript), 0755))
+
+	logger := logrus.New()
+	store, err := sqlpp.NewResultStore(t.TempDir(), time.Hour, logger)
+	require.NoError(t, err)
+	defer store.Close()
+	executor := sqlpp.NewExecutor(mockSqlpp, 30, logger)
+	executor.SetOutputLimit(64, store)
+	handler := NewToolHandler(executor, logger, WithResultStore(store))
+
+	result, err := handler.ExecuteToolResult(context.Background(), "search_schema", map[string]interface{}{"term": "customers"})
+	require.NoError(t, err)
+	search := result.Structured.(schemaSearchResult)
+	require.NotEmpty(t, search.Matches)
+	assert.Equal(t, "customers", search.Matches[0].Name)
+
+	// Catalogs over the read limit fail rather than being parsed in part
+	handler = NewToolHandler(executor, logger, WithResultStore(store), WithCatalogLimit(100))
+	_, err = handler.ExecuteToolResult(context.Background(), "search_schema", map[string]interface{}{"term": "customers"})
+	assert.ErrorContains(t, err, "more than sqlpp.max_catalog_bytes (100)")
+}
+
+func TestExecuteTool_FetchResultPage(t *testing.T) {
+	logger := logrus.New()
+	store, err := sqlpp.NewResultStore(t.TempDir(), time.Hour, logger)
+	require.NoError(t, err)
+	defer store.Close()
+
+	handler := NewToolHandler(&MockExecutor{}, logger, WithResultStore(store))
+
+	toolNames := make([]string, 0)
+	for _, tool := range handler.GetTools() {
+		toolNames = append(toolNames, tool.Name)
+	}
+	assert.Contains(t, toolNames, "fetch_result_page")
+
+	// Unknown handles are reported as errors
+	result, err := handler.ExecuteTool(context.Background(), "fetch_result_page", map[string]interface{}{
+		"handle": "missing",
+	})
+	require.Error(t, err)
+	assert.Empty(t, result)
+	assert.Contains(t, err.Error(), "not found")
+
+	// Handle is required
+	_, err = handler.ExecuteTool(context.Background(), "fetch_result_page", map[string]interface{}{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "handle parameter is required")
+}
+
+func TestExecuteTool_RunningQueries(t *testing.T) {
+	logger := logrus.New()
+	handler := NewToolHandler(&MockExecutor{}, logger, WithQueryRegistry(sqlpp.NewQueryRegistry()))
+
+	toolNames := make([]string, 0)
+	for _, tool := range handler.GetTools() {
+		toolNames = append(toolNames, tool.Name)
+	}
+	assert.Contains(t, toolNames, "list_running_queries")
+	assert.Contains(t, toolNames, "cancel_query")
+
+	result, err := handler.ExecuteToolResult(context.Background(), "list_running_queries", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Contains(t, result.Text, `"queries": []`)
+
+	// Unknown IDs are reported as errors
+	_, err = handler.ExecuteTool(context.Background(), "cancel_query", map[string]interface{}{
+		"id": "q-1",
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, sqlpp.ErrQueryNotFound)
+
+	// ID is required
+	_, err = handler.ExecuteTool(context.Background(), "cancel_query", map[string]interface{}{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "id parameter is required")
+}
+
+func TestGetIntArg(t *testing.T) {
+	handler := NewToolHandler(&MockExecutor{}, logrus.New())
+
+	arguments := map[string]interface{}{
+		"float_arg":  float64(42),
+		"int_arg":    7,
+		"string_arg": "12",
+	}
+
+	assert.Equal(t, 42, handler.getIntArg(arguments, "float_arg", 0))
+	assert.Equal(t, 7, handler.getIntArg(arguments, "int_arg", 0))
+	assert.Equal(t, 5, handler.getIntArg(arguments, "string_arg", 5))
+	assert.Equal(t, 5, handler.getIntArg(arguments, "missing_arg", 5))
+}
+
 func TestGetStringArg(t *testing.T) {
 	mockExecutor := &MockExecutor{}
 	logger := logrus.New()
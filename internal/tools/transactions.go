@@ -0,0 +1,226 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/stainedhead/gosqlpp-mcp-server/internal/sqlpp"
+	"github.com/stainedhead/gosqlpp-mcp-server/pkg/types"
+)
+
+// transactionStatus is the structured content of the transaction tools
+type transactionStatus struct {
+	sqlpp.Transaction
+	State              string `json:"state"`                          // open, committed or rolled_back
+	IdleTimeoutSeconds int    `json:"idle_timeout_seconds,omitempty"` // set when the transaction is opened
+}
+
+// Transaction states reported by the transaction tools
+const (
+	transactionOpen       = "open"
+	transactionCommitted  = "committed"
+	transactionRolledBack = "rolled_back"
+)
+
+// Begin transaction tool
+func (h *ToolHandler) createBeginTransactionTool() Tool {
+	schema := jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"connection": {
+				Type:        "string",
+				Description: "Database connection name to open the transaction on",
+			},
+			"output": {
+				Type:        "string",
+				Description: "Output format (json, table, csv, etc.) for statements run in the transaction",
+			},
+		},
+		Required: []string{"connection"},
+	}
+	return Tool{
+		Name:         "begin_transaction",
+		Description:  "Begin a transaction on a connection. Until it is committed or rolled back, execute_sql_command and execute_sql_file calls from this session on that connection run inside it, so changes can be inspected before committing.",
+		InputSchema:  &schema,
+		OutputSchema: transactionOutputSchema(),
+	}
+}
+
+// Commit transaction tool
+func (h *ToolHandler) createCommitTransactionTool() Tool {
+	return Tool{
+		Name:         "commit_transaction",
+		Description:  "Commit this session's open transaction on a connection",
+		InputSchema:  transactionInputSchema(),
+		OutputSchema: transactionOutputSchema(),
+	}
+}
+
+// Rollback transaction tool
+func (h *ToolHandler) createRollbackTransactionTool() Tool {
+	return Tool{
+		Name:         "rollback_transaction",
+		Description:  "Roll back this session's open transaction on a connection, discarding its changes",
+		InputSchema:  transactionInputSchema(),
+		OutputSchema: transactionOutputSchema(),
+	}
+}
+
+// transactionInputSchema describes the arguments of commit_transaction and
+// rollback_transaction
+func transactionInputSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"connection": {
+				Type:        "string",
+				Description: "Database connection name the transaction is open on",
+			},
+		},
+		Required: []string{"connection"},
+	}
+}
+
+// transactionOutputSchema describes the structured content of the
+// transaction tools, matching transactionStatus
+func transactionOutputSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"id":                   {Type: "string"},
+			"session":              {Type: "string", Description: "MCP session the transaction belongs to"},
+			"connection":           {Type: "string"},
+			"output":               {Type: "string", Description: "Output format of statements in the transaction"},
+			"started_at":           {Type: "string", Description: "Start time (RFC 3339)"},
+			"last_used":            {Type: "string", Description: "Time of the last statement (RFC 3339)"},
+			"statements":           {Type: "integer", Description: "Statements run in the transaction"},
+			"state":                {Type: "string", Enum: []any{transactionOpen, transactionCommitted, transactionRolledBack}},
+			"idle_timeout_seconds": {Type: "integer", Description: "Idle time after which the server rolls the transaction back"},
+		},
+		Required: []string{"id", "connection", "started_at", "last_used", "statements", "state"},
+	}
+}
+
+func (h *ToolHandler) executeBeginTransaction(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	if h.transactions == nil {
+		return nil, fmt.Errorf("unknown tool: begin_transaction")
+	}
+
+	connection := h.getStringArg(arguments, "connection", "")
+	output := h.getStringArg(arguments, "output", "")
+	if connection == "" {
+		return nil, fmt.Errorf("connection parameter is required")
+	}
+
+	tx, result, err := h.transactions.Begin(ctx, sqlpp.SessionIDFrom(ctx), connection, output)
+	if err != nil {
+		return nil, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	if !result.Success {
+		return nil, &ExecutionError{Result: result}
+	}
+
+	status := transactionStatus{
+		Transaction:        *tx,
+		State:              transactionOpen,
+		IdleTimeoutSeconds: int(h.transactions.IdleTimeout().Seconds()),
+	}
+	text := fmt.Sprintf("Transaction %s started on %s. SQL from this session on %s now runs in it until commit_transaction or rollback_transaction.", tx.ID, connection, connection)
+	if status.IdleTimeoutSeconds > 0 {
+		text += fmt.Sprintf(" It is rolled back if unused for %s.", h.transactions.IdleTimeout())
+	}
+	return &ToolResult{Text: text, Structured: status}, nil
+}
+
+func (h *ToolHandler) executeCommitTransaction(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	if h.transactions == nil {
+		return nil, fmt.Errorf("unknown tool: commit_transaction")
+	}
+
+	connection := h.getStringArg(arguments, "connection", "")
+	if connection == "" {
+		return nil, fmt.Errorf("connection parameter is required")
+	}
+
+	tx, result, err := h.transactions.Commit(ctx, sqlpp.SessionIDFrom(ctx), connection)
+	if err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	if !result.Success {
+		result.Error = fmt.Sprintf("%s (transaction %s was rolled back)", result.Error, tx.ID)
+		return nil, &ExecutionError{Result: result}
+	}
+	// DDL in the transaction becomes visible to other connections now
+	h.invalidateSchema(connection)
+
+	return &ToolResult{
+		Text:       fmt.Sprintf("Transaction %s on %s committed after %d statements", tx.ID, connection, tx.Statements),
+		Structured: transactionStatus{Transaction: *tx, State: transactionCommitted},
+	}, nil
+}
+
+func (h *ToolHandler) executeRollbackTransaction(ctx context.Context, arguments map[string]interface{}) (*ToolResult, error) {
+	if h.transactions == nil {
+		return nil, fmt.Errorf("unknown tool: rollback_transaction")
+	}
+
+	connection := h.getStringArg(arguments, "connection", "")
+	if connection == "" {
+		return nil, fmt.Errorf("connection parameter is required")
+	}
+
+	tx, result, err := h.transactions.Rollback(ctx, sqlpp.SessionIDFrom(ctx), connection)
+	if err != nil {
+		return nil, fmt.Errorf("error rolling back transaction: %w", err)
+	}
+
+	status := transactionStatus{Transaction: *tx, State: transactionRolledBack}
+	switch {
+	case result == nil:
+		return &ToolResult{
+			Text:       fmt.Sprintf("Transaction %s on %s had already been rolled back by the server", tx.ID, connection),
+			Structured: status,
+		}, nil
+	case !result.Success:
+		// Stopping the sqlpp process ends the transaction regardless
+		h.logger.WithField("transaction", tx.ID).Warn("Rollback statement failed; transaction ended with its sqlpp process")
+	}
+
+	return &ToolResult{
+		Text:       fmt.Sprintf("Transaction %s on %s rolled back", tx.ID, connection),
+		Structured: status,
+	}, nil
+}
+
+// sqlRunFunc runs SQL for a tool call
+type sqlRunFunc func(ctx context.Context, command string) (*types.SqlppResult, error)
+
+// sqlRunner returns the function that runs SQL for a call on connection: in
+// the session's transaction when one is open there, otherwise through the
+// executor. The tools run the SQL the caller asked for through it; helper
+// queries such as catalog lookups and plans bypass it, because a failed
+// statement rolls the whole transaction back.
+func (h *ToolHandler) sqlRunner(ctx context.Context, connection, output string) sqlRunFunc {
+	if session := sqlpp.SessionIDFrom(ctx); h.transactions != nil && h.transactions.Active(session, connection) {
+		return func(ctx context.Context, command string) (*types.SqlppResult, error) {
+			result, err := h.transactions.Execute(ctx, session, connection, command, output)
+			// Some databases commit DDL straight away, even in a transaction
+			if sqlpp.IsDDL(command) {
+				h.invalidateSchema(connection)
+			}
+			return result, err
+		}
+	}
+	return func(ctx context.Context, command string) (*types.SqlppResult, error) {
+		return h.executor.ExecuteSQLCommand(ctx, connection, command, output)
+	}
+}
+
+// invalidateSchema drops the cached schema results of connection, if schema
+// results are cached
+func (h *ToolHandler) invalidateSchema(connection string) {
+	if h.schemaCache != nil {
+		h.schemaCache.Invalidate(connection)
+	}
+}
@@ -5,6 +5,88 @@ type SqlppResult struct {
 	Success bool   `json:"success"`
 	Output  string `json:"output"`
 	Error   string `json:"error,omitempty"`
+
+	// Output limits: when Truncated is set, Output holds only a preview and
+	// the full output can be paged through with ResultHandle, if present
+	Truncated    bool   `json:"truncated,omitempty"`
+	OutputSize   int64  `json:"output_size,omitempty"`
+	ResultHandle string `json:"result_handle,omitempty"`
+
+	// Execution details
+	ExitCode   int      `json:"exit_code"`
+	DurationMs int64    `json:"duration_ms"`
+	Stderr     string   `json:"stderr,omitempty"` // diagnostics from sqlpp, including warnings on success
+	Args       []string `json:"args,omitempty"`
+	ErrorCode  string   `json:"error_code,omitempty"` // failure category, see sqlpp.ErrorCode
+	Attempts   int      `json:"attempts,omitempty"`   // executions including retries
+	Cached     bool     `json:"cached,omitempty"`     // served from the schema cache without running sqlpp
+}
+
+// Metadata returns the execution details of the result without its output
+func (r *SqlppResult) Metadata() ExecutionMetadata {
+	return ExecutionMetadata{
+		Success:      r.Success,
+		ExitCode:     r.ExitCode,
+		DurationMs:   r.DurationMs,
+		Args:         r.Args,
+		Stderr:       r.Stderr,
+		Error:        r.Error,
+		ErrorCode:    r.ErrorCode,
+		Attempts:     r.Attempts,
+		Truncated:    r.Truncated,
+		OutputSize:   r.OutputSize,
+		ResultHandle: r.ResultHandle,
+		Cached:       r.Cached,
+	}
+}
+
+// ExecutionMetadata describes how a sqlpp command ran. It is returned as MCP
+// structured content alongside the textual tool output.
+type ExecutionMetadata struct {
+	Success      bool     `json:"success"`
+	ExitCode     int      `json:"exit_code"`
+	DurationMs   int64    `json:"duration_ms"`
+	Args         []string `json:"args"`
+	Stderr       string   `json:"stderr,omitempty"`
+	Error        string   `json:"error,omitempty"`
+	ErrorCode    string   `json:"error_code,omitempty"`
+	Attempts     int      `json:"attempts,omitempty"`
+	Truncated    bool     `json:"truncated"`
+	OutputSize   int64    `json:"output_size"`
+	ResultHandle string   `json:"result_handle,omitempty"`
+	Cached       bool     `json:"cached,omitempty"`
+}
+
+// Batch statuses reported in BatchResult
+const (
+	BatchSucceeded = "succeeded"
+	BatchFailed    = "failed"
+	BatchSkipped   = "skipped" // not run because an earlier batch failed
+)
+
+// BatchResult is the outcome of one GO-separated batch of a SQL command
+type BatchResult struct {
+	Index        int    `json:"index"` // 1-based position in the command
+	Line         int    `json:"line"`  // line of the command the batch starts on
+	Status       string `json:"status"`
+	Output       string `json:"output,omitempty"`
+	DurationMs   int64  `json:"duration_ms"`
+	ExitCode     int    `json:"exit_code"`
+	Runs         int    `json:"runs"` // executions, from the separator's repeat count
+	Stderr       string `json:"stderr,omitempty"`
+	Error        string `json:"error,omitempty"`
+	ErrorCode    string `json:"error_code,omitempty"`
+	Truncated    bool   `json:"truncated,omitempty"`
+	OutputSize   int64  `json:"output_size,omitempty"`
+	ResultHandle string `json:"result_handle,omitempty"`
+}
+
+// BatchExecution is the structured content of a SQL command run as several
+// batches. The metadata summarizes the run: it reports the first failed
+// batch, or the last batch when all succeeded, with the total duration.
+type BatchExecution struct {
+	ExecutionMetadata
+	Batches []BatchResult `json:"batches"`
 }
 
 // ToolParameter represents a parameter for MCP tools
@@ -61,6 +61,11 @@ exit 0
 	srv, err := server.New(cfg, logger)
 	require.NoError(t, err)
 	require.NotNil(t, srv)
+
+	// Transactions need a sqlpp that lists --delimiter
+	cfg.Sqlpp.Transactions = config.TransactionConfig{Enabled: true, BeginStatement: "BEGIN", CommitStatement: "COMMIT", RollbackStatement: "ROLLBACK"}
+	_, err = server.New(cfg, logger)
+	assert.ErrorContains(t, err, "does not support --delimiter")
 }
 
 func TestSqlppExecutor(t *testing.T) {
@@ -107,25 +112,25 @@ esac
 	executor := sqlpp.NewExecutor(mockSqlpp, 30, logger)
 
 	// Test validation
-	err = executor.ValidateExecutable()
+	err = executor.ValidateExecutable(context.Background())
 	require.NoError(t, err)
 
 	// Test list connections
-	result, err := executor.ListConnections()
+	result, err := executor.ListConnections(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.True(t, result.Success)
 	assert.Contains(t, result.Output, "conn1")
 
 	// Test list drivers
-	result, err = executor.ListDrivers()
+	result, err = executor.ListDrivers(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.True(t, result.Success)
 	assert.Contains(t, result.Output, "mysql")
 
 	// Test schema command
-	result, err = executor.ExecuteSchemaCommand("tables", "test-conn", "", "json")
+	result, err = executor.ExecuteSchemaCommand(context.Background(), "tables", "test-conn", "", "json")
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.True(t, result.Success)
@@ -236,11 +241,11 @@ exit 1
 	executor := sqlpp.NewExecutor(mockSqlpp, 30, logger)
 
 	// Validate executable first
-	err = executor.ValidateExecutable()
+	err = executor.ValidateExecutable(context.Background())
 	require.NoError(t, err)
 
 	// Test list connections
-	result, err := executor.ListConnections()
+	result, err := executor.ListConnections(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result)
 	assert.True(t, result.Success)
@@ -258,3 +263,45 @@ exit 1
 	assert.Contains(t, result.Output, `"driver": "sqlite3"`)
 	assert.Contains(t, result.Output, `"notes": "Local SQLite database for testing"`)
 }
+
+func TestServerCreation_ReplayMode(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// An empty cassette is enough; no sqlpp executable exists
+	cassette := filepath.Join(tmpDir, "demo.cassette")
+	err := os.WriteFile(cassette, nil, 0600)
+	require.NoError(t, err)
+
+	// Scripts are listed as resources at startup
+	scriptRoot := filepath.Join(tmpDir, "scripts")
+	require.NoError(t, os.MkdirAll(scriptRoot, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(scriptRoot, "daily report.sql"), []byte("SELECT 1"), 0644))
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Transport: "stdio",
+			Host:      "localhost",
+			Port:      8080,
+		},
+		Sqlpp: config.SqlppConfig{
+			ExecutablePath: filepath.Join(tmpDir, "missing-bin"),
+			Timeout:        30,
+			ScriptRoots:    []string{scriptRoot},
+			Cassette: config.CassetteConfig{
+				Mode: config.CassetteReplay,
+				Path: cassette,
+			},
+		},
+		Log: config.LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+
+	srv, err := server.New(cfg, logger)
+	require.NoError(t, err)
+	require.NotNil(t, srv)
+}